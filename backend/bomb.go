@@ -1,11 +1,21 @@
 package main
 
-import "bomberman-dom/models"
+import (
+	"bomberman-dom/models"
+	"sort"
+	"sync"
+)
 
 const (
-	BombTimer         = 150 // Ticks before explosion (e.g., 3 seconds at 50 ticks/sec)
-	FlameTime         = 25  // Ticks for how long flames last (0.5 seconds at 50 ticks/sec)
+	BombTimer         = 150 // Default ticks before explosion (e.g., 3 seconds at 50 ticks/sec)
+	FlameTime         = 25  // Default ticks for how long flames last (0.5 seconds at 50 ticks/sec)
 	InvincibilityTime = 100 // Ticks for invincibility after respawn (2 seconds)
+
+	// BombPlacementCooldown is the minimum number of ticks between two bombs from the same player.
+	BombPlacementCooldown = 5
+
+	// MaxFlames caps the number of active flames in a game to bound memory when many bombs go off at once.
+	MaxFlames = 500
 )
 
 // PlaceBomb adds a new bomb to the game state at the player's position.
@@ -16,6 +26,17 @@ func PlaceBomb(gs *models.GameState, player *models.Player) {
 		return
 	}
 
+	// Rate-limit placement so a high BombCount can't be dumped all at once
+	if player.BombCooldown > 0 {
+		return
+	}
+
+	// Never place a bomb inside a wall or an intact block, even if a bug left the player there
+	if isWall(gs, player.Position) || hasBlock(gs, player.Position) {
+		gameLogger.Warnf("Refusing bomb from %s on solid tile %v", player.ID, player.Position)
+		return
+	}
+
 	// Check if there's already a bomb at this position
 	for _, bomb := range gs.Bombs {
 		if bomb.Position == player.Position {
@@ -24,14 +45,17 @@ func PlaceBomb(gs *models.GameState, player *models.Player) {
 	}
 
 	player.BombsPlaced++
+	player.BombCooldown = gs.Config.BombCooldown
 
 	bomb := &models.Bomb{
 		Position:   player.Position,
 		OwnerID:    player.ID,
-		Timer:      BombTimer,
+		Timer:      gs.Config.BombTimer,
 		FlameRange: player.FlameRange,
+		Lava:       player.HasLava,
 	}
 
+	bomb.BlastTiles = BlastTiles(gs, bomb)
 	gs.Bombs = append(gs.Bombs, bomb)
 }
 
@@ -40,10 +64,17 @@ func UpdateBombs(gs *models.GameState) {
 	var explodingBombs []*models.Bomb
 	var remainingBombs []*models.Bomb
 
-	// First, find all bombs that should explode in this tick
+	// First, find all bombs that should explode in this tick. Punched bombs fly on and only
+	// explode once they have landed.
 	for _, bomb := range gs.Bombs {
-		bomb.Timer--
-		if bomb.Timer <= 0 {
+		if bomb.Airborne > 0 {
+			advanceAirborneBomb(gs, bomb)
+		}
+		if bomb.Timer > 0 {
+			bomb.Timer--
+		}
+		bomb.Imminent = IsBombImminent(gs, bomb)
+		if bomb.Timer <= 0 && bomb.Airborne == 0 {
 			explodingBombs = append(explodingBombs, bomb)
 		} else {
 			remainingBombs = append(remainingBombs, bomb)
@@ -64,100 +95,242 @@ func UpdateBombs(gs *models.GameState) {
 		}
 		CreateFlames(gs, bomb)
 	}
+
+	// Refresh blast predictions, since explosions may have opened new paths
+	for _, bomb := range gs.Bombs {
+		bomb.BlastTiles = BlastTiles(gs, bomb)
+	}
+}
+
+// BombImminentThreshold is the remaining timer below which a bomb is flagged as about to explode,
+// letting clients flash it faster. It is a quarter of the game's bomb timer.
+func BombImminentThreshold(gs *models.GameState) int {
+	return gs.Config.BombTimer / 4
+}
+
+// IsBombImminent reports whether a bomb's timer is below the pre-detonation threshold.
+func IsBombImminent(gs *models.GameState, bomb *models.Bomb) bool {
+	return bomb.Timer < BombImminentThreshold(gs)
 }
 
 // createFlames generates the flame objects for an exploding bomb.
+// The covered tiles come from BlastTiles, so the prediction sent to clients always matches.
+// Flames are laid out one ring at a time so that, if the MaxFlames cap is reached,
+// the tiles closest to the bomb are the ones that survive.
 func CreateFlames(gs *models.GameState, bomb *models.Bomb) {
-	// Add flame at the bomb's center
-	gs.Flames = append(gs.Flames, &models.Flame{Position: bomb.Position, Timer: FlameTime})
-	isPlayer(gs, bomb.Position)  // Check if a player is on the bomb itself
-	isPowerUp(gs, bomb.Position) // Check if a power-up is at the bomb's position
+	tiles := BlastTiles(gs, bomb)
+	sort.SliceStable(tiles, func(i, j int) bool {
+		return manhattan(tiles[i], bomb.Position) < manhattan(tiles[j], bomb.Position)
+	})
 
-	// Directions: up, down, left, right
+	for _, pos := range tiles {
+		if len(gs.Flames) >= MaxFlames {
+			gameLogger.Warnf("Flame cap of %d reached, truncating explosion at %v", MaxFlames, bomb.Position)
+			return
+		}
+		flame := newFlame(pos, gs.Config.FlameTime, bomb.OwnerID)
+		flame.Lava = bomb.Lava
+		flame.Tick = gs.Tick
+		gs.Flames = append(gs.Flames, flame)
+
+		// Dmg players and/or PowerUps and dont stop flames
+		isPlayer(gs, pos, bomb)
+		isPowerUp(gs, pos)
+
+		// Destroy the block the flame stopped on (BlastTiles already ended the direction here)
+		isBlock(gs, pos, bomb)
+	}
+}
+
+// manhattan returns the grid distance between two positions.
+func manhattan(a, b models.Position) int {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return dx + dy
+}
+
+// BlastTiles returns the tiles a bomb's explosion would cover right now, without changing the game state.
+// The blast stops at the map edge, before walls, and on the first intact block.
+func BlastTiles(gs *models.GameState, bomb *models.Bomb) []models.Position {
+	tiles := []models.Position{bomb.Position}
 	dirs := []models.Position{{X: 0, Y: -1}, {X: 0, Y: 1}, {X: -1, Y: 0}, {X: 1, Y: 0}}
 
 	for _, dir := range dirs {
 		for i := 1; i <= bomb.FlameRange; i++ {
 			pos := models.Position{X: bomb.Position.X + dir.X*i, Y: bomb.Position.Y + dir.Y*i}
-
-			// Stop flames if they hit an indestructible wall
-			if isWall(gs, pos) {
+			// Stop at the map edge even if the border isn't walled off
+			if !inMapBounds(gs.Map, pos) || isWall(gs, pos) {
 				break
 			}
-
-			gs.Flames = append(gs.Flames, &models.Flame{Position: pos, Timer: FlameTime})
-
-			// Dmg players and/or PowerUps and dont stop flames
-			isPlayer(gs, pos)
-			isPowerUp(gs, pos)
-
-			// If the flame hits a destructible block, it stops spreading in that direction
-			if isBlock(gs, pos) {
+			tiles = append(tiles, pos)
+			if hasBlock(gs, pos) {
 				break
 			}
 		}
 	}
+	return tiles
 }
 
 // UpdateFlames reduces the timer on active flames and removes them when they expire.
+// The slice is filtered in place and expired flames go back to flamePool. A lava flame
+// leaves a Hazard on its tile as it clears.
 func UpdateFlames(gs *models.GameState) {
-	var remainingFlames []*models.Flame
+	remainingFlames := gs.Flames[:0]
 	for _, flame := range gs.Flames {
 		flame.Timer--
 		if flame.Timer > 0 {
 			remainingFlames = append(remainingFlames, flame)
+		} else {
+			if flame.Lava {
+				addHazard(gs, flame.Position, flame.OwnerID)
+			}
+			releaseFlame(flame)
 		}
 	}
+	// Clear the tail so the backing array doesn't keep released flames reachable
+	clear(gs.Flames[len(remainingFlames):])
 	gs.Flames = remainingFlames
 }
 
+// flamePool recycles Flame structs; explosions create and expire many of them every second.
+// Nothing may hold a *Flame past the tick it expires in: state is marshaled before the next tick.
+var flamePool = sync.Pool{
+	New: func() any { return &models.Flame{} },
+}
+
+// newFlame takes a flame from the pool and fully initializes it.
+func newFlame(pos models.Position, timer int, ownerID string) *models.Flame {
+	flame := flamePool.Get().(*models.Flame)
+	*flame = models.Flame{Position: pos, Timer: timer, OwnerID: ownerID}
+	return flame
+}
+
+// releaseFlame zeroes a flame and returns it to the pool.
+func releaseFlame(flame *models.Flame) {
+	*flame = models.Flame{}
+	flamePool.Put(flame)
+}
+
 // Finds a block at a given position, marks it as destroyed,
 // and reveals a power-up if one is hidden. It returns true if a block was found and destroyed.
-func isBlock(gs *models.GameState, pos models.Position) bool {
-	for _, block := range gs.Map.Blocks {
-		if !block.Destroyed && block.Position == pos {
-			block.Destroyed = true
-			// If the block has a power-up, add it to the active power-ups on the map.
-			if block.HiddenPowerUp != nil {
-				gs.PowerUps = append(gs.PowerUps, &models.ActivePowerUp{
-					Position: block.Position,
-					Type:     block.HiddenPowerUp.Type,
-				})
-				block.HiddenPowerUp = nil // Power-up is no longer hidden
-			}
-			return true
+func isBlock(gs *models.GameState, pos models.Position, bomb *models.Bomb) bool {
+	block := gs.Map.BlockAt(pos)
+	if block == nil {
+		return false
+	}
+	gs.Map.DestroyBlock(block)
+	destroyed := models.DestroyedBlock{Position: block.Position}
+
+	// Credit the bomb owner for the ranking tiebreaker
+	for _, p := range gs.Players {
+		if p.ID == bomb.OwnerID {
+			p.BlocksDestroyed++
+			break
 		}
 	}
-	return false
+	// If the block has a power-up, add it to the active power-ups on the map.
+	if block.HiddenPowerUp != nil {
+		gs.PowerUps = append(gs.PowerUps, &models.ActivePowerUp{
+			Position:   block.Position,
+			Type:       block.HiddenPowerUp.Type,
+			RevealTick: gs.Tick + PowerUpRevealDelay,
+		})
+		destroyed.PowerUp = &block.HiddenPowerUp.Type
+		block.HiddenPowerUp = nil // Power-up is no longer hidden
+	}
+	gs.DestroyedBlocks = append(gs.DestroyedBlocks, destroyed)
+	return true
+}
+
+// inMapBounds checks if a position lies inside the map. Nothing is inside a nil map.
+func inMapBounds(m *models.Map, pos models.Position) bool {
+	if m == nil {
+		return false
+	}
+	return pos.X >= 0 && pos.X < m.Width && pos.Y >= 0 && pos.Y < m.Height
+}
+
+// hasBlock checks if an intact destructible block is at a position, without destroying it.
+func hasBlock(gs *models.GameState, pos models.Position) bool {
+	return gs.Map.BlockAt(pos) != nil
 }
 
 // checks if a position is an indestructible wall.
 func isWall(gs *models.GameState, pos models.Position) bool {
-	for _, wall := range gs.Map.Walls {
-		if wall.Position == pos {
-			return true
+	return gs.Map.WallAt(pos)
+}
+
+// isPlayer burns every player at a given position that the bomb's fire can hurt.
+// The hit is credited to the owner of the first flame on the tile, so where two blasts
+// overlap the bomb that got there first takes the kill.
+func isPlayer(gs *models.GameState, pos models.Position, bomb *models.Bomb) {
+	killerID := flameOwnerAt(gs, pos, bomb.OwnerID)
+	for _, player := range gs.Players {
+		if player.Position == pos && canBurn(gs, player, bomb.OwnerID) {
+			burnPlayer(gs, player, killerID)
 		}
 	}
-	return false
 }
 
-// isPlayer checks if an alive player is at a given position. If so, it reduces
-// their lives and returns true to stop the flame.
-func isPlayer(gs *models.GameState, pos models.Position) {
-	for _, player := range gs.Players {
-		// Player can only be hit if they are alive AND not invincible.
-		if player.Alive && player.Position == pos && player.Invincible <= 0 {
-			player.Lives--
-			if player.Lives > 0 {
-				// Respawn the player at their starting point.
-				player.Position = player.SpawnPoint
-				player.Invincible = InvincibilityTime
-			} else {
-				// The player is out of lives.
-				player.Alive = false
+// BurnPlayersInFlames burns players standing in fire lit on an earlier tick, e.g. one who
+// walked into it or whose Shield ran out. Flames lit this tick already hit in CreateFlames.
+func BurnPlayersInFlames(gs *models.GameState) {
+	for _, flame := range gs.Flames {
+		if flame.Tick == gs.Tick {
+			continue
+		}
+		for _, player := range gs.Players {
+			if player.Position == flame.Position && canBurn(gs, player, flame.OwnerID) {
+				burnPlayer(gs, player, flameOwnerAt(gs, flame.Position, flame.OwnerID))
+			}
+		}
+	}
+}
+
+// canBurn reports whether fire from ownerID's bomb can hurt a player: they must be alive,
+// past their respawn invincibility and not Shielded. With friendly fire off in team mode,
+// the owner's teammates are not hurt either.
+func canBurn(gs *models.GameState, player *models.Player, ownerID string) bool {
+	if !player.Alive || player.Invincible > 0 || player.Shielded > 0 {
+		return false
+	}
+	if gs.Config.TeamMode && !gs.Config.FriendlyFire && player.ID != ownerID {
+		for _, owner := range gs.Players {
+			if owner.ID == ownerID {
+				return owner.TeamID != player.TeamID
 			}
 		}
 	}
+	return true
+}
+
+// flameOwnerAt returns the owner of the oldest flame on a tile, or fallback if none is burning there.
+func flameOwnerAt(gs *models.GameState, pos models.Position, fallback string) string {
+	for _, flame := range gs.Flames {
+		if flame.Position == pos {
+			return flame.OwnerID
+		}
+	}
+	return fallback
+}
+
+// burnPlayer takes a life from a player hit by fire or lava. They respawn at their starting
+// point with a short invincibility, or are eliminated on their last life, credited to killerID.
+func burnPlayer(gs *models.GameState, player *models.Player, killerID string) {
+	player.Lives--
+	if player.Lives > 0 {
+		// Respawn the player at their starting point.
+		player.Position = player.SpawnPoint
+		player.Invincible = InvincibilityTime
+	} else {
+		// The player is out of lives.
+		KillPlayer(gs, player, killerID)
+	}
 }
 
 // destroyPowerUpAt finds and removes a power-up at a given position.
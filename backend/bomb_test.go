@@ -0,0 +1,380 @@
+package main
+
+import (
+	"bomberman-dom/logging"
+	"bomberman-dom/models"
+	"strings"
+	"testing"
+)
+
+func TestBombImminentFlag(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	PlaceBomb(gs, gs.Players[0])
+	if len(gs.Bombs) != 1 {
+		t.Fatalf("bomb not placed")
+	}
+	bomb := gs.Bombs[0]
+	threshold := BombImminentThreshold(gs)
+
+	// One tick left above the threshold: still calm
+	bomb.Timer = threshold + 1
+	UpdateBombs(gs)
+	if bomb.Timer != threshold || bomb.Imminent {
+		t.Errorf("timer %d: imminent = %v, want false at the threshold", bomb.Timer, bomb.Imminent)
+	}
+
+	// Crossing the threshold flags it
+	UpdateBombs(gs)
+	if bomb.Timer != threshold-1 || !bomb.Imminent {
+		t.Errorf("timer %d: imminent = %v, want true below the threshold", bomb.Timer, bomb.Imminent)
+	}
+}
+
+func TestFlameCapWithManyBombsOnOneTick(t *testing.T) {
+	cfg := DefaultGameConfig()
+	cfg.MapWidth, cfg.MapHeight = MaxMapSize, MaxMapSize
+	gs := NewGame(nil, cfg)
+	gs.Map.Blocks = nil
+	gs.Map.Reindex()
+
+	// A long-range bomb on every free tile, all going off on the same tick
+	for y := 1; y < cfg.MapHeight-1; y++ {
+		for x := 1; x < cfg.MapWidth-1; x++ {
+			if pos := at(x, y); !gs.Map.WallAt(pos) {
+				gs.Bombs = append(gs.Bombs, &models.Bomb{Position: pos, OwnerID: "p1", Timer: 1, FlameRange: MaxMapSize})
+			}
+		}
+	}
+	UpdateBombs(gs)
+
+	if len(gs.Flames) != MaxFlames {
+		t.Fatalf("%d flames after the explosions, want exactly the cap of %d", len(gs.Flames), MaxFlames)
+	}
+	for _, flame := range gs.Flames {
+		if !inMapBounds(gs.Map, flame.Position) || gs.Map.WallAt(flame.Position) {
+			t.Errorf("flame on invalid tile %v", flame.Position)
+		}
+	}
+
+	// With room for only five more flames, a blast keeps its centre and the tiles next to it
+	gs.Flames = gs.Flames[:MaxFlames-5]
+	centre := at(7, 7)
+	gs.Bombs = []*models.Bomb{{Position: centre, OwnerID: "p1", Timer: 1, FlameRange: 3}}
+	UpdateBombs(gs)
+	added := gs.Flames[MaxFlames-5:]
+	if len(added) != 5 {
+		t.Fatalf("blast added %d flames, want the 5 left under the cap", len(added))
+	}
+	if added[0].Position != centre {
+		t.Errorf("first flame of the truncated blast at %v, want the centre %v", added[0].Position, centre)
+	}
+	for _, flame := range added[1:] {
+		if manhattan(flame.Position, centre) != 1 {
+			t.Errorf("truncated blast kept %v over a tile next to the centre", flame.Position)
+		}
+	}
+}
+
+func TestBlastTilesTruncated(t *testing.T) {
+	gs := newTestGame(at(13, 11))
+	gs.Map.Blocks = []*models.Block{{Position: at(3, 1)}}
+	gs.Map.Reindex()
+
+	// Corner bomb: walls above and to the left, a block two tiles to the right
+	bomb := &models.Bomb{Position: at(1, 1), FlameRange: 3}
+	got := map[models.Position]bool{}
+	for _, pos := range BlastTiles(gs, bomb) {
+		got[pos] = true
+	}
+	want := []models.Position{at(1, 1), at(2, 1), at(3, 1), at(1, 2), at(1, 3), at(1, 4)}
+	if len(got) != len(want) {
+		t.Errorf("blast covers %d tiles, want %d: %v", len(got), len(want), got)
+	}
+	for _, pos := range want {
+		if !got[pos] {
+			t.Errorf("blast misses %v", pos)
+		}
+	}
+
+	// The bomb's prediction matches the flames it actually creates
+	bomb.Timer = 1
+	bomb.BlastTiles = BlastTiles(gs, bomb)
+	gs.Bombs = append(gs.Bombs, bomb)
+	UpdateBombs(gs)
+	if len(gs.Flames) != len(want) {
+		t.Errorf("explosion made %d flames, want %d", len(gs.Flames), len(want))
+	}
+	for _, flame := range gs.Flames {
+		if !got[flame.Position] {
+			t.Errorf("flame at %v outside the predicted blast", flame.Position)
+		}
+	}
+}
+
+func TestEdgeBombOnBorderlessMap(t *testing.T) {
+	gs := newTestGame(at(7, 7))
+	inner := gs.Map.Walls[:0]
+	for _, w := range gs.Map.Walls {
+		if x, y := w.Position.X, w.Position.Y; x > 0 && y > 0 && x < gs.Map.Width-1 && y < gs.Map.Height-1 {
+			inner = append(inner, w)
+		}
+	}
+	gs.Map.Walls = inner
+	gs.Map.Reindex()
+
+	for _, corner := range []models.Position{at(0, 0), at(gs.Map.Width-1, gs.Map.Height-1)} {
+		gs.Flames = nil
+		gs.Bombs = []*models.Bomb{{Position: corner, Timer: 1, FlameRange: 3}}
+		UpdateBombs(gs)
+		if len(gs.Flames) != 7 {
+			t.Errorf("bomb at %v made %d flames, want 7 (centre plus 3 along each open edge)", corner, len(gs.Flames))
+		}
+		for _, flame := range gs.Flames {
+			if !inMapBounds(gs.Map, flame.Position) {
+				t.Errorf("bomb at %v put a flame outside the map at %v", corner, flame.Position)
+			}
+		}
+	}
+}
+
+func TestBombCooldownWithinOneTick(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	p := gs.Players[0]
+	p.BombCount = 5
+
+	// Bomb, step off, bomb again: plenty of capacity, but the cooldown allows only one
+	ResolveMoves(gs, []MoveIntent{
+		{Player: p, Action: ActionBomb},
+		{Player: p, Action: ActionMove, Direction: models.DirRight},
+		{Player: p, Action: ActionBomb},
+		{Player: p, Action: ActionBomb},
+	})
+	if len(gs.Bombs) != 1 || p.BombsPlaced != 1 {
+		t.Fatalf("%d bombs placed in one tick, want 1", len(gs.Bombs))
+	}
+
+	for i := 0; i < gs.Config.BombCooldown; i++ {
+		GameTick(gs)
+	}
+	ResolveMoves(gs, []MoveIntent{{Player: p, Action: ActionBomb}})
+	if len(gs.Bombs) != 2 {
+		t.Errorf("%d bombs after the cooldown, want 2", len(gs.Bombs))
+	}
+}
+
+func TestPlacingBombNeverHurtsOwner(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	gs.Config.BombTimer = MinBombTimer // The shortest fuse a lobby can set
+	owner := gs.Players[0]
+	lives := owner.Lives
+
+	// Placing the bomb and standing on it does nothing until the fuse runs out
+	PlaceBomb(gs, owner)
+	if len(gs.Bombs) != 1 {
+		t.Fatal("bomb not placed")
+	}
+	for i := 0; i < MinBombTimer-1; i++ {
+		GameTick(gs)
+	}
+	if len(gs.Flames) != 0 || owner.Lives != lives {
+		t.Fatalf("%d flames and %d lives of %d before the fuse ran out", len(gs.Flames), owner.Lives, lives)
+	}
+	GameTick(gs)
+	if len(gs.Flames) == 0 || owner.Lives != lives-1 {
+		t.Fatalf("owner who stayed put has %d lives after the blast, want %d", owner.Lives, lives-1)
+	}
+
+	// Even the shortest fuse leaves time to walk off the bomb and out of its range
+	gs = newTestGame(at(1, 1), at(13, 11))
+	gs.Config.BombTimer = MinBombTimer
+	owner = gs.Players[0]
+	PlaceBomb(gs, owner)
+	ResolveMoves(gs, []MoveIntent{{Player: owner, Action: ActionMoveStart, Direction: models.DirRight}})
+	for i := 0; i < MinBombTimer; i++ {
+		GameTick(gs)
+	}
+	if len(gs.Flames) == 0 {
+		t.Fatal("bomb did not explode")
+	}
+	if owner.Lives != lives || owner.Position.X <= 1+owner.FlameRange {
+		t.Errorf("owner at %v with %d lives after the blast, want them clear of it with %d", owner.Position, owner.Lives, lives)
+	}
+}
+
+func TestKillCreditsBombOwner(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(3, 1), at(13, 11), at(5, 1))
+	bomber, victim, other, second := gs.Players[0], gs.Players[1], gs.Players[2], gs.Players[3]
+	victim.Lives, second.Lives = 1, 1
+
+	gs.Bombs = []*models.Bomb{{Position: at(2, 1), OwnerID: bomber.ID, Timer: 1, FlameRange: 1}}
+	GameTick(gs)
+	if victim.Alive || len(gs.EliminationOrder) != 1 {
+		t.Fatalf("victim alive=%v, eliminations %+v, want the victim knocked out", victim.Alive, gs.EliminationOrder)
+	}
+	if got := gs.EliminationOrder[0]; got.PlayerID != victim.ID || got.KillerID != bomber.ID {
+		t.Errorf("elimination %+v, want %s killed by %s", got, victim.ID, bomber.ID)
+	}
+
+	// Where two blasts overlap, the flame already burning on the tile takes the kill
+	gs.Flames = []*models.Flame{{Position: at(5, 1), OwnerID: other.ID, Timer: 5, Tick: gs.Tick}}
+	isPlayer(gs, at(5, 1), &models.Bomb{Position: at(4, 1), OwnerID: bomber.ID})
+	if second.Alive || len(gs.EliminationOrder) != 2 {
+		t.Fatalf("second victim alive=%v, eliminations %+v", second.Alive, gs.EliminationOrder)
+	}
+	if got := gs.EliminationOrder[1]; got.KillerID != other.ID {
+		t.Errorf("overlapping blasts credited %q, want the first flame's owner %s", got.KillerID, other.ID)
+	}
+}
+
+func TestShortFuseLobbySetting(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	lh.lobby.BombTimer = MinBombTimer
+	lh.lobby.FlameTime = MinFlameTime
+	cfg := lh.gameConfig()
+	cfg.MapSeed = 1
+	players := []*models.Player{NewGamePlayer("p1", "Player 1", cfg), NewGamePlayer("p2", "Player 2", cfg)}
+	players[0].Position, players[1].Position = at(1, 1), at(13, 11)
+	gs := NewGame(players, cfg)
+
+	PlaceBomb(gs, players[0])
+	players[0].Position = at(1, 3) // Out of the blast
+	placed := gs.Tick
+	for len(gs.Flames) == 0 && gs.Tick < placed+BombTimer {
+		GameTick(gs)
+	}
+	if fuse := gs.Tick - placed; fuse != MinBombTimer {
+		t.Fatalf("bomb exploded after %d ticks, want the lobby's %d (default %d)", fuse, MinBombTimer, BombTimer)
+	}
+
+	exploded := gs.Tick
+	for len(gs.Flames) > 0 && gs.Tick < exploded+FlameTime {
+		GameTick(gs)
+	}
+	// The explosion tick itself counts toward the flame time
+	if burned := gs.Tick - exploded + 1; burned != MinFlameTime {
+		t.Errorf("flames lasted %d ticks, want the lobby's %d (default %d)", burned, MinFlameTime, FlameTime)
+	}
+}
+
+func TestPooledFlamesStartClean(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	gs.Tick = 100
+
+	// A lava blast from p1 whose flames all expire back into the pool
+	gs.Bombs = []*models.Bomb{{Position: at(5, 5), OwnerID: "p1", Timer: 1, FlameRange: 3, Lava: true}}
+	UpdateBombs(gs)
+	for len(gs.Flames) > 0 {
+		UpdateFlames(gs)
+	}
+	gs.Hazards = nil
+
+	// A plain blast from p2 elsewhere must not inherit anything from the recycled flames
+	gs.Tick = 200
+	bomb := &models.Bomb{Position: at(9, 7), OwnerID: "p2", Timer: 1, FlameRange: 2}
+	gs.Bombs = []*models.Bomb{bomb}
+	blast := map[models.Position]bool{}
+	for _, pos := range BlastTiles(gs, bomb) {
+		blast[pos] = true
+	}
+	UpdateBombs(gs)
+	if len(gs.Flames) != len(blast) {
+		t.Fatalf("%d flames, want %d", len(gs.Flames), len(blast))
+	}
+	for _, flame := range gs.Flames {
+		want := models.Flame{Position: flame.Position, Timer: gs.Config.FlameTime, OwnerID: "p2", Tick: 200}
+		if !blast[flame.Position] || *flame != want {
+			t.Errorf("flame %+v, want %+v on a tile of the new blast", *flame, want)
+		}
+	}
+}
+
+// BenchmarkFlameLifecycle lights and expires a large explosion's worth of flames per
+// iteration, with and without flamePool.
+func BenchmarkFlameLifecycle(b *testing.B) {
+	const flames = 40
+	lifecycles := []struct {
+		name    string
+		light   func(pos models.Position) *models.Flame
+		release func(*models.Flame)
+	}{
+		{"pooled", func(pos models.Position) *models.Flame { return newFlame(pos, FlameTime, "p1") }, releaseFlame},
+		{"unpooled", func(pos models.Position) *models.Flame {
+			return &models.Flame{Position: pos, Timer: FlameTime, OwnerID: "p1"}
+		}, func(*models.Flame) {}},
+	}
+	for _, lc := range lifecycles {
+		b.Run(lc.name, func(b *testing.B) {
+			b.ReportAllocs()
+			active := make([]*models.Flame, 0, flames)
+			for i := 0; i < b.N; i++ {
+				for j := 0; j < flames; j++ {
+					active = append(active, lc.light(at(j, i%13)))
+				}
+				for _, flame := range active {
+					lc.release(flame)
+				}
+				active = active[:0]
+			}
+		})
+	}
+}
+
+func TestDestroyedBlocksListed(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	gs.Map.Blocks = []*models.Block{
+		{Position: at(5, 6), HiddenPowerUp: &models.PowerUp{Type: models.FlameUp}},
+		{Position: at(7, 5)},
+		{Position: at(5, 9)}, // Out of range
+	}
+	gs.Map.Reindex()
+	gs.Bombs = []*models.Bomb{{Position: at(5, 5), OwnerID: "p1", Timer: 1, FlameRange: 2}}
+
+	GameTick(gs)
+	got := map[models.Position]*models.PowerUpType{}
+	for _, b := range gs.DestroyedBlocks {
+		got[b.Position] = b.PowerUp
+	}
+	if len(gs.DestroyedBlocks) != 2 || len(got) != 2 {
+		t.Fatalf("destroyed blocks %+v, want (5,6) and (7,5)", gs.DestroyedBlocks)
+	}
+	if pu, ok := got[at(5, 6)]; !ok || pu == nil || *pu != models.FlameUp {
+		t.Errorf("block at (5,6) listed with power-up %v, want FlameUp", pu)
+	}
+	if pu, ok := got[at(7, 5)]; !ok || pu != nil {
+		t.Errorf("block at (7,5) listed with power-up %v, want none", pu)
+	}
+
+	// The list only covers the tick the blocks went down on
+	GameTick(gs)
+	if len(gs.DestroyedBlocks) != 0 {
+		t.Errorf("destroyed blocks %+v still listed a tick later", gs.DestroyedBlocks)
+	}
+}
+
+func TestBombRefusedOnSolidTile(t *testing.T) {
+	gs := newTestGame(at(2, 2), at(3, 3))
+	onWall, onBlock := gs.Players[0], gs.Players[1]
+	gs.Map.Blocks = []*models.Block{{Position: at(3, 3)}}
+	gs.Map.Reindex()
+
+	var logged strings.Builder
+	defer func(previous *logging.Logger) { gameLogger = previous }(gameLogger)
+	gameLogger = logging.New(&logged, logging.Warn)
+
+	for _, p := range []*models.Player{onWall, onBlock} {
+		PlaceBomb(gs, p)
+		if len(gs.Bombs) != 0 || p.BombsPlaced != 0 || p.BombCooldown != 0 {
+			t.Errorf("%s placed a bomb inside solid terrain at %v", p.ID, p.Position)
+		}
+	}
+	if n := strings.Count(logged.String(), "Refusing bomb"); n != 2 {
+		t.Errorf("logged %d refusals, want 2:\n%s", n, logged.String())
+	}
+
+	// Back on open floor the same player places as usual
+	onWall.Position = at(1, 1)
+	PlaceBomb(gs, onWall)
+	if len(gs.Bombs) != 1 {
+		t.Error("bomb refused on an open tile")
+	}
+}
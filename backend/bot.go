@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"fmt"
+	"math/rand"
+)
+
+const (
+	BotMoveInterval  = 5   // Ticks between bot moves (4 moves per second at 20 ticks/sec)
+	BotBombChance    = 0.3 // Chance to drop a bomb when next to a block or opponent
+	BotPathSearchMax = 400 // Upper bound on tiles visited by one path search
+)
+
+// Bot drives a game Player that has no WebSocket connection behind it.
+// It produces the same MoveIntents a human's inputs do.
+type Bot struct {
+	Player   *models.Player
+	cooldown int
+}
+
+// NewBotPlayer creates the game Player for the n-th bot (1-based).
+func NewBotPlayer(n int, cfg models.GameConfig) *models.Player {
+	bot := NewGamePlayer(fmt.Sprintf("bot_%d", n), fmt.Sprintf("Bot %d", n), cfg)
+	bot.IsBot = true
+	return bot
+}
+
+// Tick lets the bot decide once its move cooldown has run out: flee danger first,
+// otherwise maybe drop a bomb, then head for the nearest power-up, opponent or block.
+// It returns the chosen input, if any, for the caller to apply.
+func (b *Bot) Tick(gs *models.GameState) (MoveIntent, bool) {
+	if !b.Player.Alive || gs.Status != models.InProgress {
+		return MoveIntent{}, false
+	}
+	if b.cooldown > 0 {
+		b.cooldown--
+		return MoveIntent{}, false
+	}
+	b.cooldown = BotMoveInterval
+
+	intent, ok := b.decide(gs)
+	// Undo a reverse-controls curse so the bot still goes where it meant to
+	if ok && intent.Action == ActionMove && HasStatus(b.Player, models.StatusReverseControls) {
+		intent.Direction = intent.Direction.Reverse()
+	}
+	return intent, ok
+}
+
+// decide picks the bot's next input.
+func (b *Bot) decide(gs *models.GameState) (MoveIntent, bool) {
+	if IsTileDangerousWithin(gs, b.Player.Position, gs.Config.BombTimer) {
+		if dir, ok := b.stepToward(gs, func(pos models.Position) bool {
+			return !IsTileDangerousWithin(gs, pos, gs.Config.BombTimer)
+		}); ok {
+			return MoveIntent{Player: b.Player, Action: ActionMove, Direction: dir}, true
+		}
+		return MoveIntent{}, false
+	}
+
+	if b.shouldPlaceBomb(gs) {
+		return MoveIntent{Player: b.Player, Action: ActionBomb}, true
+	}
+
+	if dir, ok := b.stepToward(gs, func(pos models.Position) bool {
+		return b.isTarget(gs, pos)
+	}); ok {
+		return MoveIntent{Player: b.Player, Action: ActionMove, Direction: dir}, true
+	}
+	return MoveIntent{}, false
+}
+
+// isTarget reports whether a tile holds a power-up or is next to an opponent or a block worth bombing.
+func (b *Bot) isTarget(gs *models.GameState, pos models.Position) bool {
+	if IsTileDangerousWithin(gs, pos, gs.Config.BombTimer) {
+		return false
+	}
+	for _, powerUp := range gs.PowerUps {
+		if powerUp.Position == pos && !powerUp.Type.IsCurse() {
+			return true
+		}
+	}
+	for _, dir := range models.Directions {
+		next := models.Position{X: pos.X + dir.Delta().X, Y: pos.Y + dir.Delta().Y}
+		if b.opponentAt(gs, next) || hasBlock(gs, next) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *Bot) opponentAt(gs *models.GameState, pos models.Position) bool {
+	for _, p := range gs.Players {
+		if p.ID == b.Player.ID || !p.Alive || p.Position != pos {
+			continue
+		}
+		if gs.Config.TeamMode && p.TeamID == b.Player.TeamID {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+// shouldPlaceBomb drops a bomb next to a block or opponent, but only if there is a safe tile to run to.
+func (b *Bot) shouldPlaceBomb(gs *models.GameState) bool {
+	if b.Player.BombsPlaced >= b.Player.BombCount || rand.Float64() >= BotBombChance {
+		return false
+	}
+
+	worthIt := false
+	for _, dir := range models.Directions {
+		next := models.Position{X: b.Player.Position.X + dir.Delta().X, Y: b.Player.Position.Y + dir.Delta().Y}
+		if hasBlock(gs, next) || b.opponentAt(gs, next) {
+			worthIt = true
+			break
+		}
+	}
+	if !worthIt {
+		return false
+	}
+
+	// Pretend the bomb is already there and check that an escape exists.
+	planned := &models.Bomb{Position: b.Player.Position, OwnerID: b.Player.ID, Timer: gs.Config.BombTimer, FlameRange: b.Player.FlameRange}
+	blast := make(map[models.Position]bool)
+	for _, pos := range BlastTiles(gs, planned) {
+		blast[pos] = true
+	}
+	_, ok := b.stepToward(gs, func(pos models.Position) bool {
+		return !blast[pos] && !IsTileDangerousWithin(gs, pos, gs.Config.BombTimer)
+	})
+	return ok
+}
+
+// stepToward runs a breadth-first search over walkable, non-dangerous tiles and returns
+// the first direction on the shortest path to a tile satisfying goal.
+func (b *Bot) stepToward(gs *models.GameState, goal func(models.Position) bool) (models.Direction, bool) {
+	type node struct {
+		pos   models.Position
+		first models.Direction
+	}
+
+	start := b.Player.Position
+	visited := map[models.Position]bool{start: true}
+	queue := []node{}
+
+	for _, dir := range models.Directions {
+		next := models.Position{X: start.X + dir.Delta().X, Y: start.Y + dir.Delta().Y}
+		if isPositionValid(next, b.Player, gs) && !hasFlame(gs, next) {
+			visited[next] = true
+			queue = append(queue, node{pos: next, first: dir})
+		}
+	}
+
+	for len(queue) > 0 && len(visited) < BotPathSearchMax {
+		current := queue[0]
+		queue = queue[1:]
+
+		if goal(current.pos) {
+			return current.first, true
+		}
+
+		for _, dir := range models.Directions {
+			next := models.Position{X: current.pos.X + dir.Delta().X, Y: current.pos.Y + dir.Delta().Y}
+			if visited[next] {
+				continue
+			}
+			visited[next] = true
+			if isPositionValid(next, b.Player, gs) && !hasFlame(gs, next) {
+				queue = append(queue, node{pos: next, first: current.first})
+			}
+		}
+	}
+	return "", false
+}
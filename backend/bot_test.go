@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"testing"
+)
+
+func TestBotAvoidsFlameTile(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	bot := &Bot{Player: gs.Players[0]}
+
+	// The power-up is two tiles to the right, but the tile in between is burning
+	gs.PowerUps = append(gs.PowerUps, &models.ActivePowerUp{Position: at(3, 1), Type: models.BombUp})
+	gs.Flames = append(gs.Flames, &models.Flame{Position: at(2, 1), Timer: FlameTime})
+
+	intent, ok := bot.Tick(gs)
+	if !ok {
+		t.Fatal("bot did nothing")
+	}
+	if intent.Action != ActionMove || intent.Direction != models.DirDown {
+		t.Errorf("bot chose %s %s, want to go around the flame (move down)", intent.Action, intent.Direction)
+	}
+
+	// With the flame gone the direct path is the shortest
+	gs.Flames = nil
+	bot.cooldown = 0
+	intent, _ = bot.Tick(gs)
+	if intent.Direction != models.DirRight {
+		t.Errorf("bot chose %s without the flame, want right", intent.Direction)
+	}
+}
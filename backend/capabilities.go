@@ -0,0 +1,76 @@
+package main
+
+import "bomberman-dom/models"
+
+const (
+	ModeClassic = "classic"
+	ModeTeam    = "team"
+
+	DefaultWaitTimer  = 20 // Seconds to wait for more players once MinPlayers joined
+	MinWaitTimer      = 5
+	MaxWaitTimer      = 60
+	DefaultStartTimer = 10 // Seconds of countdown before the game starts
+	MinStartTimer     = 3
+	MaxStartTimer     = 30
+
+	// No countdown may be shorter than a second, whatever its configured minimum
+	MinCountdownSeconds = 1
+
+	DefaultLives = 3 // Lives each player starts a game with
+	MinLives     = 1 // One life is "hardcore" mode
+	MaxLives     = 9
+
+	// Stats players start a game with. Speed stops mattering once MoveInterval reaches
+	// MinMoveInterval, so it is capped there.
+	DefaultStartingBombs = 1
+	MaxStartingBombs     = 8
+	DefaultStartingFlame = 1
+	MaxStartingFlame     = 8
+	DefaultStartingSpeed = 0
+	MaxStartingSpeed     = BaseMoveInterval - MinMoveInterval
+
+	MinBombTimer = 40  // Ticks; 2 seconds at 20 ticks/sec
+	MaxBombTimer = 300 // 15 seconds
+	MinFlameTime = 5
+	MaxFlameTime = 60
+
+	// AFK timeout in ticks; 0 turns it off, otherwise between 10 seconds and 5 minutes
+	MinAFKTimeout = 200
+	MaxAFKTimeout = 6000
+
+	// Round wins needed to take a series; 1 plays single games
+	MinSeriesWins = 1
+	MaxSeriesWins = 5
+)
+
+// GetCapabilities describes what the server supports so clients can build their lobby UI dynamically.
+func GetCapabilities() *models.Capabilities {
+	powerUps := []models.PowerUpInfo{}
+	for _, t := range models.PowerUpTypes {
+		powerUps = append(powerUps, models.PowerUpInfo{ID: t, Name: t.String()})
+	}
+
+	return &models.Capabilities{
+		Modes:             []string{ModeClassic, ModeTeam},
+		SpawnArrangements: []string{SpawnCorners, SpawnTeamAdjacent},
+		BlockDensities:    BlockDensities,
+		BlockPatterns:     BlockPatterns,
+		Settings: map[string]models.SettingRange{
+			"waitTimer":       {Min: MinWaitTimer, Max: MaxWaitTimer, Default: DefaultWaitTimer},
+			"startTimer":      {Min: MinStartTimer, Max: MaxStartTimer, Default: DefaultStartTimer},
+			"mapWidth":        {Min: MinMapSize, Max: MaxMapSize, Default: MapWidth},
+			"mapHeight":       {Min: MinMapSize, Max: MaxMapSize, Default: MapHeight},
+			"lives":           {Min: MinLives, Max: MaxLives, Default: DefaultLives},
+			"startingBombs":   {Min: DefaultStartingBombs, Max: MaxStartingBombs, Default: DefaultStartingBombs},
+			"startingFlame":   {Min: DefaultStartingFlame, Max: MaxStartingFlame, Default: DefaultStartingFlame},
+			"startingSpeed":   {Min: DefaultStartingSpeed, Max: MaxStartingSpeed, Default: DefaultStartingSpeed},
+			"bombTimer":       {Min: MinBombTimer, Max: MaxBombTimer, Default: BombTimer},
+			"flameTime":       {Min: MinFlameTime, Max: MaxFlameTime, Default: FlameTime},
+			"powerUpDropRate": {Min: 0, Max: 100, Default: DefaultPowerUpDropRate},
+			"afkTimeout":      {Min: 0, Max: MaxAFKTimeout, Default: 0},
+			"seriesWins":      {Min: MinSeriesWins, Max: MaxSeriesWins, Default: MinSeriesWins},
+			"maxPlayers":      {Min: 2, Max: len(SpawnPoints(MapWidth, MapHeight)), Default: 4},
+		},
+		PowerUps: powerUps,
+	}
+}
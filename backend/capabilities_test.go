@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGetCapabilities(t *testing.T) {
+	data, err := json.Marshal(GetCapabilities())
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	var caps struct {
+		Modes    []string `json:"modes"`
+		Settings map[string]struct {
+			Min, Max, Default int
+		} `json:"settings"`
+	}
+	if err := json.Unmarshal(data, &caps); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+
+	if len(caps.Modes) != 2 || caps.Modes[0] != ModeClassic || caps.Modes[1] != ModeTeam {
+		t.Errorf("modes = %v", caps.Modes)
+	}
+
+	want := map[string][3]int{
+		"waitTimer":  {MinWaitTimer, MaxWaitTimer, DefaultWaitTimer},
+		"startTimer": {MinStartTimer, MaxStartTimer, DefaultStartTimer},
+		"mapWidth":   {MinMapSize, MaxMapSize, MapWidth},
+		"mapHeight":  {MinMapSize, MaxMapSize, MapHeight},
+	}
+	for name, w := range want {
+		got, ok := caps.Settings[name]
+		if !ok {
+			t.Errorf("setting %q missing", name)
+			continue
+		}
+		if got.Min != w[0] || got.Max != w[1] || got.Default != w[2] {
+			t.Errorf("%s = %+v, want min %d max %d default %d", name, got, w[0], w[1], w[2])
+		}
+		if got.Default < got.Min || got.Default > got.Max {
+			t.Errorf("%s default %d outside [%d, %d]", name, got.Default, got.Min, got.Max)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxLengthChatOverConnection(t *testing.T) {
+	_, srv := newTestServer(t)
+	conn, _, err := dialWS(srv, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	sendJSON(t, conn, models.MSG_HELLO, &models.HelloRequest{Version: models.ProtocolVersion})
+	sendJSON(t, conn, models.MSG_JOIN_LOBBY, map[string]string{"nickname": "talker"})
+	readUntil(t, conn, models.MSG_LOBBY_UPDATE)
+
+	// 500 four-byte characters: the longest message allowed, and far more bytes than characters
+	longest := strings.Repeat("💣", MaxChatMessageLength)
+	sendJSON(t, conn, models.MSG_CHAT_MESSAGE, &models.ChatMessageRequest{Message: longest})
+	var chat models.ChatMessage
+	if err := json.Unmarshal(readUntil(t, conn, models.MSG_CHAT_MESSAGE).Data, &chat); err != nil {
+		t.Fatal(err)
+	}
+	if chat.Message != longest || chat.Nickname != "talker" {
+		t.Errorf("chat came back as %d bytes from %q, want the full message", len(chat.Message), chat.Nickname)
+	}
+
+	// One character more is refused, and the connection stays open
+	sendJSON(t, conn, models.MSG_CHAT_MESSAGE, &models.ChatMessageRequest{Message: longest + "!"})
+	if msg := readUntil(t, conn, models.MSG_ERROR); !strings.Contains(string(msg.Data), "cannot exceed") {
+		t.Errorf("overlong chat: error %s", msg.Data)
+	}
+	sendJSON(t, conn, models.MSG_PING, nil)
+	readUntil(t, conn, models.MSG_PONG)
+}
+
+func TestChatFloodRejected(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	now := time.Unix(1700000000, 0)
+	lh.chatLimiter.now = func() time.Time { return now }
+	spammer := addTestPlayer(lh, "spammer", true)
+	listener := addTestPlayer(lh, "listener", true)
+	drainMessages(t, spammer)
+	drainMessages(t, listener)
+
+	send := func() {
+		lh.handleChatMessage(spammer, &models.WebSocketMessage{
+			Type: models.MSG_CHAT_MESSAGE,
+			Data: &models.ChatMessageRequest{Message: "spam"},
+		})
+	}
+	for i := 0; i < ChatBurst+1; i++ {
+		send()
+	}
+	if got := len(messagesOfType(drainMessages(t, listener), models.MSG_CHAT_MESSAGE)); got != ChatBurst {
+		t.Errorf("listener got %d of %d rapid messages, want the first %d", got, ChatBurst+1, ChatBurst)
+	}
+	errs := messagesOfType(drainMessages(t, spammer), models.MSG_ERROR)
+	if len(errs) != 1 || !strings.Contains(string(errs[0].Data), "too fast") {
+		t.Fatalf("spammer errors = %v, want one rate limit error", errs)
+	}
+
+	// Once the bucket has refilled the player can talk again
+	now = now.Add(ChatRefill)
+	send()
+	if got := len(messagesOfType(drainMessages(t, listener), models.MSG_CHAT_MESSAGE)); got != 1 {
+		t.Errorf("listener got %d messages after the refill, want 1", got)
+	}
+}
+
+func TestLobbyChatStaysInLobby(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	member := addTestPlayer(lh, "member", true)
+	outsider := addTestPlayer(lh, "outsider", false) // Connected, not in the lobby
+	drainMessages(t, member)
+	drainMessages(t, outsider)
+
+	say := func(message, channel string) {
+		lh.handleChatMessage(member, &models.WebSocketMessage{
+			Type: models.MSG_CHAT_MESSAGE,
+			Data: &models.ChatMessageRequest{Message: message, Channel: channel},
+		})
+	}
+	channelsOf := func(p *models.WebSocketPlayer) []string {
+		var channels []string
+		for _, m := range messagesOfType(drainMessages(t, p), models.MSG_CHAT_MESSAGE) {
+			var chat models.ChatMessage
+			if err := json.Unmarshal(m.Data, &chat); err != nil {
+				t.Fatal(err)
+			}
+			channels = append(channels, chat.Channel)
+		}
+		return channels
+	}
+
+	say("lobby only", "")
+	if got := channelsOf(member); len(got) != 1 || got[0] != ChatChannelLobby {
+		t.Errorf("member got %v, want their lobby message", got)
+	}
+	if got := channelsOf(outsider); len(got) != 0 {
+		t.Errorf("lobby chat reached a player outside the lobby: %v", got)
+	}
+
+	say("hello everyone", ChatChannelGlobal)
+	for _, p := range []*models.WebSocketPlayer{member, outsider} {
+		if got := channelsOf(p); len(got) != 1 || got[0] != ChatChannelGlobal {
+			t.Errorf("%s got %v, want the global message", p.WebSocketID, got)
+		}
+	}
+	if len(lh.lobby.Messages) != 1 {
+		t.Errorf("lobby history has %d messages, want only the lobby one", len(lh.lobby.Messages))
+	}
+
+	say("psst", "team")
+	if errs := messagesOfType(drainMessages(t, member), models.MSG_ERROR); len(errs) != 1 {
+		t.Errorf("unknown channel: got %d errors, want 1", len(errs))
+	}
+	if got := channelsOf(outsider); len(got) != 0 {
+		t.Errorf("message on an unknown channel delivered: %v", got)
+	}
+}
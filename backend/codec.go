@@ -0,0 +1,113 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gorilla/websocket"
+)
+
+// Encodings a client can ask for in its hello. State updates are the only messages
+// sent in the binary encoding; everything else stays JSON text.
+const (
+	EncodingJSON = "json"
+	EncodingGob  = "gob"
+)
+
+// binaryStateMarker starts every binary state frame. JSON frames always start with '{',
+// so writePump can tell the two apart from the first byte.
+const binaryStateMarker byte = 0x01
+
+// binaryMessageMarker starts a binary frame sent by a client. Its gob payload is a
+// binaryMessage; clients may send any message this way, regardless of their hello encoding.
+const binaryMessageMarker byte = 0x02
+
+// binaryMessage is the gob payload of a binary client message. Data holds the message's JSON
+// data, since handlers decode their requests from JSON.
+type binaryMessage struct {
+	Type string
+	Data []byte
+}
+
+// binaryState is the gob payload of a binary MSG_GAME_STATE_UPDATE.
+type binaryState struct {
+	Type  string
+	State *models.ClientGameState
+}
+
+// encodeMessage marshals a message the way the player asked for in its hello.
+func encodeMessage(player *models.WebSocketPlayer, message *models.WebSocketMessage) ([]byte, error) {
+	if player.Encoding() == EncodingGob && message.Type == models.MSG_GAME_STATE_UPDATE {
+		if gs, ok := message.Data.(*models.GameState); ok {
+			return encodeBinaryState(gs)
+		}
+	}
+	return json.Marshal(message)
+}
+
+// encodeBinaryState gob-encodes a state update behind binaryStateMarker. gob ignores the
+// JSON marshaler, so the client schema is encoded directly to leave server-only fields out.
+func encodeBinaryState(gs *models.GameState) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(binaryStateMarker)
+	if err := gob.NewEncoder(&buf).Encode(&binaryState{Type: models.MSG_GAME_STATE_UPDATE, State: models.NewClientGameState(gs)}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeBinaryState is the inverse of encodeBinaryState.
+func decodeBinaryState(frame []byte) (*models.WebSocketMessage, error) {
+	if len(frame) == 0 || frame[0] != binaryStateMarker {
+		return nil, fmt.Errorf("not a binary state frame")
+	}
+	var decoded binaryState
+	if err := gob.NewDecoder(bytes.NewReader(frame[1:])).Decode(&decoded); err != nil {
+		return nil, err
+	}
+	return &models.WebSocketMessage{Type: decoded.Type, Data: decoded.State}, nil
+}
+
+// decodeBinaryMessage decodes a binary frame sent by a client into a message, with Data in the
+// same form ReadJSON would have produced.
+func decodeBinaryMessage(frame []byte) (*models.WebSocketMessage, error) {
+	if len(frame) == 0 || frame[0] != binaryMessageMarker {
+		return nil, fmt.Errorf("not a binary message frame")
+	}
+	var decoded binaryMessage
+	if err := gob.NewDecoder(bytes.NewReader(frame[1:])).Decode(&decoded); err != nil {
+		return nil, err
+	}
+	message := &models.WebSocketMessage{Type: decoded.Type}
+	if len(decoded.Data) > 0 {
+		if err := json.Unmarshal(decoded.Data, &message.Data); err != nil {
+			return nil, err
+		}
+	}
+	return message, nil
+}
+
+// decodeFrame decodes a client frame by its WebSocket type: text frames are JSON and binary
+// frames use the binary codec. Any other type is rejected.
+func decodeFrame(frameType int, data []byte) (*models.WebSocketMessage, error) {
+	switch frameType {
+	case websocket.TextMessage:
+		var message models.WebSocketMessage
+		if err := json.Unmarshal(data, &message); err != nil {
+			return nil, err
+		}
+		return &message, nil
+	case websocket.BinaryMessage:
+		return decodeBinaryMessage(data)
+	default:
+		return nil, fmt.Errorf("unsupported frame type %d", frameType)
+	}
+}
+
+// isBinaryFrame reports whether an encoded message must go out as a binary WebSocket frame.
+func isBinaryFrame(data []byte) bool {
+	return len(data) > 0 && data[0] == binaryStateMarker
+}
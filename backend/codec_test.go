@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// busyGame is a four-player game on the generated default map with a few bombs and flames
+// out, a typical mid-game state update.
+func busyGame() *models.GameState {
+	cfg := DefaultGameConfig()
+	cfg.MapSeed = 1
+	corners := []models.Position{at(1, 1), at(13, 1), at(1, 11), at(13, 11)}
+	players := make([]*models.Player, len(corners))
+	for i, pos := range corners {
+		p := NewGamePlayer(string(rune('a'+i)), "Player", cfg)
+		p.Position, p.SpawnPoint = pos, pos
+		players[i] = p
+	}
+	gs := NewGame(players, cfg)
+	for _, p := range players {
+		PlaceBomb(gs, p)
+	}
+	gs.Flames = append(gs.Flames, newFlame(at(3, 3), FlameTime, "a"), newFlame(at(3, 4), FlameTime, "a"))
+	gs.Tick = 42
+	return gs
+}
+
+func TestBinaryStateRoundTrip(t *testing.T) {
+	gs := busyGame()
+	frame, err := encodeBinaryState(gs)
+	if err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	if !isBinaryFrame(frame) {
+		t.Fatal("binary state not marked as a binary frame")
+	}
+	message, err := decodeBinaryState(frame)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if message.Type != models.MSG_GAME_STATE_UPDATE {
+		t.Errorf("decoded type %q, want %q", message.Type, models.MSG_GAME_STATE_UPDATE)
+	}
+
+	// The binary update must carry exactly what the JSON update does
+	want := jsonWithNullLists(t, gs)
+	got := jsonWithNullLists(t, message.Data)
+	if !bytes.Equal(got, want) {
+		t.Errorf("binary round trip differs from the JSON state\n got: %s\nwant: %s", got, want)
+	}
+}
+
+// jsonWithNullLists marshals v with every empty list turned into null. gob doesn't tell an
+// empty slice from a nil one, so this is how a decoded binary state compares with the JSON one.
+func jsonWithNullLists(t *testing.T, v interface{}) []byte {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var generic interface{}
+	if err := json.Unmarshal(data, &generic); err != nil {
+		t.Fatal(err)
+	}
+	var nullLists func(v interface{}) interface{}
+	nullLists = func(v interface{}) interface{} {
+		switch v := v.(type) {
+		case []interface{}:
+			if len(v) == 0 {
+				return nil
+			}
+			for i := range v {
+				v[i] = nullLists(v[i])
+			}
+		case map[string]interface{}:
+			for k := range v {
+				v[k] = nullLists(v[k])
+			}
+		}
+		return v
+	}
+	data, err = json.Marshal(nullLists(generic))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func BenchmarkStateEncoding(b *testing.B) {
+	gs := busyGame()
+	encoders := []struct {
+		name   string
+		encode func() ([]byte, error)
+	}{
+		{EncodingJSON, func() ([]byte, error) {
+			return json.Marshal(&models.WebSocketMessage{Type: models.MSG_GAME_STATE_UPDATE, Data: gs})
+		}},
+		{EncodingGob, func() ([]byte, error) { return encodeBinaryState(gs) }},
+	}
+	for _, enc := range encoders {
+		b.Run(enc.name, func(b *testing.B) {
+			var size int
+			for i := 0; i < b.N; i++ {
+				data, err := enc.encode()
+				if err != nil {
+					b.Fatal(err)
+				}
+				size = len(data)
+			}
+			b.ReportMetric(float64(size), "bytes/msg")
+		})
+	}
+}
+
+// clientBinaryFrame encodes a message the way a client sends it in a binary frame.
+func clientBinaryFrame(t *testing.T, msgType string, data interface{}) []byte {
+	t.Helper()
+	raw, err := json.Marshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	buf.WriteByte(binaryMessageMarker)
+	if err := gob.NewEncoder(&buf).Encode(&binaryMessage{Type: msgType, Data: raw}); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestBinaryFrameRouting(t *testing.T) {
+	lh, srv := newTestServer(t)
+	conn, _, err := dialWS(srv, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	// A binary hello reaches the same handler as a text one
+	frame := clientBinaryFrame(t, models.MSG_HELLO, &models.HelloRequest{Version: models.ProtocolVersion})
+	if err := conn.WriteMessage(websocket.BinaryMessage, frame); err != nil {
+		t.Fatalf("write binary frame: %v", err)
+	}
+	var reply models.HelloResponse
+	if err := json.Unmarshal(readUntil(t, conn, models.MSG_HELLO).Data, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Version != models.ProtocolVersion {
+		t.Errorf("hello reply = %+v, want version %d", reply, models.ProtocolVersion)
+	}
+	waitFor(t, "version recorded", func() bool {
+		players := connectedPlayers(lh)
+		return len(players) == 1 && players[0].ProtocolVersion() == models.ProtocolVersion
+	})
+
+	// Binary data that isn't a client message is refused, and the connection stays open
+	if err := conn.WriteMessage(websocket.BinaryMessage, []byte(`{"type":"hello"}`)); err != nil {
+		t.Fatalf("write binary frame: %v", err)
+	}
+	if errMsg := readUntil(t, conn, models.MSG_ERROR); !strings.Contains(string(errMsg.Data), "Invalid message format") {
+		t.Errorf("unmarked binary frame got %s, want an invalid format error", errMsg.Data)
+	}
+	sendJSON(t, conn, models.MSG_CHAT_MESSAGE, &models.ChatMessageRequest{Message: "still here", Channel: "global"})
+	readUntil(t, conn, models.MSG_CHAT_MESSAGE)
+}
+
+func TestDecodeFrameRejectsOtherTypes(t *testing.T) {
+	if _, err := decodeFrame(websocket.PingMessage, []byte(`{"type":"hello"}`)); err == nil {
+		t.Error("ping frame decoded as a message")
+	}
+}
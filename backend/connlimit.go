@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+)
+
+// DefaultMaxConnsPerIP is how many WebSocket connections one address may hold open at once.
+// Override it with MAX_CONNS_PER_IP.
+const DefaultMaxConnsPerIP = 8
+
+// maxConnsPerIPFromEnv reads MAX_CONNS_PER_IP, falling back to DefaultMaxConnsPerIP
+// if it is unset or not a positive number.
+func maxConnsPerIPFromEnv() int {
+	if n, err := strconv.Atoi(os.Getenv("MAX_CONNS_PER_IP")); err == nil && n > 0 {
+		return n
+	}
+	return DefaultMaxConnsPerIP
+}
+
+// ConnLimiter counts open connections per remote IP.
+type ConnLimiter struct {
+	limit  int
+	counts map[string]int
+	mu     sync.Mutex
+}
+
+func NewConnLimiter(limit int) *ConnLimiter {
+	return &ConnLimiter{limit: limit, counts: make(map[string]int)}
+}
+
+// Acquire takes a connection slot for ip, reporting false if it already holds limit connections.
+func (c *ConnLimiter) Acquire(ip string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts[ip] >= c.limit {
+		return false
+	}
+	c.counts[ip]++
+	return true
+}
+
+// Release gives back a slot taken by Acquire.
+func (c *ConnLimiter) Release(ip string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts[ip] <= 1 {
+		delete(c.counts, ip)
+		return
+	}
+	c.counts[ip]--
+}
+
+// remoteIP returns the client address of a request without its port. Forwarding headers
+// are ignored since any client can set them.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestConnectionLimitPerIP(t *testing.T) {
+	lh, srv := newTestServer(t)
+	lh.connLimiter = NewConnLimiter(3)
+
+	var conns []*websocket.Conn
+	for i := 0; i < 3; i++ {
+		conn, _, err := dialWS(srv, nil)
+		if err != nil {
+			t.Fatalf("connection %d of 3 refused: %v", i+1, err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+
+	_, resp, err := dialWS(srv, nil)
+	if err == nil {
+		t.Fatal("connection past the limit was upgraded")
+	}
+	if resp == nil || resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("connection past the limit got %v, want 429", resp)
+	}
+
+	// Closing a connection gives its slot back
+	conns[0].Close()
+	waitFor(t, "closed connection to release its slot", func() bool {
+		lh.connLimiter.mu.Lock()
+		defer lh.connLimiter.mu.Unlock()
+		return lh.connLimiter.counts["127.0.0.1"] == 2
+	})
+	conn, _, err := dialWS(srv, nil)
+	if err != nil {
+		t.Fatalf("connection refused after one closed: %v", err)
+	}
+	conn.Close()
+}
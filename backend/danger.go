@@ -0,0 +1,39 @@
+package main
+
+import "bomberman-dom/models"
+
+// IsTileDangerous reports whether a tile is burning now or will be hit by a bomb exploding
+// before the imminent threshold runs out. Blasts stop at walls and blocks exactly as in
+// CreateFlames, so tiles shielded by them are safe.
+func IsTileDangerous(gs *models.GameState, pos models.Position) bool {
+	return IsTileDangerousWithin(gs, pos, BombImminentThreshold(gs))
+}
+
+// IsTileDangerousWithin is IsTileDangerous with a custom lookahead in ticks.
+// Passing gs.Config.BombTimer treats every placed bomb as a threat.
+func IsTileDangerousWithin(gs *models.GameState, pos models.Position, ticks int) bool {
+	if hasFlame(gs, pos) {
+		return true
+	}
+	for _, bomb := range gs.Bombs {
+		if bomb.Timer > ticks {
+			continue
+		}
+		for _, tile := range BlastTiles(gs, bomb) {
+			if tile == pos {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hasFlame checks if an active flame is at a position.
+func hasFlame(gs *models.GameState, pos models.Position) bool {
+	for _, flame := range gs.Flames {
+		if flame.Position == pos {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"testing"
+)
+
+func TestIsTileDangerous(t *testing.T) {
+	gs := newTestGame(at(13, 11))
+	bomb := &models.Bomb{Position: at(2, 1), OwnerID: "p1", Timer: 1, FlameRange: 3}
+	gs.Bombs = append(gs.Bombs, bomb)
+
+	tests := []struct {
+		name string
+		pos  models.Position
+		want bool
+	}{
+		{"bomb tile", at(2, 1), true},
+		{"in line", at(5, 1), true},
+		{"beyond range", at(6, 1), false},
+		{"behind a wall", at(2, 3), false}, // (2,2) is a wall
+		{"off the line", at(3, 3), false},
+	}
+	for _, tt := range tests {
+		if got := IsTileDangerous(gs, tt.pos); got != tt.want {
+			t.Errorf("%s %v: dangerous = %v, want %v", tt.name, tt.pos, got, tt.want)
+		}
+	}
+
+	// A bomb with plenty of time left only counts with a longer lookahead
+	bomb.Timer = gs.Config.BombTimer
+	if IsTileDangerous(gs, at(3, 1)) {
+		t.Error("fresh bomb counted as imminent danger")
+	}
+	if !IsTileDangerousWithin(gs, at(3, 1), gs.Config.BombTimer) {
+		t.Error("fresh bomb ignored with a full-timer lookahead")
+	}
+
+	// Burning tiles are always dangerous
+	gs.Flames = append(gs.Flames, &models.Flame{Position: at(9, 9), Timer: 1})
+	if !IsTileDangerous(gs, at(9, 9)) {
+		t.Error("flame tile not dangerous")
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import "bomberman-dom/models"
+
+// DefaultVisionRadius is how many tiles a player can see in fog-of-war mode.
+const DefaultVisionRadius = 3
+
+// IsVisible reports whether a tile is within radius of the viewer and not hidden behind a wall.
+// Line of sight is traced tile by tile between the two centers; only walls block it.
+func IsVisible(gs *models.GameState, from, to models.Position, radius int) bool {
+	dx, dy := to.X-from.X, to.Y-from.Y
+	if dx*dx+dy*dy > radius*radius {
+		return false
+	}
+
+	// Bresenham line from `from` to `to`, checking every tile strictly in between
+	x, y := from.X, from.Y
+	stepX, stepY := 1, 1
+	if dx < 0 {
+		stepX, dx = -1, -dx
+	}
+	if dy < 0 {
+		stepY, dy = -1, -dy
+	}
+	err := dx - dy
+	for {
+		if x == to.X && y == to.Y {
+			return true
+		}
+		if (x != from.X || y != from.Y) && isWall(gs, models.Position{X: x, Y: y}) {
+			return false
+		}
+		e2 := 2 * err
+		if e2 > -dy {
+			err -= dy
+			x += stepX
+		}
+		if e2 < dx {
+			err += dx
+			y += stepY
+		}
+	}
+}
+
+// FilteredGameState returns the view of the game a player gets in fog-of-war mode.
+// The map layout and all flames stay visible; opponents, bombs and power-ups are only
+// included when the viewer can see their tile. Dead viewers see everything.
+func FilteredGameState(gs *models.GameState, viewer *models.Player) *models.GameState {
+	if !viewer.Alive {
+		return gs
+	}
+
+	view := *gs
+	visible := func(pos models.Position) bool {
+		return IsVisible(gs, viewer.Position, pos, gs.Config.VisionRadius)
+	}
+
+	view.Players = []*models.Player{}
+	for _, p := range gs.Players {
+		if p.ID == viewer.ID || visible(p.Position) {
+			view.Players = append(view.Players, p)
+		}
+	}
+
+	view.Bombs = []*models.Bomb{}
+	for _, bomb := range gs.Bombs {
+		if visible(bomb.Position) {
+			view.Bombs = append(view.Bombs, bomb)
+		}
+	}
+
+	view.PowerUps = []*models.ActivePowerUp{}
+	for _, powerUp := range gs.PowerUps {
+		if visible(powerUp.Position) {
+			view.PowerUps = append(view.PowerUps, powerUp)
+		}
+	}
+
+	return &view
+}
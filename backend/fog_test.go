@@ -0,0 +1,39 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"testing"
+)
+
+func TestFogRadius(t *testing.T) {
+	// Viewer in an open corridor on row 1; (2,2) is a wall below the corridor
+	gs := newTestGame(at(1, 1), at(4, 1), at(5, 1), at(3, 3))
+	viewer := gs.Players[0]
+	gs.Config.VisionRadius = 3
+
+	view := FilteredGameState(gs, viewer)
+	seen := map[string]bool{}
+	for _, p := range view.Players {
+		seen[p.ID] = true
+	}
+	if !seen["p1"] || !seen["p2"] {
+		t.Error("viewer or opponent at distance 3 hidden")
+	}
+	if seen["p3"] {
+		t.Error("opponent at distance 4 visible")
+	}
+	if seen["p4"] {
+		t.Error("opponent behind the wall at (2,2) visible")
+	}
+
+	gs.Bombs = []*models.Bomb{{Position: at(3, 1)}, {Position: at(7, 1)}}
+	if view := FilteredGameState(gs, viewer); len(view.Bombs) != 1 || view.Bombs[0].Position != at(3, 1) {
+		t.Errorf("visible bombs = %d, want only the one in range", len(view.Bombs))
+	}
+
+	// A dead player spectates the whole game
+	viewer.Alive = false
+	if view := FilteredGameState(gs, viewer); len(view.Players) != len(gs.Players) {
+		t.Error("dead viewer doesn't see everyone")
+	}
+}
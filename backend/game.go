@@ -1,32 +1,99 @@
 package main
 
-import "bomberman-dom/models"
+import (
+	"bomberman-dom/logging"
+	"bomberman-dom/models"
+	"os"
+	"time"
+)
 
-// NewGame initializes and returns a new GameState with players and a map.
-func NewGame(players []*models.Player) *models.GameState {
+// GameOverGraceTicks is how long the game keeps running after the win condition is first met,
+// so deaths a few ticks apart still count as simultaneous (0.5 seconds at 20 ticks/sec).
+const GameOverGraceTicks = 10
+
+// DefaultMaxGameDuration is the hard cap on a game's length, after which it is finished
+// with the standings at that moment. Override it with MAX_GAME_DURATION.
+const DefaultMaxGameDuration = 15 * time.Minute
+
+// maxGameTicksFromEnv reads MAX_GAME_DURATION (a Go duration such as "10m") and converts it
+// to game ticks, falling back to DefaultMaxGameDuration if it is unset or invalid.
+func maxGameTicksFromEnv() int {
+	d := DefaultMaxGameDuration
+	if v, err := time.ParseDuration(os.Getenv("MAX_GAME_DURATION")); err == nil && v > 0 {
+		d = v
+	}
+	return int(d / TickInterval)
+}
+
+// gameLogger is used by game logic that runs outside the LobbyHandler.
+var gameLogger = logging.Default()
+
+// DefaultGameConfig returns the rules of a game nobody has customized.
+func DefaultGameConfig() models.GameConfig {
+	return models.GameConfig{
+		MapWidth:      MapWidth,
+		MapHeight:     MapHeight,
+		BlockDensity:  BlockDensityNormal,
+		BlockPattern:  BlockPatternRandom,
+		PowerUpChance: float64(DefaultPowerUpDropRate) / 100,
+		StartingLives: DefaultLives,
+		StartingBombs: DefaultStartingBombs,
+		StartingFlame: DefaultStartingFlame,
+		StartingSpeed: DefaultStartingSpeed,
+		BombTimer:     BombTimer,
+		FlameTime:     FlameTime,
+		BombCooldown:  BombPlacementCooldown,
+		GameOverGrace: GameOverGraceTicks,
+		VisionRadius:  DefaultVisionRadius,
+		MaxTicks:      maxGameTicksFromEnv(),
+	}
+}
+
+// NewGame initializes and returns a new GameState with players and a map generated from cfg.
+func NewGame(players []*models.Player, cfg models.GameConfig) *models.GameState {
+	return NewGameWithMap(players, cfg, GenerateMap(cfg, MapRNG(cfg.MapSeed)))
+}
+
+// NewGameWithMap is NewGame on a map the caller already has, such as the previewed or custom map.
+func NewGameWithMap(players []*models.Player, cfg models.GameConfig, gameMap *models.Map) *models.GameState {
 	return &models.GameState{
 		Players:  players,
-		Map:      GenerateMap(MapWidth, MapHeight),
+		Map:      gameMap,
 		Bombs:    []*models.Bomb{},
 		Flames:   []*models.Flame{},
+		Hazards:  []*models.Hazard{},
 		PowerUps: []*models.ActivePowerUp{},
 		Status:   models.InProgress, // Or a 'Starting' status with a countdown
+		Config:   cfg,
+
+		WinningTeam:    -1,
+		GraceTicksLeft: -1,
 	}
 }
 
 // GameTick is the main loop of the game. It updates the state of all objects.
 // This function should be called repeatedly (e.g., by a ticker on the server).
 func GameTick(gs *models.GameState) {
-	if gs.Status != models.InProgress {
+	if gs == nil || gs.Status != models.InProgress {
 		return // Don't update the game if it's not running.
 	}
+	gs.Tick++
+	gs.DestroyedBlocks = gs.DestroyedBlocks[:0]
+
+	// 0. Retry moves that were blocked on an earlier tick, then advance held directions
+	TickMoveCooldowns(gs)
+	RetryPendingMoves(gs)
+	AdvanceContinuousMoves(gs)
+	SurrenderIdlePlayers(gs)
 
 	// --- UPDATE GAME OBJECTS ---
 	// 1. Update bombs (countdown, explosions, create flames)
 	UpdateBombs(gs)
 
-	// 2. Update flames (countdown, removal)
+	// 2. Update flames (countdown, removal). Lava cools before the flames clearing this tick add more.
+	UpdateHazards(gs)
 	UpdateFlames(gs) // You will need to create this function
+	BurnPlayersInFlames(gs)
 
 	// 3. Update player states (e.g., invincibility timers)
 	UpdatePlayers(gs)
@@ -36,9 +103,66 @@ func GameTick(gs *models.GameState) {
 	PowerUpPickups(gs)
 
 	// --- CHECK GAME OVER CONDITION ---
-	// 4. Check if the game has ended
+	// 4. Check if the game has ended. Once the win condition is met, the game keeps ticking
+	// for GameOverGrace ticks before the result is decided from whoever is still alive.
+	if gs.Config.MaxTicks > 0 && gs.Tick >= gs.Config.MaxTicks {
+		finishTimedOut(gs)
+		return
+	}
 	if IsGameOver(gs) {
+		if gs.GraceTicksLeft < 0 {
+			gs.GraceTicksLeft = gs.Config.GameOverGrace
+		}
+		if gs.GraceTicksLeft > 0 {
+			gs.GraceTicksLeft--
+			return
+		}
+
 		gs.Status = models.Finished
-		gs.Winner = GetWinner(gs)
+		if gs.Config.TeamMode {
+			gs.WinningTeam = GetWinningTeam(gs)
+			gs.Draw = gs.WinningTeam == -1
+		} else {
+			gs.Winner = GetWinner(gs)
+			gs.Draw = gs.Winner == nil
+		}
+	}
+}
+
+// finishTimedOut ends a game that hit its MaxTicks cap. The result comes from the current
+// standings: a sole leader wins, or their team if every leader shares one; otherwise it's a draw.
+func finishTimedOut(gs *models.GameState) {
+	gs.Status = models.Finished
+	gs.TimedOut = true
+	gs.Winner = nil
+	gs.WinningTeam = -1
+
+	var leaders []*models.Player
+	for _, s := range Standings(gs) {
+		if s.Place != 1 {
+			break
+		}
+		for _, p := range gs.Players {
+			if p.ID == s.PlayerID {
+				leaders = append(leaders, p)
+			}
+		}
+	}
+
+	if gs.Config.TeamMode {
+		for _, p := range leaders {
+			if gs.WinningTeam == -1 {
+				gs.WinningTeam = p.TeamID
+			} else if gs.WinningTeam != p.TeamID {
+				gs.WinningTeam = -1
+				break
+			}
+		}
+		gs.Draw = gs.WinningTeam == -1
+		return
+	}
+	if len(leaders) == 1 {
+		gs.Winner = leaders[0]
 	}
+	gs.Draw = gs.Winner == nil
 }
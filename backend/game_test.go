@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"testing"
+	"time"
+)
+
+// tickUntilFinished runs GameTick until the game ends, failing after limit ticks.
+func tickUntilFinished(t *testing.T, gs *models.GameState, limit int) {
+	t.Helper()
+	for i := 0; i < limit && gs.Status != models.Finished; i++ {
+		GameTick(gs)
+	}
+	if gs.Status != models.Finished {
+		t.Fatalf("game still running after %d ticks", limit)
+	}
+}
+
+// newTeamGame puts p1 and p2 (team 0) next to each other and p3 (team 1) across the map.
+func newTeamGame(friendlyFire bool) *models.GameState {
+	gs := newTestGame(at(1, 1), at(3, 1), at(13, 11))
+	gs.Config.TeamMode = true
+	gs.Config.FriendlyFire = friendlyFire
+	gs.Players[2].TeamID = 1
+	return gs
+}
+
+func TestTeamWin(t *testing.T) {
+	gs := newTeamGame(false)
+	KillPlayer(gs, gs.Players[2], gs.Players[0].ID)
+	tickUntilFinished(t, gs, gs.Config.GameOverGrace+2)
+
+	if gs.Draw || gs.WinningTeam != 0 {
+		t.Errorf("WinningTeam = %d, Draw = %v; want team 0 to win", gs.WinningTeam, gs.Draw)
+	}
+}
+
+func TestFriendlyFire(t *testing.T) {
+	for _, ff := range []bool{false, true} {
+		gs := newTeamGame(ff)
+		owner, mate := gs.Players[0], gs.Players[1]
+		owner.FlameRange = 3
+		PlaceBomb(gs, owner)
+		owner.Position = at(1, 3) // Step out of the blast
+		gs.Bombs[0].Timer = 1
+		GameTick(gs)
+
+		lost := gs.Config.StartingLives - mate.Lives
+		if ff && lost != 1 {
+			t.Errorf("friendly fire on: teammate lost %d lives, want 1", lost)
+		}
+		if !ff && lost != 0 {
+			t.Errorf("friendly fire off: teammate lost %d lives, want 0", lost)
+		}
+	}
+}
+
+func TestSimultaneousDeathIsDraw(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(3, 1))
+	for _, p := range gs.Players {
+		p.Lives = 1
+	}
+	// p1's bomb, placed long enough ago that it can hurt its owner too
+	gs.Tick = 100
+	gs.Bombs = append(gs.Bombs, &models.Bomb{Position: at(2, 1), OwnerID: "p1", Timer: 1, FlameRange: 1})
+
+	tickUntilFinished(t, gs, gs.Config.GameOverGrace+2)
+	if !gs.Draw || gs.Winner != nil {
+		t.Errorf("Draw = %v, Winner = %v; want a draw", gs.Draw, gs.Winner)
+	}
+	for _, p := range gs.Players {
+		if p.Alive {
+			t.Errorf("%s survived the blast", p.ID)
+		}
+	}
+}
+
+func TestWinnerDyingWithinGraceIsDraw(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	KillPlayer(gs, gs.Players[1], "p1")
+	GameTick(gs) // Win condition met: the grace period starts
+	if gs.Status != models.InProgress || gs.GraceTicksLeft <= 0 {
+		t.Fatalf("game ended without a grace period (status %v, grace %d)", gs.Status, gs.GraceTicksLeft)
+	}
+
+	// The last survivor dies a few ticks later, still within the grace period
+	GameTick(gs)
+	KillPlayer(gs, gs.Players[0], "p2")
+	tickUntilFinished(t, gs, gs.Config.GameOverGrace)
+	if !gs.Draw || gs.Winner != nil {
+		t.Errorf("Draw = %v, Winner = %v; want a draw", gs.Draw, gs.Winner)
+	}
+
+	// Outside the grace period, the survivor wins
+	gs = newTestGame(at(1, 1), at(13, 11))
+	KillPlayer(gs, gs.Players[1], "p1")
+	tickUntilFinished(t, gs, gs.Config.GameOverGrace+2)
+	if gs.Draw || gs.Winner != gs.Players[0] {
+		t.Errorf("Draw = %v, Winner = %v; want p1 to win", gs.Draw, gs.Winner)
+	}
+}
+
+func TestEliminationOrderAndSharedRanks(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 1), at(1, 11), at(13, 11))
+	p1, p2, p3, p4 := gs.Players[0], gs.Players[1], gs.Players[2], gs.Players[3]
+
+	gs.Tick = 1
+	KillPlayer(gs, p4, "p1")
+	gs.Tick = 2
+	KillPlayer(gs, p2, "p1")
+	KillPlayer(gs, p3, "p1")
+
+	wantRanks := map[string]int{"p4": 1, "p2": 2, "p3": 2}
+	if len(gs.EliminationOrder) != 3 || gs.EliminationOrder[0].PlayerID != "p4" {
+		t.Fatalf("EliminationOrder = %+v, want p4 first", gs.EliminationOrder)
+	}
+	for _, e := range gs.EliminationOrder {
+		if e.Rank != wantRanks[e.PlayerID] {
+			t.Errorf("%s eliminated with rank %d, want %d", e.PlayerID, e.Rank, wantRanks[e.PlayerID])
+		}
+	}
+
+	wantPlaces := map[string]int{p1.ID: 1, p2.ID: 2, p3.ID: 2, p4.ID: 4}
+	for _, s := range Standings(gs) {
+		if s.Place != wantPlaces[s.PlayerID] {
+			t.Errorf("%s placed %d, want %d", s.PlayerID, s.Place, wantPlaces[s.PlayerID])
+		}
+	}
+
+	// A tiebreaker separates players eliminated together
+	p3.BlocksDestroyed = 5
+	for _, s := range Standings(gs) {
+		if s.PlayerID == p3.ID && s.Place != 2 || s.PlayerID == p2.ID && s.Place != 3 {
+			t.Errorf("with the tiebreaker %s placed %d", s.PlayerID, s.Place)
+		}
+	}
+}
+
+func TestGameTickWithNilMap(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(3, 1))
+	gs.Map = nil
+	gs.Bombs = []*models.Bomb{{Position: at(1, 1), OwnerID: "p2", Timer: 1, FlameRange: 2}}
+	gs.PowerUps = []*models.ActivePowerUp{{Position: at(2, 1), Type: models.BombUp}}
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Fatalf("game with a nil map panicked: %v", r)
+		}
+	}()
+	for i := 0; i < 5; i++ {
+		ResolveMoves(gs, []MoveIntent{
+			{Player: gs.Players[0], Direction: models.DirRight},
+			{Player: gs.Players[1], Action: ActionBomb},
+		})
+		GameTick(gs)
+	}
+	if gs.Tick != 5 {
+		t.Errorf("game stopped at tick %d, want 5", gs.Tick)
+	}
+	if gs.Players[0].Position != at(1, 1) {
+		t.Errorf("player moved to %v on a game without a map", gs.Players[0].Position)
+	}
+}
+
+func TestMaxDurationCapFinishesWithResult(t *testing.T) {
+	t.Setenv("MAX_GAME_DURATION", "1s")
+	if got, want := maxGameTicksFromEnv(), int(time.Second/TickInterval); got != want {
+		t.Fatalf("MAX_GAME_DURATION=1s gives %d ticks, want %d", got, want)
+	}
+
+	for _, tc := range []struct {
+		name       string
+		blocks     [2]int
+		wantWinner string
+	}{
+		{"sole leader wins", [2]int{3, 1}, "p1"},
+		{"tied leaders draw", [2]int{2, 2}, ""},
+	} {
+		gs := newTestGame(at(1, 1), at(13, 11))
+		gs.Config.MaxTicks = 20
+		gs.Players[0].BlocksDestroyed, gs.Players[1].BlocksDestroyed = tc.blocks[0], tc.blocks[1]
+		tickUntilFinished(t, gs, 2*gs.Config.MaxTicks)
+
+		if gs.Tick != gs.Config.MaxTicks || !gs.TimedOut {
+			t.Errorf("%s: finished at tick %d (timed out %v), want the cap at %d", tc.name, gs.Tick, gs.TimedOut, gs.Config.MaxTicks)
+		}
+		switch {
+		case tc.wantWinner == "" && (!gs.Draw || gs.Winner != nil):
+			t.Errorf("%s: draw %v, winner %v; want a draw", tc.name, gs.Draw, gs.Winner)
+		case tc.wantWinner != "" && (gs.Draw || gs.Winner == nil || gs.Winner.ID != tc.wantWinner):
+			t.Errorf("%s: draw %v, winner %v; want %s", tc.name, gs.Draw, gs.Winner, tc.wantWinner)
+		}
+	}
+}
+
+func TestGameConfigPropagates(t *testing.T) {
+	cfg := DefaultGameConfig()
+	cfg.MapSeed = 1
+	cfg.MapWidth, cfg.MapHeight = 11, 9
+	cfg.BombTimer = 20
+	player, other := NewGamePlayer("p1", "Player 1", cfg), NewGamePlayer("p2", "Player 2", cfg)
+	player.Position, other.Position = at(1, 1), at(9, 7)
+	gs := NewGame([]*models.Player{player, other}, cfg)
+
+	if gs.Config != cfg {
+		t.Errorf("game config %+v, want %+v", gs.Config, cfg)
+	}
+	if gs.Map.Width != 11 || gs.Map.Height != 9 {
+		t.Errorf("map is %dx%d, want 11x9", gs.Map.Width, gs.Map.Height)
+	}
+	// The generated border follows the configured size
+	if !gs.Map.WallAt(at(10, 8)) || gs.Map.WallAt(at(9, 7)) {
+		t.Errorf("border walls don't match an 11x9 map:\n%s", gs.Map)
+	}
+
+	PlaceBomb(gs, player)
+	if len(gs.Bombs) != 1 || gs.Bombs[0].Timer != 20 {
+		t.Fatalf("bombs %+v, want one with the configured 20-tick fuse", gs.Bombs)
+	}
+	for len(gs.Flames) == 0 && gs.Tick < 2*BombTimer && gs.Status == models.InProgress {
+		GameTick(gs)
+	}
+	if gs.Tick != 20 {
+		t.Errorf("bomb exploded on tick %d, want 20", gs.Tick)
+	}
+}
+
+func TestNewGameWithMapKeepsMap(t *testing.T) {
+	cfg := DefaultGameConfig()
+	cfg.MapSeed = 1
+	gameMap := GenerateMap(cfg, MapRNG(cfg.MapSeed))
+	gs := NewGameWithMap([]*models.Player{NewGamePlayer("p1", "Player 1", cfg)}, cfg, gameMap)
+	if gs.Map != gameMap {
+		t.Error("game built on a different map than the one passed in")
+	}
+	if gs.Config != cfg || gs.Status != models.InProgress || gs.WinningTeam != -1 || gs.GraceTicksLeft != -1 {
+		t.Errorf("game set up differently from NewGame: %+v", gs)
+	}
+}
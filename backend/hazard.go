@@ -0,0 +1,49 @@
+package main
+
+import "bomberman-dom/models"
+
+// HazardTime is how long lava stays once the flame that left it clears (3 seconds at 20 ticks/sec).
+const HazardTime = 60
+
+// addHazard leaves lava on a tile, or restarts the timer of lava already there. Lava that is
+// topped up keeps its first owner.
+func addHazard(gs *models.GameState, pos models.Position, ownerID string) {
+	for _, hazard := range gs.Hazards {
+		if hazard.Position == pos {
+			hazard.Timer = HazardTime
+			return
+		}
+	}
+	gs.Hazards = append(gs.Hazards, &models.Hazard{Position: pos, Timer: HazardTime, OwnerID: ownerID})
+}
+
+// hazardAt reports whether lava covers a tile.
+func hazardAt(gs *models.GameState, pos models.Position) bool {
+	for _, hazard := range gs.Hazards {
+		if hazard.Position == pos {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateHazards cools lava by one tick and removes it once its timer runs out. Nobody can walk
+// into lava, but a player can be caught in it as it forms, e.g. while a Shield kept the flame
+// off them; they burn as soon as they can be hurt.
+func UpdateHazards(gs *models.GameState) {
+	remaining := gs.Hazards[:0]
+	for _, hazard := range gs.Hazards {
+		hazard.Timer--
+		if hazard.Timer <= 0 {
+			continue
+		}
+		remaining = append(remaining, hazard)
+		for _, player := range gs.Players {
+			if player.Position == hazard.Position && canBurn(gs, player, hazard.OwnerID) {
+				burnPlayer(gs, player, hazard.OwnerID)
+			}
+		}
+	}
+	clear(gs.Hazards[len(remaining):])
+	gs.Hazards = remaining
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"testing"
+)
+
+func TestLavaOutlastsFlamesAndBlocksMovement(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(5, 3))
+	walker := gs.Players[1]
+	gs.Bombs = []*models.Bomb{{Position: at(5, 5), OwnerID: "p1", Timer: 1, FlameRange: 1, Lava: true}}
+	lava := at(5, 4)
+
+	GameTick(gs)
+	exploded := gs.Tick
+	for len(gs.Flames) > 0 {
+		GameTick(gs)
+	}
+	cleared := gs.Tick
+	if cleared-exploded+1 != FlameTime {
+		t.Fatalf("flames lasted %d ticks, want %d", cleared-exploded+1, FlameTime)
+	}
+	if !hazardAt(gs, lava) {
+		t.Fatalf("no lava at %v once the flames cleared", lava)
+	}
+
+	// The lava stays HazardTime ticks past the flames and nobody can walk in meanwhile
+	for gs.Tick < cleared+HazardTime-1 {
+		GameTick(gs)
+	}
+	if !hazardAt(gs, lava) {
+		t.Fatalf("lava gone on tick %d, want it until tick %d", gs.Tick, cleared+HazardTime)
+	}
+	MovePlayer(walker, models.DirDown, gs)
+	walker.PendingDirection = "" // Don't let the blocked move retry on its own
+	if walker.Position != at(5, 3) {
+		t.Fatalf("walked into active lava at %v", walker.Position)
+	}
+
+	GameTick(gs)
+	if hazardAt(gs, lava) || len(gs.Hazards) != 0 {
+		t.Fatalf("lava still there on tick %d: %d hazards", gs.Tick, len(gs.Hazards))
+	}
+	MovePlayer(walker, models.DirDown, gs)
+	if walker.Position != lava || !walker.Alive || walker.Lives != DefaultLives {
+		t.Errorf("after the lava cooled: at %v, alive %v with %d lives; want unharmed on %v", walker.Position, walker.Alive, walker.Lives, lava)
+	}
+}
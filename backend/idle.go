@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"os"
+	"time"
+)
+
+const (
+	DefaultIdleTimeout = 5 * time.Minute
+	IdleSweepInterval  = 15 * time.Second
+)
+
+// idleTimeoutFromEnv reads IDLE_TIMEOUT (a Go duration such as "90s" or "5m"),
+// falling back to DefaultIdleTimeout when unset or invalid.
+func idleTimeoutFromEnv() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("IDLE_TIMEOUT")); err == nil && d > 0 {
+		return d
+	}
+	return DefaultIdleTimeout
+}
+
+// runIdleSweeper periodically disconnects idle connections so they stop holding lobby slots.
+func (lh *LobbyHandler) runIdleSweeper() {
+	ticker := time.NewTicker(IdleSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		lh.reapIdlePlayers()
+	}
+}
+
+// reapIdlePlayers closes every connection that has been silent longer than idleTimeout.
+// Players taking part in a running game are never dropped. Closing the connection ends
+// its readPump, which unregisters the player and broadcasts the freed slot.
+func (lh *LobbyHandler) reapIdlePlayers() []*models.WebSocketPlayer {
+	now := lh.now()
+	reaped := []*models.WebSocketPlayer{}
+
+	lh.hub.Mutex.RLock()
+	lh.lobby.Mutex.RLock()
+	for _, player := range lh.hub.Players {
+		if now.Sub(player.LastActive()) <= lh.idleTimeout {
+			continue
+		}
+		if lh.isInActiveGame(player) {
+			continue
+		}
+		reaped = append(reaped, player)
+	}
+	lh.lobby.Mutex.RUnlock()
+	lh.hub.Mutex.RUnlock()
+
+	for _, player := range reaped {
+		lh.logger.Infof("Player %s idle for %v, disconnecting", player.WebSocketID, now.Sub(player.LastActive()).Round(time.Second))
+		if player.Conn != nil {
+			player.Conn.Close()
+		}
+	}
+	return reaped
+}
+
+// isInActiveGame reports whether the player is in the lobby while a game is running.
+// The caller must hold lh.lobby.Mutex.
+func (lh *LobbyHandler) isInActiveGame(player *models.WebSocketPlayer) bool {
+	if !lh.gameRunning {
+		return false
+	}
+	_, inLobby := lh.lobby.Players[player.WebSocketID]
+	return inLobby
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReapIdlePlayersWithFrozenClock(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	start := time.Unix(1700000000, 0)
+	lh.now = func() time.Time { return start }
+	lh.idleTimeout = time.Minute
+
+	stale := addTestPlayer(lh, "stale", false)
+	fresh := addTestPlayer(lh, "fresh", false)
+	playing := addTestPlayer(lh, "playing", true)
+
+	// Freeze the clock two minutes later; only fresh has spoken since
+	now := start.Add(2 * time.Minute)
+	lh.now = func() time.Time { return now }
+	fresh.Touch(now.Add(-30 * time.Second))
+
+	lh.lobby.Mutex.Lock()
+	lh.lobby.GameStarted = true
+	lh.gameRunning = true
+	lh.lobby.Mutex.Unlock()
+	lh.GameState = newTestGame(at(1, 1), at(13, 11))
+
+	reaped := lh.reapIdlePlayers()
+	if len(reaped) != 1 || reaped[0] != stale {
+		t.Fatalf("reaped %d players, want only the stale one", len(reaped))
+	}
+
+	// Once the round is over, the idle player from it is fair game too
+	lh.lobby.Mutex.Lock()
+	lh.lobby.GameStarted = false
+	lh.gameRunning = false
+	lh.lobby.Mutex.Unlock()
+	reaped = lh.reapIdlePlayers()
+	found := false
+	for _, p := range reaped {
+		if p == fresh {
+			t.Error("fresh player reaped")
+		}
+		found = found || p == playing
+	}
+	if !found {
+		t.Error("idle player from a finished game was kept")
+	}
+}
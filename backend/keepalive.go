@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"time"
+)
+
+const (
+	DefaultPingInterval = 54 * time.Second
+	DefaultReadDeadline = 60 * time.Second
+)
+
+// Keepalive sets how often the server pings each connection and how long a connection may stay
+// silent, pongs included, before it is treated as dropped. ReadDeadline is always longer than
+// PingInterval, otherwise a healthy client would time out between two pings.
+type Keepalive struct {
+	PingInterval time.Duration
+	ReadDeadline time.Duration
+}
+
+// NewKeepalive builds a Keepalive, filling in defaults for zero values. A read deadline that is
+// not longer than the ping interval is replaced by one in the default 9:10 ratio to it.
+func NewKeepalive(pingInterval, readDeadline time.Duration) Keepalive {
+	if pingInterval <= 0 {
+		pingInterval = DefaultPingInterval
+	}
+	if readDeadline <= 0 && pingInterval == DefaultPingInterval {
+		readDeadline = DefaultReadDeadline
+	}
+	if readDeadline <= pingInterval {
+		readDeadline = pingInterval * 10 / 9
+	}
+	return Keepalive{PingInterval: pingInterval, ReadDeadline: readDeadline}
+}
+
+// keepaliveFromEnv reads PING_INTERVAL and READ_DEADLINE (Go durations such as "10s"). Unset
+// or invalid values fall back as in NewKeepalive, so lowering PING_INTERVAL alone is enough
+// to detect dropped mobile connections sooner.
+func keepaliveFromEnv() Keepalive {
+	return NewKeepalive(durationFromEnv("PING_INTERVAL"), durationFromEnv("READ_DEADLINE"))
+}
+
+// durationFromEnv parses an env variable as a positive duration, or returns 0.
+func durationFromEnv(name string) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(name)); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNewKeepaliveDeadlineOutlastsInterval(t *testing.T) {
+	for _, tc := range []struct {
+		interval, deadline time.Duration
+		want               Keepalive
+	}{
+		{0, 0, Keepalive{DefaultPingInterval, DefaultReadDeadline}},
+		{9 * time.Second, 0, Keepalive{9 * time.Second, 10 * time.Second}},
+		{9 * time.Second, 5 * time.Second, Keepalive{9 * time.Second, 10 * time.Second}},
+		{9 * time.Second, 20 * time.Second, Keepalive{9 * time.Second, 20 * time.Second}},
+	} {
+		if got := NewKeepalive(tc.interval, tc.deadline); got != tc.want {
+			t.Errorf("NewKeepalive(%v, %v) = %+v, want %+v", tc.interval, tc.deadline, got, tc.want)
+		}
+	}
+}
+
+func TestMissedPongDisconnects(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	lh.keepalive = NewKeepalive(50*time.Millisecond, 150*time.Millisecond)
+	srv := httptest.NewServer(NewServeMux(lh))
+	defer srv.Close()
+
+	// A client that never reads never answers the pings
+	silent, _, err := dialWS(srv, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer silent.Close()
+	waitFor(t, "registration", func() bool { return len(connectedPlayers(lh)) == 1 })
+
+	// One that reads answers them automatically and stays connected
+	live, _, err := dialWS(srv, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer live.Close()
+	go func() {
+		for {
+			if _, _, err := live.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	waitFor(t, "silent client dropped", func() bool { return len(connectedPlayers(lh)) == 1 })
+	time.Sleep(3 * lh.keepalive.ReadDeadline)
+	if n := len(connectedPlayers(lh)); n != 1 {
+		t.Errorf("%d connections left after several read deadlines, want only the one answering pings", n)
+	}
+
+	// The server closed the silent client's connection rather than just forgetting it
+	silent.SetReadDeadline(time.Now().Add(time.Second))
+	for {
+		_, _, err := silent.ReadMessage()
+		if err == nil {
+			continue
+		}
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() {
+			t.Error("silent client's connection still open")
+		}
+		break
+	}
+}
@@ -1,30 +1,152 @@
 package main
 
 import (
+	"bomberman-dom/logging"
 	"bomberman-dom/models"
+	"bytes"
 	"encoding/json"
-	"log"
+	"fmt"
+	"maps"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gorilla/websocket"
 )
 
+const (
+	// MaxChatMessageLength is the longest chat message accepted, in characters.
+	MaxChatMessageLength = 500
+
+	// Chat channels. Lobby chat reaches the sender's lobby and is kept in its history; global
+	// chat, only sent when a client asks for it, reaches every connection and is not kept.
+	ChatChannelLobby  = "lobby"
+	ChatChannelGlobal = "global"
+
+	// JoinHintWait is the error hint for a join refused because a game is running. Players
+	// outside the game can't watch it, so the client should retry once the game is over.
+	JoinHintWait = "wait_for_game_end"
+
+	// MaxMessageSize is the largest frame read from a client. A max-length chat message
+	// can take up to 12 bytes per character once JSON-escaped (a \uXXXX surrogate pair),
+	// so 500 characters fit in 6000 bytes with room left for the envelope.
+	MaxMessageSize = 8192
+
+	// TickInterval is the game loop period: 20 ticks per second.
+	TickInterval = 50 * time.Millisecond
+
+	// StateKeepaliveTicks is the longest gap between two state updates while nothing changes (1 second).
+	StateKeepaliveTicks = 20
+)
+
+// defaultAllowedOrigins are accepted when ALLOWED_ORIGINS is not set (local dev).
+var defaultAllowedOrigins = []string{
+	"http://localhost:3000",
+	"http://127.0.0.1:3000",
+	"http://localhost:8080",
+	"http://127.0.0.1:8080",
+}
+
+// allowedOrigins is read from the comma-separated ALLOWED_ORIGINS env variable.
+var allowedOrigins = loadAllowedOrigins(os.Getenv("ALLOWED_ORIGINS"))
+
 var upgrader = websocket.Upgrader{
 	ReadBufferSize:  1024,
 	WriteBufferSize: 1024,
 	CheckOrigin: func(r *http.Request) bool {
-		return true
+		return isOriginAllowed(r.Header.Get("Origin"), allowedOrigins)
 	},
 }
 
+// loadAllowedOrigins parses a comma-separated origin list, falling back to the dev defaults.
+func loadAllowedOrigins(value string) []string {
+	origins := []string{}
+	for _, origin := range strings.Split(value, ",") {
+		origin = strings.TrimRight(strings.TrimSpace(origin), "/")
+		if origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	if len(origins) == 0 {
+		return defaultAllowedOrigins
+	}
+	return origins
+}
+
+// isOriginAllowed reports whether a request Origin header matches the allowed list.
+// Requests without an Origin header come from non-browser clients and are accepted.
+// A rejected origin makes the upgrader answer with 403 Forbidden.
+func isOriginAllowed(origin string, allowed []string) bool {
+	if origin == "" {
+		return true
+	}
+	origin = strings.TrimRight(origin, "/")
+	for _, a := range allowed {
+		if a == "*" || strings.EqualFold(a, origin) {
+			return true
+		}
+	}
+	return false
+}
+
 type LobbyHandler struct {
 	hub       *models.Hub
 	lobby     *models.Lobby
 	GameState *models.GameState
+	logger    *logging.Logger
+	metrics   *Metrics
+	bots      []*Bot // Bots filling empty slots in the current game
+
+	// True from startGame until the game loop is done with the game. Guarded by lobby.Mutex;
+	// only the loop touches the GameState while it runs, so other goroutines check this instead
+	gameRunning bool
+
+	// countdownGen identifies the live wait/start countdown; bumping it (under the lobby mutex)
+	// makes any older countdown goroutine exit at its next tick
+	countdownGen uint64
+
+	// Map generated when the start countdown began, reused by startGame if previewGen is still current
+	previewMap *models.Map
+	previewGen uint64
+
+	// Moves received from clients, applied in order at the start of the next tick
+	pendingMoves []MoveIntent
+	movesMutex   sync.Mutex
+
+	// Players waiting for a full state, served by the game loop after the next tick
+	pendingResyncs []*models.WebSocketPlayer
+	// Players waiting for their own stats, served the same way
+	pendingStats []*models.WebSocketPlayer
+	// Emotes waiting to be broadcast, served the same way since they carry the sender's position
+	pendingEmotes []pendingEmote
+
+	overflowPolicy string           // What to do when a player's Send buffer is full
+	idleTimeout    time.Duration    // Connections silent for longer than this are dropped
+	keepalive      Keepalive        // Ping interval and read deadline for dead connection detection
+	maxGameTicks   int              // Games still running after this many ticks are force-finished
+	now            func() time.Time // Clock used by the idle sweeper and countdowns
+	chatLimiter    *RateLimiter     // Chat flood protection, keyed by WebSocketID
+	emoteLimiter   *RateLimiter     // Same for in-game emotes
+	resyncLimiter  *RateLimiter     // Same for full state requests
+	statsLimiter   *RateLimiter     // Same for stats requests
+	connLimiter    *ConnLimiter     // Open connections per remote IP
+	motd           *MOTD            // Message of the day in the welcome message
+
+	// Sleeps on the same clock as now; countdowns wait with it
+	sleep func(time.Duration)
 }
 
-func NewLobbyHandler() *LobbyHandler {
+// NewLobbyHandler creates the lobby handler. A nil logger falls back to logging.Default().
+func NewLobbyHandler(logger *logging.Logger) *LobbyHandler {
+	if logger == nil {
+		logger = logging.Default()
+	}
+
 	hub := &models.Hub{
 		Players:    make(map[string]*models.WebSocketPlayer),
 		Register:   make(chan *models.WebSocketPlayer),
@@ -41,19 +163,53 @@ func NewLobbyHandler() *LobbyHandler {
 		GameStarted: false,
 		CreatedAt:   time.Now(),
 		Messages:    make([]models.ChatMessage, 0),
-		WaitTimer:   20,
-		StartTimer:  10,
+		WaitTimer:   DefaultWaitTimer,
+		StartTimer:  DefaultStartTimer,
 		Host:        "",
 		Status:      "waiting",
+
+		Mode:             ModeClassic,
+		FriendlyFire:     false,
+		SpawnArrangement: SpawnCorners,
+		SymmetricMap:     false,
+		BlockDensity:     BlockDensityNormal,
+		BlockPattern:     BlockPatternRandom,
+		MapWidth:         MapWidth,
+		MapHeight:        MapHeight,
+		StartingLives:    DefaultLives,
+		StartingBombs:    DefaultStartingBombs,
+		StartingFlame:    DefaultStartingFlame,
+		StartingSpeed:    DefaultStartingSpeed,
+		BombTimer:        BombTimer,
+		FlameTime:        FlameTime,
+		PowerUpDropRate:  DefaultPowerUpDropRate,
+		SeriesWins:       MinSeriesWins,
+		SeriesScore:      map[string]int{},
 	}
 
 	lobbyHandler := &LobbyHandler{
 		hub:       hub,
 		lobby:     singleLobby,
 		GameState: nil, // GameState is nil until the game starts
+		logger:    logger,
+		metrics:   NewMetrics(),
+
+		chatLimiter:    NewRateLimiter(ChatBurst, ChatRefill),
+		emoteLimiter:   NewRateLimiter(EmoteBurst, EmoteRefill),
+		resyncLimiter:  NewRateLimiter(ResyncBurst, ResyncRefill),
+		statsLimiter:   NewRateLimiter(StatsBurst, StatsRefill),
+		connLimiter:    NewConnLimiter(maxConnsPerIPFromEnv()),
+		motd:           motdFromEnv(),
+		overflowPolicy: overflowPolicyFromEnv(),
+		idleTimeout:    idleTimeoutFromEnv(),
+		keepalive:      keepaliveFromEnv(),
+		maxGameTicks:   maxGameTicksFromEnv(),
+		now:            time.Now,
+		sleep:          time.Sleep,
 	}
 
 	go lobbyHandler.run()
+	go lobbyHandler.runIdleSweeper()
 	return lobbyHandler
 }
 
@@ -73,20 +229,31 @@ func (lh *LobbyHandler) run() {
 }
 
 func (lh *LobbyHandler) ServeWS(w http.ResponseWriter, r *http.Request) {
+	ip := remoteIP(r)
+	if !lh.connLimiter.Acquire(ip) {
+		lh.logger.Warnf("Refusing connection from %s: per-IP connection limit reached", ip)
+		http.Error(w, "Too many connections", http.StatusTooManyRequests)
+		return
+	}
+
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
-		log.Printf("WebSocket upgrade error: %v", err)
+		lh.connLimiter.Release(ip)
+		lh.logger.Errorf("WebSocket upgrade error: %v", err)
 		return
 	}
 
 	player := &models.WebSocketPlayer{
 		WebSocketID: generatePlayerID(),
+		RemoteIP:    ip,
 		Conn:        conn,
 		Send:        make(chan []byte, 256),
+		State:       make(chan []byte, 1),
 		IsConnected: true,
 		IsActive:    true,
-		JoinedAt:    time.Now(),
+		JoinedAt:    lh.now(),
 	}
+	player.Touch(lh.now())
 
 	lh.hub.Register <- player
 
@@ -99,17 +266,36 @@ func (lh *LobbyHandler) registerPlayer(player *models.WebSocketPlayer) {
 	defer lh.hub.Mutex.Unlock()
 
 	lh.hub.Players[player.WebSocketID] = player
-	log.Printf("✅ Player %s connected", player.WebSocketID)
+	lh.metrics.activeConnections.Add(1)
+	lh.metrics.totalConnections.Add(1)
+	lh.logger.Infof("Player %s connected", player.WebSocketID)
 
+	motd, motdAsChat := lh.motd.Get()
 	welcomeMsg := &models.WebSocketMessage{
 		Type: models.MSG_SUCCESS,
 		Data: map[string]interface{}{
 			"message":     "Connected successfully - please provide nickname to join lobby",
 			"playerId":    player.WebSocketID,
 			"playerCount": len(lh.lobby.Players),
+			"motd":        motd,
 		},
 	}
 	lh.sendToPlayer(player, welcomeMsg)
+
+	// Only this player sees the MOTD line; it never enters the lobby's chat history
+	if motd != "" && motdAsChat {
+		lh.sendToPlayer(player, &models.WebSocketMessage{
+			Type: models.MSG_CHAT_MESSAGE,
+			Data: models.ChatMessage{
+				ID:        generateChatID(),
+				Nickname:  "Server",
+				Message:   motd,
+				Timestamp: time.Now(),
+				Type:      "system",
+				Channel:   ChatChannelLobby,
+			},
+		})
+	}
 }
 
 func (lh *LobbyHandler) unregisterPlayer(player *models.WebSocketPlayer) {
@@ -117,52 +303,97 @@ func (lh *LobbyHandler) unregisterPlayer(player *models.WebSocketPlayer) {
 	defer lh.hub.Mutex.Unlock()
 
 	if _, exists := lh.hub.Players[player.WebSocketID]; exists {
-		if lh.GameState != nil && lh.GameState.Status == models.InProgress {
+		lh.lobby.Mutex.Lock()
+		gameRunning := lh.gameRunning
+		if gameRunning {
+			// The game loop eliminates the player at the start of its next tick
 			for _, gamePlayer := range lh.GameState.Players {
 				if gamePlayer.ID == player.WebSocketID {
-					gamePlayer.Alive = false
+					lh.movesMutex.Lock()
+					lh.pendingMoves = append(lh.pendingMoves, MoveIntent{Player: gamePlayer, Action: ActionLeave})
+					lh.movesMutex.Unlock()
 					break
 				}
 			}
 		}
 
-		lh.lobby.Mutex.Lock()
 		delete(lh.lobby.Players, player.WebSocketID)
+		lh.chatLimiter.Forget(player.WebSocketID)
+		lh.emoteLimiter.Forget(player.WebSocketID)
+		lh.resyncLimiter.Forget(player.WebSocketID)
+		lh.statsLimiter.Forget(player.WebSocketID)
 		playerCount := len(lh.lobby.Players)
 
-		// Reset game status if game was in progress but now we don't have enough players
-		if lh.lobby.Status == "playing" && playerCount < lh.lobby.MinPlayers {
-			log.Printf("🔄 Resetting game status: not enough players (%d/%d)", playerCount, lh.lobby.MinPlayers)
+		// A running game goes on without the player and the win condition decides the match.
+		// The game loop abandons it once nobody is left to play it.
+		if playerCount == 0 {
+			lh.resetEmptyLobby()
+		}
+
+		// Reopen the lobby after a finished game if there aren't enough players for another
+		if lh.lobby.Status == "playing" && !gameRunning && playerCount < lh.minPlayersToStart() {
+			lh.logger.Warnf("Resetting game status: not enough players (%d/%d)", playerCount, lh.minPlayersToStart())
 			lh.lobby.Status = "waiting"
 			lh.lobby.GameStarted = false
 		}
 
-		if lh.lobby.Host == player.WebSocketID && playerCount > 0 {
-			for playerID := range lh.lobby.Players {
-				lh.lobby.Host = playerID
-				break
+		// Cancel a running countdown if the lobby dropped below the minimum
+		if (lh.lobby.Status == "waiting_for_players" || lh.lobby.Status == "starting") && playerCount < lh.minPlayersToStart() {
+			lh.logger.Infof("Countdown cancelled: not enough players (%d/%d)", playerCount, lh.minPlayersToStart())
+			lh.lobby.Status = "waiting"
+			lh.beginCountdown()
+		}
+
+		// Hand the host role to the longest-waiting remaining player
+		var newHost *models.WebSocketPlayer
+		if lh.lobby.Host == player.WebSocketID {
+			if playerCount > 0 {
+				newHost = playersByJoinOrder(lh.lobby)[0]
+				lh.lobby.Host = newHost.WebSocketID
+			} else {
+				lh.lobby.Host = ""
 			}
 		}
+		gameStarted := lh.lobby.GameStarted
 		lh.lobby.Mutex.Unlock()
 
 		delete(lh.hub.Players, player.WebSocketID)
-		close(player.Send)
+		lh.connLimiter.Release(player.RemoteIP)
+		player.CloseSend()
 		player.IsConnected = false
-		log.Printf("❌ Player %s disconnected", player.WebSocketID)
+		lh.metrics.activeConnections.Add(-1)
+		lh.logger.Infof("Player %s disconnected", player.WebSocketID)
 
-		if !lh.lobby.GameStarted && playerCount > 0 {
+		if newHost != nil {
+			lh.logger.Infof("Host left, %s is the new host", newHost.Name)
+			lh.broadcastToLobby("", &models.WebSocketMessage{
+				Type: models.MSG_HOST_CHANGED,
+				Data: &models.HostChangedEvent{
+					HostID:   newHost.WebSocketID,
+					Nickname: newHost.Name,
+				},
+			})
+		}
+
+		if playerCount > 0 {
+			message := "Player left the lobby"
+			if gameRunning {
+				message = "Player disconnected during the game"
+			}
 			lh.broadcastToLobby("", &models.WebSocketMessage{
 				Type: models.MSG_PLAYER_LEFT,
 				Data: &models.PlayerLeftEvent{
 					PlayerID:    player.WebSocketID,
 					Nickname:    player.Name,
 					PlayerCount: playerCount,
-					Message:     "Player left the lobby", // More accurate message
+					Message:     message,
 				},
 			})
 
 			// Send updated lobby status after player left
-			lh.sendLobbyUpdate()
+			if !gameStarted {
+				lh.sendLobbyUpdate()
+			}
 		}
 	}
 }
@@ -187,30 +418,63 @@ func (lh *LobbyHandler) broadcastToLobby(lobbyID string, message *models.WebSock
 	}
 }
 
+// broadcastToAll sends a message to every connection on the server, whether or not it has
+// joined a lobby.
+func (lh *LobbyHandler) broadcastToAll(message *models.WebSocketMessage) {
+	lh.hub.Mutex.RLock()
+	defer lh.hub.Mutex.RUnlock()
+
+	for _, player := range lh.hub.Players {
+		lh.sendToPlayer(player, message)
+	}
+}
+
 func (lh *LobbyHandler) sendToPlayer(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
 	if !player.IsConnected {
 		return
 	}
 
-	data, err := json.Marshal(message)
+	data, err := encodeMessage(player, message)
 	if err != nil {
-		log.Printf("Error marshaling message: %v", err)
+		lh.logger.Errorf("Error marshaling message: %v", err)
+		return
+	}
+
+	// State updates supersede each other, so they skip the queue and overwrite the latest one
+	if message.Type == models.MSG_GAME_STATE_UPDATE && player.OfferState(data) {
+		lh.metrics.messagesSent.Add(1)
 		return
 	}
 
 	select {
 	case player.Send <- data:
+		lh.metrics.messagesSent.Add(1)
 	default:
-		close(player.Send)
-		delete(lh.hub.Players, player.WebSocketID)
+		if enqueueOnOverflow(player, data, lh.overflowPolicy) {
+			lh.metrics.messagesSent.Add(1)
+			return
+		}
+		// The client can't keep up. Evict it through the Unregister channel so the hub map
+		// and send channel are only ever touched by unregisterPlayer under the hub lock.
+		// This runs in its own goroutine because sendToPlayer may be called from run() itself.
+		if player.MarkEvicting() {
+			lh.logger.Warnf("Send buffer full for player %s, evicting", player.WebSocketID)
+			go func() { lh.hub.Unregister <- player }()
+		}
 	}
 }
 
 func (lh *LobbyHandler) sendError(player *models.WebSocketPlayer, errMsg string) {
+	lh.sendErrorWithHint(player, errMsg, "")
+}
+
+// sendErrorWithHint is sendError with a machine-readable hint telling the client what to do next.
+func (lh *LobbyHandler) sendErrorWithHint(player *models.WebSocketPlayer, errMsg, hint string) {
 	errorResponse := &models.ErrorResponse{
 		Code:    400,
 		Message: errMsg,
 		Type:    "error",
+		Hint:    hint,
 	}
 
 	message := &models.WebSocketMessage{
@@ -227,29 +491,40 @@ func (lh *LobbyHandler) readPump(player *models.WebSocketPlayer) {
 		player.Conn.Close()
 	}()
 
-	player.Conn.SetReadLimit(512)
-	player.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
-	player.Conn.SetPongHandler(func(string) error {
-		player.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
+	player.Conn.SetReadLimit(MaxMessageSize)
+	player.Conn.SetReadDeadline(time.Now().Add(lh.keepalive.ReadDeadline))
+	player.Conn.SetPongHandler(func(appData string) error {
+		player.Conn.SetReadDeadline(time.Now().Add(lh.keepalive.ReadDeadline))
+		if latency, ok := latencyFromPingPayload(appData, time.Now()); ok {
+			player.SetLatency(latency)
+		}
 		return nil
 	})
 
 	for {
-		var message models.WebSocketMessage
-		err := player.Conn.ReadJSON(&message)
+		frameType, data, err := player.Conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
-				log.Printf("WebSocket error: %v", err)
+				lh.logger.Warnf("WebSocket error: %v", err)
 			}
 			break
 		}
 
-		lh.handleMessage(player, &message)
+		message, err := decodeFrame(frameType, data)
+		if err != nil {
+			lh.logger.Debugf("Undecodable frame from %s: %v", player.WebSocketID, err)
+			lh.sendError(player, "Invalid message format")
+			continue
+		}
+
+		player.Touch(lh.now())
+		lh.metrics.messagesReceived.Add(1)
+		lh.handleMessage(player, message)
 	}
 }
 
 func (lh *LobbyHandler) writePump(player *models.WebSocketPlayer) {
-	ticker := time.NewTicker(54 * time.Second)
+	ticker := time.NewTicker(lh.keepalive.PingInterval)
 	defer func() {
 		ticker.Stop()
 		player.Conn.Close()
@@ -264,56 +539,179 @@ func (lh *LobbyHandler) writePump(player *models.WebSocketPlayer) {
 				return
 			}
 
-			if err := player.Conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			// A waiting state update goes first, so an event like game_end is never followed
+			// by a state from before it
+			select {
+			case state := <-player.State:
+				if err := writeFrame(player.Conn, state); err != nil {
+					return
+				}
+			default:
+			}
+			if err := writeFrame(player.Conn, message); err != nil {
+				return
+			}
+
+		case state := <-player.State:
+			player.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := writeFrame(player.Conn, state); err != nil {
 				return
 			}
 
 		case <-ticker.C:
 			player.Conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
-			if err := player.Conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+			// The send time travels in the ping payload so the pong handler can measure the round trip
+			payload := []byte(strconv.FormatInt(time.Now().UnixNano(), 10))
+			if err := player.Conn.WriteMessage(websocket.PingMessage, payload); err != nil {
 				return
 			}
 		}
 	}
 }
 
+// writeFrame writes an encoded message as a binary frame if it is one, otherwise as text.
+func writeFrame(conn *websocket.Conn, message []byte) error {
+	frameType := websocket.TextMessage
+	if isBinaryFrame(message) {
+		frameType = websocket.BinaryMessage
+	}
+	return conn.WriteMessage(frameType, message)
+}
+
 func (lh *LobbyHandler) handleMessage(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
 
 	if lh.lobby.GameStarted && lh.GameState != nil {
 		switch message.Type {
-		case models.MSG_PLAYER_MOVE, models.MSG_PLACE_BOMB:
+		case models.MSG_PLAYER_MOVE, models.MSG_PLACE_BOMB, models.MSG_MOVE_START, models.MSG_MOVE_STOP, models.MSG_PUNCH_BOMB:
 			lh.handleGameAction(player, message)
 			return
+		case models.MSG_EMOTE:
+			lh.handleEmote(player, message)
+			return
+		case models.MSG_RESYNC:
+			lh.handleResync(player, message)
+			return
+		case models.MSG_GET_MY_STATS:
+			lh.handleGetMyStats(player)
+			return
 		}
 	}
 
 	// Handle lobby/chat messages
 	switch message.Type {
+	case models.MSG_HELLO:
+		lh.handleHello(player, message)
 	case models.MSG_JOIN_LOBBY:
 		lh.handleJoinLobby(player, message)
 	case models.MSG_LOBBY_STATUS:
 		lh.handleLobbyStatusRequest(player, message)
+	case models.MSG_GET_CAPABILITIES:
+		lh.handleGetCapabilities(player)
+	case models.MSG_PLAYER_READY:
+		lh.handlePlayerReady(player)
+	case models.MSG_START_GAME:
+		lh.handleStartGame(player)
+	case models.MSG_KICK_PLAYER:
+		lh.handleKickPlayer(player, message)
+	case models.MSG_LOBBY_SETTINGS:
+		lh.handleLobbySettings(player, message)
 	case models.MSG_CHAT_MESSAGE:
 		lh.handleChatMessage(player, message)
 	case models.MSG_PING:
-		lh.handlePing(player)
+		lh.handlePing(player, message)
 	case models.MSG_PLAYER_MOVE:
 		lh.handlePlayerMove(player, message)
 	case models.MSG_PLACE_BOMB:
 		lh.handlePlaceBomb(player, message)
+	case models.MSG_RESYNC, models.MSG_GET_MY_STATS:
+		lh.sendError(player, "No game is running")
 	default:
-		log.Printf("Unknown message type: %s", message.Type)
+		lh.logger.Warnf("Unknown message type: %s", message.Type)
+	}
+}
+
+// handleHello records the client's protocol version. Clients outside the supported
+// range get an error explaining why and are disconnected. Only the first accepted hello
+// counts; later ones get an error.
+func (lh *LobbyHandler) handleHello(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
+	// The first accepted hello is final, so state updates never switch encoding mid-stream
+	if player.ProtocolVersion() != 0 {
+		lh.sendError(player, "Protocol already negotiated")
+		return
+	}
+
+	var hello models.HelloRequest
+	dataBytes, _ := json.Marshal(message.Data)
+	if err := json.Unmarshal(dataBytes, &hello); err != nil {
+		lh.sendError(player, "Invalid hello data")
+		return
+	}
+
+	if hello.Version < models.MinProtocolVersion || hello.Version > models.ProtocolVersion {
+		lh.logger.Infof("Rejecting player %s: protocol version %d not supported", player.WebSocketID, hello.Version)
+		lh.sendError(player, fmt.Sprintf("Unsupported protocol version %d, this server supports versions %d to %d; please update your client",
+			hello.Version, models.MinProtocolVersion, models.ProtocolVersion))
+		if player.MarkEvicting() {
+			go func() { lh.hub.Unregister <- player }()
+		}
+		return
+	}
+
+	// Unknown encodings fall back to JSON rather than failing the handshake
+	encoding := EncodingJSON
+	if hello.Encoding == EncodingGob {
+		encoding = EncodingGob
+	}
+
+	if !player.SetHandshake(hello.Version, encoding) {
+		lh.sendError(player, "Protocol already negotiated")
+		return
 	}
+	lh.sendToPlayer(player, &models.WebSocketMessage{
+		Type: models.MSG_HELLO,
+		Data: &models.HelloResponse{
+			Version:    hello.Version,
+			MinVersion: models.MinProtocolVersion,
+			MaxVersion: models.ProtocolVersion,
+			Encoding:   encoding,
+		},
+	})
 }
 
-func (lh *LobbyHandler) handlePing(player *models.WebSocketPlayer) {
+// handlePing answers an application-level ping. If the client sends its own send time
+// as "timestamp", it is echoed back as "clientTimestamp" so the client can compute the round trip.
+func (lh *LobbyHandler) handlePing(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
+	data := map[string]interface{}{
+		"timestamp": time.Now().Unix(),
+		"latency":   player.Latency(),
+	}
+	if messageData, ok := message.Data.(map[string]interface{}); ok {
+		if clientTimestamp, exists := messageData["timestamp"]; exists {
+			data["clientTimestamp"] = clientTimestamp
+		}
+	}
+
 	pongMsg := &models.WebSocketMessage{
 		Type: models.MSG_PONG,
-		Data: map[string]interface{}{"timestamp": time.Now().Unix()},
+		Data: data,
 	}
 	lh.sendToPlayer(player, pongMsg)
 }
 
+// latencyFromPingPayload returns the round trip in milliseconds for a pong carrying
+// the UnixNano send time written by writePump.
+func latencyFromPingPayload(appData string, now time.Time) (int64, bool) {
+	sentAt, err := strconv.ParseInt(appData, 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	rtt := now.Sub(time.Unix(0, sentAt))
+	if rtt < 0 {
+		return 0, false
+	}
+	return rtt.Milliseconds(), true
+}
+
 func generatePlayerID() string {
 	return "player_" + time.Now().Format("20060102150405") + "_" + randomString(6)
 }
@@ -339,6 +737,38 @@ func (lh *LobbyHandler) GetPlayerCount() int {
 	return len(lh.lobby.Players)
 }
 
+// GetLobbySummary returns a snapshot of the lobby that is safe to hand to HTTP clients.
+func (lh *LobbyHandler) GetLobbySummary() *models.LobbySummary {
+	lobby := lh.GetLobby()
+	playerCount := lh.GetPlayerCount()
+
+	lobby.Mutex.RLock()
+	defer lobby.Mutex.RUnlock()
+
+	summary := &models.LobbySummary{
+		ID:          lobby.ID,
+		Name:        lobby.Name,
+		PlayerCount: playerCount,
+		MaxPlayers:  lobby.MaxPlayers,
+		Status:      lobby.Status,
+		Host:        lobby.Host,
+		Mode:        lobby.Mode,
+	}
+	if host, ok := lobby.Players[lobby.Host]; ok {
+		summary.HostNickname = host.Name
+	}
+	return summary
+}
+
+// ServeLobby answers GET /lobby with the lobby summary, for lobby browsers and dashboards.
+func (lh *LobbyHandler) ServeLobby(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	writeJSON(w, lh.GetLobbySummary())
+}
+
 func (lh *LobbyHandler) handleJoinLobby(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
 	var joinRequest struct {
 		Nickname string `json:"nickname"`
@@ -364,7 +794,12 @@ func (lh *LobbyHandler) handleJoinLobby(player *models.WebSocketPlayer, message
 		return
 	}
 
-	// Check if nickname is already taken by ACTIVE players only
+	// The version check happens in the hello, so a client that skipped it can't be served
+	if player.ProtocolVersion() == 0 {
+		lh.sendError(player, "Send hello with your protocol version before joining")
+		return
+	}
+
 	lh.lobby.Mutex.Lock()
 
 	// Check if lobby is in countdown phase - prevent new players
@@ -374,12 +809,17 @@ func (lh *LobbyHandler) handleJoinLobby(player *models.WebSocketPlayer, message
 		return
 	}
 
-	for _, p := range lh.lobby.Players {
-		if p.Name == joinRequest.Nickname && p.IsConnected {
-			lh.lobby.Mutex.Unlock()
-			lh.sendError(player, "Nickname already taken")
-			return
-		}
+	// Nothing about the lobby or the running game changes; the client is told to come back later
+	if lh.lobby.Status == "playing" && lh.gameRunning {
+		lh.lobby.Mutex.Unlock()
+		lh.sendErrorWithHint(player, "Game in progress - wait for it to end to join", JoinHintWait)
+		return
+	}
+
+	if nicknameTaken(lh.lobby, joinRequest.Nickname, player.WebSocketID) {
+		lh.lobby.Mutex.Unlock()
+		lh.sendError(player, "Nickname already taken")
+		return
 	}
 
 	if len(lh.lobby.Players) >= lh.lobby.MaxPlayers {
@@ -390,7 +830,7 @@ func (lh *LobbyHandler) handleJoinLobby(player *models.WebSocketPlayer, message
 
 	// Update player and add to lobby
 	player.Name = joinRequest.Nickname
-	player.Lives = 3
+	player.Lives = lh.lobby.StartingLives
 	player.LobbyID = lh.lobby.ID
 	lh.lobby.Players[player.WebSocketID] = player
 
@@ -413,7 +853,7 @@ func (lh *LobbyHandler) handleJoinLobby(player *models.WebSocketPlayer, message
 	playerCount := len(lh.lobby.Players)
 	lh.lobby.Mutex.Unlock()
 
-	log.Printf("✅ Player %s joined lobby", player.Name)
+	lh.logger.Infof("Player %s joined lobby", player.Name)
 
 	// Send success message with FULL lobby state
 	successMsg := &models.WebSocketMessage{
@@ -453,7 +893,7 @@ func (lh *LobbyHandler) sendLobbyUpdate() {
 	lobbyUpdate := &models.WebSocketMessage{
 		Type: models.MSG_LOBBY_UPDATE,
 		Data: &models.LobbyUpdate{
-			Lobby:       lh.lobby,
+			Lobby:       newLobbyView(lh.lobby),
 			PlayerCount: len(lh.lobby.Players),
 			Status:      lh.lobby.Status,
 		},
@@ -481,6 +921,25 @@ func (lh *LobbyHandler) handleChatMessage(player *models.WebSocketPlayer, messag
 		return
 	}
 
+	if utf8.RuneCountInString(chatRequest.Message) > MaxChatMessageLength {
+		lh.sendError(player, fmt.Sprintf("Message cannot exceed %d characters", MaxChatMessageLength))
+		return
+	}
+
+	channel := chatRequest.Channel
+	if channel == "" {
+		channel = ChatChannelLobby
+	}
+	if channel != ChatChannelLobby && channel != ChatChannelGlobal {
+		lh.sendError(player, fmt.Sprintf("Unknown chat channel %q", channel))
+		return
+	}
+
+	if !lh.chatLimiter.Allow(player.WebSocketID) {
+		lh.sendError(player, "You are sending messages too fast, please slow down")
+		return
+	}
+
 	chatMsg := models.ChatMessage{
 		ID:        generateChatID(),
 		PlayerID:  player.WebSocketID,
@@ -488,6 +947,15 @@ func (lh *LobbyHandler) handleChatMessage(player *models.WebSocketPlayer, messag
 		Message:   chatRequest.Message,
 		Timestamp: time.Now(),
 		Type:      "chat",
+		Channel:   channel,
+	}
+
+	if channel == ChatChannelGlobal {
+		lh.broadcastToAll(&models.WebSocketMessage{
+			Type: models.MSG_CHAT_MESSAGE,
+			Data: chatMsg,
+		})
+		return
 	}
 
 	lh.lobby.Mutex.Lock()
@@ -504,158 +972,795 @@ func (lh *LobbyHandler) handleChatMessage(player *models.WebSocketPlayer, messag
 	lh.broadcastToLobby("", broadcastMsg)
 }
 
-func (lh *LobbyHandler) handleLobbyStatusRequest(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
-	lh.lobby.Mutex.RLock()
-	statusUpdate := &models.LobbyUpdate{
-		Lobby:       lh.lobby,
-		PlayerCount: len(lh.lobby.Players),
-		Status:      lh.lobby.Status,
+// pendingEmote is an emote queued by handleEmote for the game loop to broadcast.
+type pendingEmote struct {
+	player *models.WebSocketPlayer
+	emote  string
+}
+
+// handleEmote queues a quick-chat emote from a player in the running game. The game loop
+// broadcasts it after the next tick, when the sender's position can be read safely.
+// Emotes are transient and never enter the lobby's chat history.
+func (lh *LobbyHandler) handleEmote(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
+	var emoteRequest models.EmoteRequest
+	dataBytes, _ := json.Marshal(message.Data)
+	if err := json.Unmarshal(dataBytes, &emoteRequest); err != nil {
+		lh.sendError(player, "Invalid emote data")
+		return
 	}
-	lh.lobby.Mutex.RUnlock()
 
-	statusMsg := &models.WebSocketMessage{
-		Type: models.MSG_LOBBY_STATUS,
-		Data: statusUpdate,
+	switch emoteRequest.Emote {
+	case models.EmoteGG, models.EmoteOops, models.EmoteHelp, models.EmoteNice:
+	default:
+		lh.sendError(player, fmt.Sprintf("Unknown emote %q", emoteRequest.Emote))
+		return
 	}
-	lh.sendToPlayer(player, statusMsg)
-}
 
-func (lh *LobbyHandler) checkGameStartConditions() {
-	lh.lobby.Mutex.Lock()
-	defer lh.lobby.Mutex.Unlock()
+	if !lh.emoteLimiter.Allow(player.WebSocketID) {
+		lh.sendError(player, "You are sending emotes too fast")
+		return
+	}
 
-	playerCount := len(lh.lobby.Players)
+	lh.movesMutex.Lock()
+	lh.pendingEmotes = append(lh.pendingEmotes, pendingEmote{player: player, emote: emoteRequest.Emote})
+	lh.movesMutex.Unlock()
+}
 
-	if playerCount == lh.lobby.MaxPlayers && lh.lobby.Status == "waiting" {
-		lh.lobby.Status = "starting"
-		go lh.startGameCountdown()
+// serveEmotes broadcasts every waiting emote. Emotes from lobby members who are not playing
+// in the current game are dropped.
+func (lh *LobbyHandler) serveEmotes() {
+	lh.movesMutex.Lock()
+	waiting := lh.pendingEmotes
+	lh.pendingEmotes = nil
+	lh.movesMutex.Unlock()
+
+	for _, queued := range waiting {
+		var gamePlayer *models.Player
+		for _, p := range lh.GameState.Players {
+			if p.ID == queued.player.WebSocketID {
+				gamePlayer = p
+				break
+			}
+		}
+		if gamePlayer == nil {
+			continue
+		}
+		lh.broadcastToLobby("", &models.WebSocketMessage{
+			Type: models.MSG_EMOTE,
+			Data: &models.EmoteEvent{
+				PlayerID: gamePlayer.ID,
+				Emote:    queued.emote,
+				Position: gamePlayer.Position,
+			},
+		})
+	}
+}
+
+// handleResync queues a request for the full game state. The game loop answers it after
+// the next tick, so the state is never read while a tick is changing it.
+func (lh *LobbyHandler) handleResync(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
+	var resyncRequest models.ResyncRequest
+	dataBytes, _ := json.Marshal(message.Data)
+	if err := json.Unmarshal(dataBytes, &resyncRequest); err != nil {
+		lh.sendError(player, "Invalid resync data")
 		return
 	}
 
-	if playerCount >= lh.lobby.MinPlayers && playerCount < lh.lobby.MaxPlayers && lh.lobby.Status == "waiting" {
-		go lh.startWaitTimer()
+	if !lh.resyncLimiter.Allow(player.WebSocketID) {
+		lh.sendError(player, "You are requesting resyncs too fast")
+		return
 	}
+
+	lh.logger.Debugf("Player %s asked for a resync from seq %d", player.Name, resyncRequest.LastSeq)
+	lh.movesMutex.Lock()
+	lh.pendingResyncs = append(lh.pendingResyncs, player)
+	lh.movesMutex.Unlock()
 }
 
-func (lh *LobbyHandler) startWaitTimer() {
-	lh.lobby.Mutex.Lock()
-	lh.lobby.Status = "waiting_for_players"
-	lh.lobby.Mutex.Unlock()
+// serveResyncs sends the full current state to every player who asked for one. In
+// fog-of-war mode players still only get the part of the game they can see.
+func (lh *LobbyHandler) serveResyncs() {
+	lh.movesMutex.Lock()
+	waiting := lh.pendingResyncs
+	lh.pendingResyncs = nil
+	lh.movesMutex.Unlock()
+
+	for _, wsPlayer := range waiting {
+		lh.sendToPlayer(wsPlayer, &models.WebSocketMessage{
+			Type: models.MSG_RESYNC,
+			Data: &models.ResyncResponse{
+				Seq:   lh.GameState.Tick,
+				State: lh.playerView(wsPlayer.WebSocketID),
+			},
+		})
+	}
+}
 
-	for i := lh.lobby.WaitTimer; i > 0; i-- {
-		time.Sleep(1 * time.Second)
+// handleGetMyStats queues a request for the player's live stats. Like resyncs, the reply is
+// built by the game loop after the next tick, so it never reads a half-updated state.
+func (lh *LobbyHandler) handleGetMyStats(player *models.WebSocketPlayer) {
+	if !lh.statsLimiter.Allow(player.WebSocketID) {
+		lh.sendError(player, "You are requesting stats too fast")
+		return
+	}
 
-		lh.lobby.Mutex.RLock()
-		currentPlayerCount := len(lh.lobby.Players)
-		status := lh.lobby.Status
-		lh.lobby.Mutex.RUnlock()
+	lh.movesMutex.Lock()
+	lh.pendingStats = append(lh.pendingStats, player)
+	lh.movesMutex.Unlock()
+}
 
-		if currentPlayerCount == lh.lobby.MaxPlayers {
-			lh.lobby.Mutex.Lock()
-			lh.lobby.Status = "starting"
-			lh.lobby.Mutex.Unlock()
-			go lh.startGameCountdown()
-			return
+// serveStats answers every waiting stats request. Lobby members who are not playing in the
+// current game, such as players who joined after it started, get an error instead.
+func (lh *LobbyHandler) serveStats() {
+	lh.movesMutex.Lock()
+	waiting := lh.pendingStats
+	lh.pendingStats = nil
+	lh.movesMutex.Unlock()
+
+	for _, wsPlayer := range waiting {
+		var gamePlayer *models.Player
+		for _, p := range lh.GameState.Players {
+			if p.ID == wsPlayer.WebSocketID {
+				gamePlayer = p
+				break
+			}
 		}
-
-		if currentPlayerCount < lh.lobby.MinPlayers {
-			lh.lobby.Mutex.Lock()
-			lh.lobby.Status = "waiting"
-			lh.lobby.Mutex.Unlock()
-			return
+		if gamePlayer == nil {
+			lh.sendError(wsPlayer, "You are not playing in this game")
+			continue
 		}
-
-		updateMsg := &models.WebSocketMessage{
-			Type: models.MSG_LOBBY_UPDATE,
-			Data: &models.LobbyUpdate{
-				Lobby:       lh.lobby,
-				PlayerCount: currentPlayerCount,
-				TimeLeft:    i,
-				Status:      status,
+		lh.sendToPlayer(wsPlayer, &models.WebSocketMessage{
+			Type: models.MSG_MY_STATS,
+			Data: &models.PlayerStats{
+				PlayerState: *GetPlayerState(gamePlayer),
+				Tick:        lh.GameState.Tick,
+				Lives:       gamePlayer.Lives,
+				Alive:       gamePlayer.Alive,
+				Score:       gamePlayer.Score,
+				Statuses:    append([]models.StatusEffect{}, gamePlayer.Statuses...),
 			},
-		}
+		})
+	}
+}
 
-		lh.broadcastToLobby("", updateMsg)
+// playerView returns the game state a lobby member is allowed to see: their fog-of-war view
+// if they play in a fog-of-war game, the full state otherwise.
+func (lh *LobbyHandler) playerView(id string) *models.GameState {
+	if !lh.GameState.Config.FogOfWar {
+		return lh.GameState
 	}
+	for _, p := range lh.GameState.Players {
+		if p.ID == id {
+			return FilteredGameState(lh.GameState, p)
+		}
+	}
+	return lh.GameState
+}
 
+func (lh *LobbyHandler) handleLobbyStatusRequest(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
 	lh.lobby.Mutex.RLock()
-	finalPlayerCount := len(lh.lobby.Players)
+	statusUpdate := &models.LobbyUpdate{
+		Lobby:       newLobbyView(lh.lobby),
+		PlayerCount: len(lh.lobby.Players),
+		Status:      lh.lobby.Status,
+	}
 	lh.lobby.Mutex.RUnlock()
 
-	if finalPlayerCount >= lh.lobby.MinPlayers {
-		lh.lobby.Mutex.Lock()
-		lh.lobby.Status = "starting"
-		lh.lobby.Mutex.Unlock()
-		go lh.startGameCountdown()
-	} else {
-		lh.lobby.Mutex.Lock()
-		lh.lobby.Status = "waiting"
-		lh.lobby.Mutex.Unlock()
+	statusMsg := &models.WebSocketMessage{
+		Type: models.MSG_LOBBY_STATUS,
+		Data: statusUpdate,
 	}
+	lh.sendToPlayer(player, statusMsg)
 }
 
-func (lh *LobbyHandler) startGameCountdown() {
-	for i := lh.lobby.StartTimer; i > 0; i-- {
-		time.Sleep(1 * time.Second)
-
-		lh.lobby.Mutex.RLock()
-		currentPlayerCount := len(lh.lobby.Players)
-		lh.lobby.Mutex.RUnlock()
-
-		updateMsg := &models.WebSocketMessage{
-			Type: models.MSG_LOBBY_UPDATE,
-			Data: &models.LobbyUpdate{
-				Lobby:       lh.lobby,
-				PlayerCount: currentPlayerCount,
-				TimeLeft:    i,
-				Status:      "starting",
-			},
-		}
-		lh.broadcastToLobby("", updateMsg)
-	}
-
-	lh.startGame()
+func (lh *LobbyHandler) handleGetCapabilities(player *models.WebSocketPlayer) {
+	lh.sendToPlayer(player, &models.WebSocketMessage{
+		Type: models.MSG_GET_CAPABILITIES,
+		Data: GetCapabilities(),
+	})
 }
 
-func (lh *LobbyHandler) startGame() {
+// handlePlayerReady toggles the player's ready flag and broadcasts the new lobby state.
+func (lh *LobbyHandler) handlePlayerReady(player *models.WebSocketPlayer) {
 	lh.lobby.Mutex.Lock()
-
+	if _, inLobby := lh.lobby.Players[player.WebSocketID]; !inLobby {
+		lh.lobby.Mutex.Unlock()
+		lh.sendError(player, "Join the lobby before getting ready")
+		return
+	}
 	if lh.lobby.GameStarted {
 		lh.lobby.Mutex.Unlock()
+		lh.sendError(player, "Game already started")
 		return
 	}
+	player.IsReady = !player.IsReady
+	lh.lobby.Mutex.Unlock()
 
-	lh.lobby.GameStarted = true
-	lh.lobby.Status = "playing"
+	lh.sendLobbyUpdate()
+}
 
-	// --- Create the list of players for the game logic ---
-	gamePlayers := []*models.Player{}
-	spawnPoints := []models.Position{
-		{X: 1, Y: 1}, {X: 13, Y: 1}, {X: 1, Y: 11}, {X: 13, Y: 11},
+// handleKickPlayer lets the host remove another player while the game hasn't started.
+// The kicked player is told why and then disconnected like any other leaving player.
+func (lh *LobbyHandler) handleKickPlayer(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
+	var kickRequest models.KickPlayerRequest
+	dataBytes, _ := json.Marshal(message.Data)
+	if err := json.Unmarshal(dataBytes, &kickRequest); err != nil {
+		lh.sendError(player, "Invalid kick request")
+		return
 	}
 
-	i := 0
-	for _, wsPlayer := range lh.lobby.Players {
-		if i >= len(spawnPoints) {
-			break
-		}
-		gamePlayer := &models.Player{
-			ID:         wsPlayer.WebSocketID,
-			Name:       wsPlayer.Name,
-			Position:   spawnPoints[i],
-			SpawnPoint: spawnPoints[i],
-			Lives:      3,
-			Alive:      true,
-			BombCount:  1,
-			FlameRange: 1,
-			Speed:      0,
-		}
-		gamePlayers = append(gamePlayers, gamePlayer)
-		i++
-	}
+	lh.lobby.Mutex.RLock()
+	isHost := lh.lobby.Host == player.WebSocketID
+	gameStarted := lh.lobby.GameStarted
+	target, exists := lh.lobby.Players[kickRequest.PlayerID]
+	lh.lobby.Mutex.RUnlock()
+
+	switch {
+	case !isHost:
+		lh.sendError(player, "Only the host can kick players")
+		return
+	case gameStarted:
+		lh.sendError(player, "Players can't be kicked once the game has started")
+		return
+	case kickRequest.PlayerID == player.WebSocketID:
+		lh.sendError(player, "You can't kick yourself")
+		return
+	case !exists:
+		lh.sendError(player, "No such player in the lobby")
+		return
+	}
+
+	lh.logger.Infof("Host %s kicked %s", player.Name, target.Name)
+	lh.sendError(target, "You were kicked from the lobby by the host")
+	if target.MarkEvicting() {
+		go func() { lh.hub.Unregister <- target }()
+	}
+}
+
+// handleStartGame lets the host skip the wait timer once every present player is ready.
+func (lh *LobbyHandler) handleStartGame(player *models.WebSocketPlayer) {
+	lh.lobby.Mutex.Lock()
+	if lh.lobby.Host != player.WebSocketID {
+		lh.lobby.Mutex.Unlock()
+		lh.sendError(player, "Only the host can start the game")
+		return
+	}
+	if lh.lobby.Status != "waiting" && lh.lobby.Status != "waiting_for_players" {
+		lh.lobby.Mutex.Unlock()
+		lh.sendError(player, "Game is already starting")
+		return
+	}
+	if len(lh.lobby.Players) < lh.minPlayersToStart() {
+		lh.lobby.Mutex.Unlock()
+		lh.sendError(player, "Not enough players to start")
+		return
+	}
+	if !allPlayersReady(lh.lobby) {
+		lh.lobby.Mutex.Unlock()
+		lh.sendError(player, "Not all players are ready")
+		return
+	}
+
+	lh.lobby.Status = "starting"
+	gen := lh.beginCountdown()
+	lh.lobby.Mutex.Unlock()
+
+	lh.logger.Infof("Host %s started the game early, all players ready", player.Name)
+	go lh.startGameCountdown(gen)
+}
+
+// handleLobbySettings lets the host change the lobby settings before the game starts. A request is
+// applied as a whole or, if any field is invalid, not at all.
+func (lh *LobbyHandler) handleLobbySettings(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
+	var settings models.LobbySettingsRequest
+
+	dataBytes, err := json.Marshal(message.Data)
+	if err != nil {
+		lh.sendError(player, "Invalid settings format")
+		return
+	}
+	if err := json.Unmarshal(dataBytes, &settings); err != nil {
+		lh.sendError(player, "Invalid settings data")
+		return
+	}
+
+	lh.lobby.Mutex.Lock()
+	if lh.lobby.Host != player.WebSocketID {
+		lh.lobby.Mutex.Unlock()
+		lh.sendError(player, "Only the host can change lobby settings")
+		return
+	}
+	if lh.lobby.Status != "waiting" && lh.lobby.Status != "waiting_for_players" {
+		lh.lobby.Mutex.Unlock()
+		lh.sendError(player, "Settings cannot change once the game is starting")
+		return
+	}
+
+	// Nothing changes unless every field is valid, so a rejected request leaves the lobby as it was
+	if err := validateLobbySettings(lh.lobby, &settings); err != nil {
+		lh.lobby.Mutex.Unlock()
+		lh.sendError(player, err.Error())
+		return
+	}
+	applyLobbySettings(lh.lobby, &settings)
+	lh.lobby.Mutex.Unlock()
+
+	lh.sendLobbyUpdate()
+}
+
+// validateLobbySettings checks every field of a settings request against its allowed range.
+// Map fields are checked together with the lobby's current values for the ones left out.
+// The caller must hold lobby.Mutex.
+func validateLobbySettings(lobby *models.Lobby, settings *models.LobbySettingsRequest) error {
+	if settings.Mode != nil && *settings.Mode != ModeClassic && *settings.Mode != ModeTeam {
+		return fmt.Errorf("Unknown game mode")
+	}
+	if settings.SpawnArrangement != nil && *settings.SpawnArrangement != SpawnCorners && *settings.SpawnArrangement != SpawnTeamAdjacent {
+		return fmt.Errorf("Unknown spawn arrangement")
+	}
+	if settings.MapWidth != nil || settings.MapHeight != nil || settings.BlockDensity != nil || settings.BlockPattern != nil {
+		width, height, density, pattern := mapSettings(lobby, settings)
+		if err := ValidateMapSize(width, height); err != nil {
+			return err
+		}
+		if err := ValidateBlockLayout(width, height, density, pattern); err != nil {
+			return err
+		}
+	}
+	if settings.StartingLives != nil && (*settings.StartingLives < MinLives || *settings.StartingLives > MaxLives) {
+		return fmt.Errorf("Lives must be between %d and %d", MinLives, MaxLives)
+	}
+	if settings.StartingBombs != nil && (*settings.StartingBombs < DefaultStartingBombs || *settings.StartingBombs > MaxStartingBombs) {
+		return fmt.Errorf("Starting bombs must be between %d and %d", DefaultStartingBombs, MaxStartingBombs)
+	}
+	if settings.StartingFlame != nil && (*settings.StartingFlame < DefaultStartingFlame || *settings.StartingFlame > MaxStartingFlame) {
+		return fmt.Errorf("Starting flame range must be between %d and %d", DefaultStartingFlame, MaxStartingFlame)
+	}
+	if settings.StartingSpeed != nil && (*settings.StartingSpeed < DefaultStartingSpeed || *settings.StartingSpeed > MaxStartingSpeed) {
+		return fmt.Errorf("Starting speed must be between %d and %d", DefaultStartingSpeed, MaxStartingSpeed)
+	}
+	if settings.WaitTimer != nil && (*settings.WaitTimer < MinWaitTimer || *settings.WaitTimer > MaxWaitTimer) {
+		return fmt.Errorf("Wait timer must be between %d and %d seconds", MinWaitTimer, MaxWaitTimer)
+	}
+	if settings.StartTimer != nil && (*settings.StartTimer < MinStartTimer || *settings.StartTimer > MaxStartTimer) {
+		return fmt.Errorf("Start timer must be between %d and %d seconds", MinStartTimer, MaxStartTimer)
+	}
+	if settings.BombTimer != nil && (*settings.BombTimer < MinBombTimer || *settings.BombTimer > MaxBombTimer) {
+		return fmt.Errorf("Bomb timer must be between %d and %d ticks", MinBombTimer, MaxBombTimer)
+	}
+	if settings.FlameTime != nil && (*settings.FlameTime < MinFlameTime || *settings.FlameTime > MaxFlameTime) {
+		return fmt.Errorf("Flame time must be between %d and %d ticks", MinFlameTime, MaxFlameTime)
+	}
+	if settings.AFKTimeout != nil && *settings.AFKTimeout != 0 && (*settings.AFKTimeout < MinAFKTimeout || *settings.AFKTimeout > MaxAFKTimeout) {
+		return fmt.Errorf("AFK timeout must be 0 (off) or between %d and %d ticks", MinAFKTimeout, MaxAFKTimeout)
+	}
+	if settings.SeriesWins != nil && (*settings.SeriesWins < MinSeriesWins || *settings.SeriesWins > MaxSeriesWins) {
+		return fmt.Errorf("Series wins must be between %d and %d", MinSeriesWins, MaxSeriesWins)
+	}
+	if settings.PowerUpDropRate != nil && (*settings.PowerUpDropRate < 0 || *settings.PowerUpDropRate > 100) {
+		return fmt.Errorf("Power-up drop rate must be between 0 and 100 percent")
+	}
+	if settings.CustomMap != nil && *settings.CustomMap != "" {
+		if _, err := LoadNamedMap(*settings.CustomMap); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// mapSettings returns the map size and block layout a settings request asks for, taking the
+// lobby's current value for each one it leaves out. The caller must hold lobby.Mutex.
+func mapSettings(lobby *models.Lobby, settings *models.LobbySettingsRequest) (width, height int, density, pattern string) {
+	width, height = lobby.MapWidth, lobby.MapHeight
+	density, pattern = lobby.BlockDensity, lobby.BlockPattern
+	if settings.MapWidth != nil {
+		width = *settings.MapWidth
+	}
+	if settings.MapHeight != nil {
+		height = *settings.MapHeight
+	}
+	if settings.BlockDensity != nil {
+		density = *settings.BlockDensity
+	}
+	if settings.BlockPattern != nil {
+		pattern = *settings.BlockPattern
+	}
+	return width, height, density, pattern
+}
+
+// applyLobbySettings copies every field present in a validated settings request onto the lobby.
+// The caller must hold lobby.Mutex.
+func applyLobbySettings(lobby *models.Lobby, settings *models.LobbySettingsRequest) {
+	if settings.Mode != nil {
+		lobby.Mode = *settings.Mode
+	}
+	if settings.SpawnArrangement != nil {
+		lobby.SpawnArrangement = *settings.SpawnArrangement
+	}
+	if settings.FriendlyFire != nil {
+		lobby.FriendlyFire = *settings.FriendlyFire
+	}
+	if settings.SymmetricMap != nil {
+		lobby.SymmetricMap = *settings.SymmetricMap
+	}
+	lobby.MapWidth, lobby.MapHeight, lobby.BlockDensity, lobby.BlockPattern = mapSettings(lobby, settings)
+	if settings.StartingLives != nil {
+		lobby.StartingLives = *settings.StartingLives
+		for _, p := range lobby.Players {
+			p.Lives = lobby.StartingLives
+		}
+	}
+	if settings.StartingBombs != nil {
+		lobby.StartingBombs = *settings.StartingBombs
+	}
+	if settings.StartingFlame != nil {
+		lobby.StartingFlame = *settings.StartingFlame
+	}
+	if settings.StartingSpeed != nil {
+		lobby.StartingSpeed = *settings.StartingSpeed
+	}
+	if settings.MapSeed != nil {
+		lobby.MapSeed = *settings.MapSeed
+	}
+	if settings.FogOfWar != nil {
+		lobby.FogOfWar = *settings.FogOfWar
+	}
+	if settings.PassThrough != nil {
+		lobby.PassThrough = *settings.PassThrough
+	}
+	if settings.PixelMovement != nil {
+		lobby.PixelMovement = *settings.PixelMovement
+	}
+	if settings.WaitTimer != nil {
+		lobby.WaitTimer = *settings.WaitTimer
+	}
+	if settings.StartTimer != nil {
+		lobby.StartTimer = *settings.StartTimer
+	}
+	if settings.BombTimer != nil {
+		lobby.BombTimer = *settings.BombTimer
+	}
+	if settings.FlameTime != nil {
+		lobby.FlameTime = *settings.FlameTime
+	}
+	if settings.AFKTimeout != nil {
+		lobby.AFKTimeout = *settings.AFKTimeout
+	}
+	if settings.SeriesWins != nil {
+		lobby.SeriesWins = *settings.SeriesWins
+	}
+	if settings.PowerUpDropRate != nil {
+		lobby.PowerUpDropRate = *settings.PowerUpDropRate
+	}
+	if settings.FillWithBots != nil {
+		lobby.FillWithBots = *settings.FillWithBots
+	}
+	if settings.CustomMap != nil {
+		lobby.CustomMap = *settings.CustomMap
+	}
+}
+
+// minPlayersToStart is the number of humans needed to start. With bot-fill on, a single
+// player can start and practice against bots.
+func (lh *LobbyHandler) minPlayersToStart() int {
+	if lh.lobby.FillWithBots {
+		return 1
+	}
+	return lh.lobby.MinPlayers
+}
+
+// allPlayersReady reports whether every player in the lobby is ready. The caller must hold the lobby mutex.
+func allPlayersReady(lobby *models.Lobby) bool {
+	for _, p := range lobby.Players {
+		if !p.IsReady {
+			return false
+		}
+	}
+	return true
+}
+
+func (lh *LobbyHandler) checkGameStartConditions() {
+	lh.lobby.Mutex.Lock()
+	defer lh.lobby.Mutex.Unlock()
+
+	playerCount := len(lh.lobby.Players)
+
+	if playerCount == lh.lobby.MaxPlayers && lh.lobby.Status == "waiting" {
+		lh.lobby.Status = "starting"
+		go lh.startGameCountdown(lh.beginCountdown())
+		return
+	}
+
+	if playerCount >= lh.minPlayersToStart() && playerCount < lh.lobby.MaxPlayers && lh.lobby.Status == "waiting" {
+		lh.lobby.Status = "waiting_for_players"
+		go lh.startWaitTimer(lh.beginCountdown())
+	}
+}
+
+// beginCountdown invalidates any running countdown and returns the generation
+// the new one must carry. The caller must hold the lobby mutex.
+func (lh *LobbyHandler) beginCountdown() uint64 {
+	lh.countdownGen++
+	return lh.countdownGen
+}
+
+// countdownActive reports whether the countdown with the given generation is still the current one.
+func (lh *LobbyHandler) countdownActive(gen uint64) bool {
+	lh.lobby.Mutex.RLock()
+	defer lh.lobby.Mutex.RUnlock()
+	return gen == lh.countdownGen
+}
+
+// runCountdown counts down the given number of seconds against a deadline on lh's clock. tick
+// gets the whole seconds left, rounded up from the time to the deadline, right away and then at
+// every second mark before it; it returns false to stop the countdown. Each wait sleeps only
+// until the next mark, so a late wake-up after a GC pause or a busy scheduler doesn't push the
+// rest of the countdown back: seconds overslept are skipped and the deadline holds.
+// It reports whether the deadline was reached.
+func (lh *LobbyHandler) runCountdown(seconds int, tick func(secondsLeft int) bool) bool {
+	deadline := lh.now().Add(time.Duration(seconds) * time.Second)
+	for {
+		remaining := deadline.Sub(lh.now())
+		if remaining <= 0 {
+			return true
+		}
+		left := int((remaining + time.Second - 1) / time.Second)
+		if !tick(left) {
+			return false
+		}
+		lh.sleep(remaining - time.Duration(left-1)*time.Second)
+	}
+}
+
+// countdownSeconds clamps a countdown length to [lo, hi], and never below MinCountdownSeconds,
+// so a bad value that got past validation can't skip or stall the countdown.
+func countdownSeconds(seconds, lo, hi int) int {
+	return max(min(seconds, hi), lo, MinCountdownSeconds)
+}
+
+func (lh *LobbyHandler) startWaitTimer(gen uint64) {
+	lh.lobby.Mutex.RLock()
+	seconds := countdownSeconds(lh.lobby.WaitTimer, MinWaitTimer, MaxWaitTimer)
+	lh.lobby.Mutex.RUnlock()
+
+	finished := lh.runCountdown(seconds, func(secondsLeft int) bool {
+		lh.lobby.Mutex.Lock()
+		// A newer countdown replaced this one (early start, lobby reset)
+		if gen != lh.countdownGen {
+			lh.lobby.Mutex.Unlock()
+			return false
+		}
+
+		currentPlayerCount := len(lh.lobby.Players)
+
+		if currentPlayerCount == lh.lobby.MaxPlayers {
+			lh.lobby.Status = "starting"
+			go lh.startGameCountdown(lh.beginCountdown())
+			lh.lobby.Mutex.Unlock()
+			return false
+		}
+
+		if currentPlayerCount < lh.minPlayersToStart() {
+			lh.lobby.Status = "waiting"
+			lh.beginCountdown()
+			lh.lobby.Mutex.Unlock()
+			return false
+		}
+		lh.lobby.Mutex.Unlock()
+
+		lh.broadcastTimer("waiting", secondsLeft)
+		return true
+	})
+	if !finished {
+		return
+	}
+
+	lh.lobby.Mutex.Lock()
+	defer lh.lobby.Mutex.Unlock()
+
+	if gen != lh.countdownGen {
+		return
+	}
+
+	if len(lh.lobby.Players) >= lh.minPlayersToStart() {
+		lh.lobby.Status = "starting"
+		go lh.startGameCountdown(lh.beginCountdown())
+	} else {
+		lh.lobby.Status = "waiting"
+		lh.beginCountdown()
+	}
+}
+
+func (lh *LobbyHandler) startGameCountdown(gen uint64) {
+	lh.broadcastMapPreview(gen)
+
+	lh.lobby.Mutex.RLock()
+	seconds := countdownSeconds(lh.lobby.StartTimer, MinStartTimer, MaxStartTimer)
+	lh.lobby.Mutex.RUnlock()
+
+	finished := lh.runCountdown(seconds, func(secondsLeft int) bool {
+		if !lh.countdownActive(gen) {
+			return false
+		}
+		lh.broadcastTimer("starting", secondsLeft)
+		return true
+	})
+	if finished {
+		lh.startGame(gen)
+	}
+}
+
+// broadcastMapPreview builds the map for the upcoming game and shows it to the lobby, so
+// clients can draw the arena during the countdown. startGame reuses the same map.
+func (lh *LobbyHandler) broadcastMapPreview(gen uint64) {
+	lh.lobby.Mutex.Lock()
+	if gen != lh.countdownGen {
+		lh.lobby.Mutex.Unlock()
+		return
+	}
+	gameMap := lh.buildMap()
+	lh.previewMap = gameMap
+	lh.previewGen = gen
+
+	// Copy now: the game will start destroying blocks on this map
+	preview := &models.MapPreview{Map: models.NewClientMap(gameMap), SpawnPoints: MapSpawnPoints(gameMap)}
+	lh.lobby.Mutex.Unlock()
+
+	lh.broadcastToLobby("", &models.WebSocketMessage{
+		Type: models.MSG_MAP_PREVIEW,
+		Data: preview,
+	})
+}
+
+// buildMap creates the map for the next game from the lobby settings: the custom map if one
+// is chosen and loads, a generated one otherwise. The caller must hold lobby.Mutex.
+func (lh *LobbyHandler) buildMap() *models.Map {
+	if lh.lobby.CustomMap != "" {
+		customMap, err := LoadNamedMap(lh.lobby.CustomMap)
+		if err == nil {
+			return customMap
+		}
+		lh.logger.Warnf("Custom map %q failed to load, using a generated map: %v", lh.lobby.CustomMap, err)
+	}
+	cfg := lh.gameConfig()
+	return GenerateMap(cfg, MapRNG(cfg.MapSeed))
+}
+
+// gameConfig builds the rules of the next game from the lobby settings. The caller must
+// hold lobby.Mutex.
+func (lh *LobbyHandler) gameConfig() models.GameConfig {
+	cfg := DefaultGameConfig()
+	cfg.MapWidth = lh.lobby.MapWidth
+	cfg.MapHeight = lh.lobby.MapHeight
+	cfg.MapSeed = lh.lobby.MapSeed
+	cfg.SymmetricMap = lh.lobby.SymmetricMap
+	cfg.BlockDensity = lh.lobby.BlockDensity
+	cfg.BlockPattern = lh.lobby.BlockPattern
+	cfg.PowerUpChance = float64(lh.lobby.PowerUpDropRate) / 100
+	cfg.StartingLives = lh.lobby.StartingLives
+	cfg.StartingBombs = lh.lobby.StartingBombs
+	cfg.StartingFlame = lh.lobby.StartingFlame
+	cfg.StartingSpeed = lh.lobby.StartingSpeed
+	cfg.BombTimer = lh.lobby.BombTimer
+	cfg.FlameTime = lh.lobby.FlameTime
+	cfg.TeamMode = lh.lobby.Mode == ModeTeam
+	cfg.FriendlyFire = lh.lobby.FriendlyFire
+	cfg.FogOfWar = lh.lobby.FogOfWar
+	cfg.PassThrough = lh.lobby.PassThrough
+	cfg.PixelMovement = lh.lobby.PixelMovement
+	cfg.AFKTimeout = lh.lobby.AFKTimeout
+	cfg.MaxTicks = lh.maxGameTicks
+	return cfg
+}
+
+// broadcastTimer sends a countdown tick. Lobby updates are kept for membership changes.
+func (lh *LobbyHandler) broadcastTimer(phase string, secondsLeft int) {
+	lh.broadcastToLobby("", &models.WebSocketMessage{
+		Type: models.MSG_TIMER_UPDATE,
+		Data: &models.TimerUpdate{
+			Phase:       phase,
+			SecondsLeft: secondsLeft,
+		},
+	})
+}
+
+// startGame moves the lobby into the playing state. The GameStarted and countdown
+// generation checks happen under the same lock as the transition, so concurrent start
+// paths cannot both start a game: the first one flips GameStarted, the rest return.
+func (lh *LobbyHandler) startGame(gen uint64) {
+	lh.lobby.Mutex.Lock()
+
+	if lh.lobby.GameStarted || lh.gameRunning || gen != lh.countdownGen {
+		lh.lobby.Mutex.Unlock()
+		return
+	}
+
+	// Play on the previewed map so clients see during the countdown exactly what they get
+	gameMap := lh.previewMap
+	if gameMap == nil || lh.previewGen != gen {
+		gameMap = lh.buildMap()
+	}
+	lh.previewMap = nil
+
+	cfg := lh.gameConfig()
+	cfg.MapWidth, cfg.MapHeight = gameMap.Width, gameMap.Height
+
+	// --- Create the list of players for the game logic ---
+	gamePlayers := []*models.Player{}
+	maxSpawns := len(MapSpawnPoints(gameMap))
+
+	i := 0
+	for _, wsPlayer := range playersByJoinOrder(lh.lobby) {
+		if i >= maxSpawns {
+			lh.logger.Warnf("Player %s has no spawn point left and sits this game out", wsPlayer.Name)
+			continue
+		}
+		gamePlayer := NewGamePlayer(wsPlayer.WebSocketID, wsPlayer.Name, cfg)
+		if cfg.TeamMode {
+			gamePlayer.TeamID = i % 2
+		}
+		wsPlayer.Lives = gamePlayer.Lives // Keep the lobby view in sync with the game
+		gamePlayers = append(gamePlayers, gamePlayer)
+		i++
+	}
+
+	// --- Fill the remaining spawn points with bots if enabled ---
+	lh.bots = nil
+	lh.movesMutex.Lock()
+	lh.pendingMoves = nil
+	lh.pendingResyncs = nil
+	lh.pendingStats = nil
+	lh.pendingEmotes = nil
+	lh.movesMutex.Unlock()
+	if lh.lobby.FillWithBots {
+		for n := 1; len(gamePlayers) < maxSpawns && len(gamePlayers) < lh.lobby.MaxPlayers; n++ {
+			botPlayer := NewBotPlayer(n, cfg)
+			if cfg.TeamMode {
+				botPlayer.TeamID = len(gamePlayers) % 2
+			}
+			gamePlayers = append(gamePlayers, botPlayer)
+			lh.bots = append(lh.bots, &Bot{Player: botPlayer})
+		}
+	}
 
 	// --- Initialize the GameState using our backend logic ---
-	lh.GameState = NewGame(gamePlayers)
+	lh.GameState = NewGameWithMap(gamePlayers, cfg, gameMap)
+
+	arrangement := lh.lobby.SpawnArrangement
+	if !cfg.TeamMode {
+		arrangement = SpawnCorners
+	}
+	if err := AssignSpawnPoints(lh.GameState.Players, lh.GameState.Map, arrangement); err != nil {
+		lh.logger.Warnf("Spawn arrangement %q failed, falling back to corners: %v", arrangement, err)
+		if err := AssignSpawnPoints(lh.GameState.Players, lh.GameState.Map, SpawnCorners); err != nil {
+			// No valid spawns on this map: stay in the lobby rather than start a broken game
+			lh.GameState = nil
+			lh.bots = nil
+			lh.lobby.Status = "waiting"
+			lh.beginCountdown()
+			lh.lobby.Mutex.Unlock()
+
+			lh.logger.Errorf("Game not started in lobby %s: %v", lh.lobby.ID, err)
+			lh.broadcastToLobby("", &models.WebSocketMessage{
+				Type: models.MSG_ERROR,
+				Data: &models.ErrorResponse{
+					Code:    500,
+					Message: "Game could not start: " + err.Error(),
+					Type:    "error",
+				},
+			})
+			lh.sendLobbyUpdate()
+			return
+		}
+	}
+
+	lh.lobby.GameStarted = true
+	lh.lobby.Status = "playing"
+	lh.gameRunning = true
+	if lh.lobby.SeriesWins > 1 {
+		lh.lobby.Round++
+	}
+	lh.metrics.gamesStarted.Add(1)
 
 	// Create the message while still holding the lock
 	startMsg := &models.WebSocketMessage{
@@ -666,18 +1771,107 @@ func (lh *LobbyHandler) startGame() {
 	lh.lobby.Mutex.Unlock() // Unlock BEFORE broadcasting and starting the loop
 
 	lh.broadcastToLobby("", startMsg)
-	log.Printf("Game started in lobby %s with %d players", lh.lobby.ID, len(lh.lobby.Players))
+	lh.logger.Infof("Game started in lobby %s with %d players", lh.lobby.ID, len(lh.lobby.Players))
 
 	// --- Start the main game loop ---
 	go lh.runGameLoop()
 }
 
+// newLobbyView copies the client-relevant parts of the lobby. The caller must hold lobby.Mutex.
+func newLobbyView(lobby *models.Lobby) *models.LobbyView {
+	players := make([]models.LobbyPlayerView, 0, len(lobby.Players))
+	for _, p := range playersByJoinOrder(lobby) {
+		players = append(players, models.LobbyPlayerView{
+			WebSocketID: p.WebSocketID,
+			Name:        p.Name,
+			Lives:       p.Lives,
+			IsReady:     p.IsReady,
+			IsConnected: p.IsConnected,
+			Latency:     p.Latency(),
+		})
+	}
+
+	return &models.LobbyView{
+		ID:               lobby.ID,
+		Name:             lobby.Name,
+		Players:          players,
+		MaxPlayers:       lobby.MaxPlayers,
+		MinPlayers:       lobby.MinPlayers,
+		GameStarted:      lobby.GameStarted,
+		Messages:         append([]models.ChatMessage(nil), lobby.Messages...),
+		WaitTimer:        lobby.WaitTimer,
+		StartTimer:       lobby.StartTimer,
+		Host:             lobby.Host,
+		Status:           lobby.Status,
+		Mode:             lobby.Mode,
+		FriendlyFire:     lobby.FriendlyFire,
+		SpawnArrangement: lobby.SpawnArrangement,
+		SymmetricMap:     lobby.SymmetricMap,
+		BlockDensity:     lobby.BlockDensity,
+		BlockPattern:     lobby.BlockPattern,
+		MapWidth:         lobby.MapWidth,
+		MapHeight:        lobby.MapHeight,
+		MapSeed:          lobby.MapSeed,
+		CustomMap:        lobby.CustomMap,
+		FillWithBots:     lobby.FillWithBots,
+		StartingLives:    lobby.StartingLives,
+		StartingBombs:    lobby.StartingBombs,
+		StartingFlame:    lobby.StartingFlame,
+		StartingSpeed:    lobby.StartingSpeed,
+		FogOfWar:         lobby.FogOfWar,
+		PassThrough:      lobby.PassThrough,
+		PixelMovement:    lobby.PixelMovement,
+		BombTimer:        lobby.BombTimer,
+		FlameTime:        lobby.FlameTime,
+		AFKTimeout:       lobby.AFKTimeout,
+		SeriesWins:       lobby.SeriesWins,
+		Round:            lobby.Round,
+		SeriesScore:      maps.Clone(lobby.SeriesScore),
+		PowerUpDropRate:  lobby.PowerUpDropRate,
+	}
+}
+
+// nicknameTaken reports whether another lobby member uses the nickname, ignoring case.
+// Disconnected players are removed from the lobby, so their names are free right away.
+// The caller must hold lobby.Mutex.
+func nicknameTaken(lobby *models.Lobby, nickname, exceptID string) bool {
+	for id, p := range lobby.Players {
+		if id != exceptID && strings.EqualFold(p.Name, nickname) {
+			return true
+		}
+	}
+	return false
+}
+
+// playersByJoinOrder returns the lobby's players sorted by JoinedAt (then ID), so that
+// spawn points and teams are handed out the same way on every run. The caller must hold the lobby mutex.
+func playersByJoinOrder(lobby *models.Lobby) []*models.WebSocketPlayer {
+	players := make([]*models.WebSocketPlayer, 0, len(lobby.Players))
+	for _, p := range lobby.Players {
+		players = append(players, p)
+	}
+	sort.Slice(players, func(i, j int) bool {
+		if !players[i].JoinedAt.Equal(players[j].JoinedAt) {
+			return players[i].JoinedAt.Before(players[j].JoinedAt)
+		}
+		return players[i].WebSocketID < players[j].WebSocketID
+	})
+	return players
+}
+
 // runGameLoop is the heart of the game, ticking the state forward.
 func (lh *LobbyHandler) runGameLoop() {
-	// Tick rate: 20 times per second (50ms interval)
-	ticker := time.NewTicker(50 * time.Millisecond)
+	ticker := time.NewTicker(TickInterval)
 	defer ticker.Stop()
 
+	// Skips updates when nothing changed
+	var throttle stateThrottle
+
+	replay, err := NewReplay(lh.GameState)
+	if err != nil {
+		lh.logger.Warnf("Replay recording disabled for this game: %v", err)
+	}
+
 	for range ticker.C {
 		if lh.GameState == nil || lh.GameState.Status == models.Finished {
 			// Stop the loop if the game ends
@@ -685,15 +1879,340 @@ func (lh *LobbyHandler) runGameLoop() {
 			return
 		}
 
+		tickStart := time.Now()
+
+		// Apply the moves collected since the last tick, earliest first
+		lh.movesMutex.Lock()
+		moves := lh.pendingMoves
+		lh.pendingMoves = nil
+		lh.movesMutex.Unlock()
+		ResolveMoves(lh.GameState, moves)
+		if replay != nil {
+			replay.Record(lh.GameState.Tick, moves...)
+		}
+
+		// Nobody is left to play or watch; the lobby was already reset by the last one out
+		lh.lobby.Mutex.Lock()
+		if len(lh.lobby.Players) == 0 {
+			lh.logger.Warnf("Abandoning game: every player left")
+			lh.GameState.Status = models.Finished
+			lh.gameRunning = false
+			lh.lobby.Mutex.Unlock()
+			return
+		}
+		lh.lobby.Mutex.Unlock()
+
+		// Let bots act after human inputs
+		for _, bot := range lh.bots {
+			if intent, ok := bot.Tick(lh.GameState); ok {
+				ResolveMoves(lh.GameState, []MoveIntent{intent})
+				if replay != nil {
+					replay.Record(lh.GameState.Tick, intent)
+				}
+			}
+		}
+
 		// Process one tick of the game
+		eliminated := len(lh.GameState.EliminationOrder)
 		GameTick(lh.GameState)
+		lh.syncPlayerLatencies()
+		lh.broadcastSurrenders(lh.GameState.EliminationOrder[eliminated:])
+		lh.broadcastDeaths(lh.GameState.EliminationOrder[eliminated:])
+		lh.notifyEliminated(lh.GameState.EliminationOrder[eliminated:])
+		if len(lh.GameState.DestroyedBlocks) > 0 {
+			lh.broadcastToLobby("", &models.WebSocketMessage{
+				Type: models.MSG_BLOCKS_DESTROYED,
+				Data: &models.BlocksDestroyedEvent{
+					Tick:   lh.GameState.Tick,
+					Blocks: append([]models.DestroyedBlock(nil), lh.GameState.DestroyedBlocks...),
+				},
+			})
+		}
+
+		// Only send when something changed, plus a periodic keepalive for clients that missed an update
+		if throttle.due(lh.GameState) {
+			// Broadcast the new state to all players, or a tailored view each in fog-of-war mode
+			if lh.GameState.Config.FogOfWar {
+				lh.sendFilteredStates()
+			} else {
+				updateMsg := &models.WebSocketMessage{
+					Type: models.MSG_GAME_STATE_UPDATE,
+					Data: lh.GameState,
+				}
+				lh.broadcastToLobby("", updateMsg)
+			}
+			lh.sendPlayerStates()
+		}
+		lh.serveResyncs()
+		lh.serveStats()
+		lh.serveEmotes()
+
+		if lh.metrics.RecordTick(time.Since(tickStart)) {
+			avg, peak := lh.metrics.TickDurations()
+			lh.logger.Warnf("Server overloaded: %d ticks in a row over %v (avg %v, max %v)", OverloadTickStreak, TickInterval, avg, peak)
+		}
+
+		if lh.GameState.Status == models.Finished {
+			lh.broadcastGameEnd()
+			lh.saveReplay(replay)
+			lh.finishRound()
+			return
+		}
+	}
+}
+
+// resetEmptyLobby returns the lobby to a fresh state once its last player has left. The server
+// runs a single default lobby, so it is reset rather than removed. Bumping the countdown
+// generation stops any wait or start countdown still running, and a game loop exits on its
+// next tick once its game is finished. Settings are kept. The caller must hold lobby.Mutex.
+func (lh *LobbyHandler) resetEmptyLobby() {
+	lh.logger.Infof("Lobby %s is empty, resetting it", lh.lobby.ID)
+	lh.lobby.Status = "waiting"
+	lh.lobby.GameStarted = false
+	lh.lobby.Round = 0
+	lh.lobby.SeriesScore = map[string]int{}
+	lh.lobby.Messages = []models.ChatMessage{}
+	lh.beginCountdown()
+	lh.previewMap = nil
+	lh.bots = nil
+
+	lh.movesMutex.Lock()
+	lh.pendingMoves = nil
+	lh.pendingResyncs = nil
+	lh.pendingStats = nil
+	lh.pendingEmotes = nil
+	lh.movesMutex.Unlock()
+}
+
+// finishRound hands a finished game back to the lobby. In series mode it also scores the round
+// and either starts the countdown to the next round or, once someone has SeriesWins round wins,
+// ends the series and reopens the lobby.
+// Nicknames live on the connections and carry over; every round starts with fresh game players.
+func (lh *LobbyHandler) finishRound() {
+	lh.lobby.Mutex.Lock()
+	lh.gameRunning = false
+	if lh.lobby.SeriesWins <= 1 {
+		lh.lobby.Mutex.Unlock()
+		return
+	}
+
+	AddRoundWins(lh.lobby.SeriesScore, lh.GameState)
+	update := &models.SeriesUpdate{
+		Round:      lh.lobby.Round,
+		WinsNeeded: lh.lobby.SeriesWins,
+		Standings:  SeriesStandings(lh.lobby.SeriesScore, lh.GameState.Players, lh.lobby.SeriesWins),
+		Finished:   SeriesOver(lh.lobby.SeriesScore, lh.lobby.SeriesWins),
+	}
+
+	lh.lobby.GameStarted = false
+	if update.Finished || len(lh.lobby.Players) < lh.minPlayersToStart() {
+		update.Finished = true
+		lh.lobby.Status = "waiting"
+		lh.lobby.Round = 0
+		lh.lobby.SeriesScore = map[string]int{}
+		lh.beginCountdown()
+	} else {
+		lh.lobby.Status = "starting"
+		go lh.startGameCountdown(lh.beginCountdown())
+	}
+	lh.lobby.Mutex.Unlock()
+
+	lh.logger.Infof("Series round %d finished in lobby %s (series over: %v)", update.Round, lh.lobby.ID, update.Finished)
+	lh.broadcastToLobby("", &models.WebSocketMessage{
+		Type: models.MSG_SERIES_UPDATE,
+		Data: update,
+	})
+	lh.sendLobbyUpdate()
+}
+
+// broadcastSurrenders announces the players among the new eliminations who surrendered for being idle.
+func (lh *LobbyHandler) broadcastSurrenders(eliminations []models.Elimination) {
+	for _, e := range eliminations {
+		if e.Reason != models.EliminationAFK {
+			continue
+		}
+		name := e.PlayerID
+		for _, p := range lh.GameState.Players {
+			if p.ID == e.PlayerID {
+				name = p.Name
+				break
+			}
+		}
+		lh.logger.Infof("Player %s surrendered after %d idle ticks", name, lh.GameState.Config.AFKTimeout)
+		lh.broadcastToLobby("", &models.WebSocketMessage{
+			Type: models.MSG_PLAYER_SURRENDERED,
+			Data: &models.SurrenderEvent{PlayerID: e.PlayerID, Name: name, Reason: e.Reason},
+		})
+	}
+}
+
+// broadcastDeaths announces the players among the new eliminations who lost their last life to a
+// flame, with who killed them. Surrenders have their own event.
+func (lh *LobbyHandler) broadcastDeaths(eliminations []models.Elimination) {
+	for _, e := range eliminations {
+		if e.Reason != "" {
+			continue
+		}
+		lh.broadcastToLobby("", &models.WebSocketMessage{
+			Type: models.MSG_PLAYER_DIED,
+			Data: &models.PlayerDiedEvent{
+				PlayerID: e.PlayerID,
+				KillerID: e.KillerID,
+				SelfKill: e.KillerID == e.PlayerID,
+				Position: e.Position,
+				Tick:     e.Tick,
+			},
+		})
+	}
+}
+
+// notifyEliminated sends each newly eliminated player still in the lobby a you_died event.
+// Nothing else changes for them: they stay in the broadcast set and watch the rest of the game.
+func (lh *LobbyHandler) notifyEliminated(eliminations []models.Elimination) {
+	if len(eliminations) == 0 {
+		return
+	}
+	lh.lobby.Mutex.RLock()
+	defer lh.lobby.Mutex.RUnlock()
+
+	for _, e := range eliminations {
+		wsPlayer, ok := lh.lobby.Players[e.PlayerID]
+		if !ok {
+			continue // Bots and players who already left
+		}
+		lh.sendToPlayer(wsPlayer, &models.WebSocketMessage{
+			Type: models.MSG_YOU_DIED,
+			Data: &models.YouDiedEvent{Tick: e.Tick, Rank: e.Rank, Reason: e.Reason},
+		})
+	}
+}
 
-		// Broadcast the new state to all players
-		updateMsg := &models.WebSocketMessage{
+// saveReplay stores the finished game's replay in REPLAYS_DIR, if set.
+func (lh *LobbyHandler) saveReplay(replay *Replay) {
+	if replay == nil {
+		return
+	}
+	replay.Finish(lh.GameState.Tick)
+	dir := replaysDir()
+	if dir == "" {
+		return
+	}
+	path, err := replay.Save(dir, replayName(lh.lobby.ID, lh.now()))
+	if err != nil {
+		lh.logger.Warnf("Failed to save replay for lobby %s: %v", lh.lobby.ID, err)
+		return
+	}
+	lh.logger.Infof("Saved replay for lobby %s to %s", lh.lobby.ID, path)
+}
+
+// stateSnapshot encodes what clients would receive, minus the tick counter, so two snapshots
+// are equal exactly when nothing visible changed. The client view is built before the tick is
+// masked, since fields like a power-up's Collectible flag are derived from it.
+func stateSnapshot(gs *models.GameState) []byte {
+	view := models.NewClientGameState(gs)
+	view.Tick = 0
+	data, err := json.Marshal(view)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// stateThrottle holds the last state sent and the tick it was sent on.
+type stateThrottle struct {
+	lastSnapshot []byte
+	lastSentTick int
+}
+
+// due reports whether a state update should go out this tick: when the client view changed,
+// or when StateKeepaliveTicks have passed since the last one. A true result counts as sent.
+func (st *stateThrottle) due(gs *models.GameState) bool {
+	snapshot := stateSnapshot(gs)
+	if bytes.Equal(snapshot, st.lastSnapshot) && gs.Tick-st.lastSentTick < StateKeepaliveTicks {
+		return false
+	}
+	st.lastSnapshot = snapshot
+	st.lastSentTick = gs.Tick
+	return true
+}
+
+// broadcastGameEnd announces the result of the finished game, including draws.
+func (lh *LobbyHandler) broadcastGameEnd() {
+	gs := lh.GameState
+	if gs.TimedOut {
+		lh.logger.Warnf("Game in lobby %s hit the %d tick cap and was force-finished", lh.lobby.ID, gs.Config.MaxTicks)
+	}
+	if gs.Draw {
+		lh.logger.Infof("Game in lobby %s ended in a draw", lh.lobby.ID)
+	} else if gs.Winner != nil {
+		lh.logger.Infof("Game in lobby %s won by %s", lh.lobby.ID, gs.Winner.Name)
+	} else {
+		lh.logger.Infof("Game in lobby %s won by team %d", lh.lobby.ID, gs.WinningTeam)
+	}
+
+	lh.broadcastToLobby("", &models.WebSocketMessage{
+		Type: models.MSG_GAME_END,
+		Data: &models.GameEndEvent{
+			Winner:           models.NewClientPlayer(gs.Winner),
+			WinningTeam:      gs.WinningTeam,
+			Draw:             gs.Draw,
+			TimedOut:         gs.TimedOut,
+			EliminationOrder: gs.EliminationOrder,
+			Standings:        Standings(gs),
+		},
+	})
+}
+
+// sendFilteredStates sends each player the part of the game state they can see.
+// Lobby members without a game player (joined mid-game) get the full state.
+func (lh *LobbyHandler) sendFilteredStates() {
+	lh.lobby.Mutex.RLock()
+	defer lh.lobby.Mutex.RUnlock()
+
+	gamePlayers := make(map[string]*models.Player, len(lh.GameState.Players))
+	for _, p := range lh.GameState.Players {
+		gamePlayers[p.ID] = p
+	}
+
+	for id, wsPlayer := range lh.lobby.Players {
+		view := lh.GameState
+		if gamePlayer, ok := gamePlayers[id]; ok {
+			view = FilteredGameState(lh.GameState, gamePlayer)
+		}
+		lh.sendToPlayer(wsPlayer, &models.WebSocketMessage{
 			Type: models.MSG_GAME_STATE_UPDATE,
-			Data: lh.GameState,
+			Data: view,
+		})
+	}
+}
+
+// sendPlayerStates sends every connected player their own private state.
+func (lh *LobbyHandler) sendPlayerStates() {
+	lh.lobby.Mutex.RLock()
+	defer lh.lobby.Mutex.RUnlock()
+
+	for _, gamePlayer := range lh.GameState.Players {
+		wsPlayer, ok := lh.lobby.Players[gamePlayer.ID]
+		if !ok {
+			continue
+		}
+		lh.sendToPlayer(wsPlayer, &models.WebSocketMessage{
+			Type: models.MSG_PLAYER_STATE,
+			Data: GetPlayerState(gamePlayer),
+		})
+	}
+}
+
+// syncPlayerLatencies copies the latency measured on each connection onto its game player
+// so state updates carry everyone's ping.
+func (lh *LobbyHandler) syncPlayerLatencies() {
+	lh.lobby.Mutex.RLock()
+	defer lh.lobby.Mutex.RUnlock()
+
+	for _, gamePlayer := range lh.GameState.Players {
+		if wsPlayer, ok := lh.lobby.Players[gamePlayer.ID]; ok {
+			gamePlayer.PlayerLatency = wsPlayer.Latency()
 		}
-		lh.broadcastToLobby("", updateMsg)
 	}
 }
 
@@ -719,100 +2238,94 @@ func (lh *LobbyHandler) handleGameAction(player *models.WebSocketPlayer, message
 	case models.MSG_PLAYER_MOVE:
 		var moveRequest struct {
 			Direction string `json:"direction"`
-			Precise   bool   `json:"precise,omitempty"` // Optional: true for 1-step movement
 		}
 		dataBytes, _ := json.Marshal(message.Data)
 		if json.Unmarshal(dataBytes, &moveRequest) == nil {
-			MovePlayer(gamePlayer, moveRequest.Direction, lh.GameState, moveRequest.Precise)
+			direction, err := models.ParseDirection(moveRequest.Direction)
+			if err != nil {
+				lh.sendError(player, err.Error())
+				return
+			}
+			lh.movesMutex.Lock()
+			lh.pendingMoves = append(lh.pendingMoves, MoveIntent{
+				Player:    gamePlayer,
+				Action:    ActionMove,
+				Direction: direction,
+			})
+			lh.movesMutex.Unlock()
 		}
 
 	case models.MSG_PLACE_BOMB:
-		PlaceBomb(lh.GameState, gamePlayer)
-	}
-}
-
-// /s
-
-// // handlePlaceBomb processes bomb placement requests during the game
-// func (lh *LobbyHandler) handlePlaceBomb(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
-// 	if !lh.lobby.GameStarted {
-// 		log.Printf("Player %s tried to place bomb but game hasn't started", player.Name)
-// 		return
-// 	}
-
-// 	log.Printf("💣 Player %s placing bomb", player.Name)
-
-// 	// TODO: Implement actual bomb placement logic
-// 	// For now, just broadcast the bomb placement to all players
-// 	bombUpdate := &models.WebSocketMessage{
-// 		Type: models.MSG_GAME_STATE_UPDATE,
-// 		Data: map[string]interface{}{
-// 			"type":      "bomb_placed",
-// 			"player_id": player.WebSocketID, // Use WebSocketID instead of embedded Player.ID
-// 			"timestamp": time.Now().Unix(),
-// 		},
-// 	}
-
-// 	lh.broadcastToLobby("", bombUpdate)
-// }
+		// Queued with the moves so bombs are applied at a tick boundary, in input order
+		lh.movesMutex.Lock()
+		lh.pendingMoves = append(lh.pendingMoves, MoveIntent{Player: gamePlayer, Action: ActionBomb})
+		lh.movesMutex.Unlock()
 
-// handlePlayerMove processes player movement requests during the game
-func (lh *LobbyHandler) handlePlayerMove(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
-	if !lh.lobby.GameStarted {
-		log.Printf("Player %s tried to move but game hasn't started", player.Name)
-		return
-	}
+	case models.MSG_MOVE_START:
+		var startRequest struct {
+			Direction string `json:"direction"`
+		}
+		dataBytes, _ := json.Marshal(message.Data)
+		if json.Unmarshal(dataBytes, &startRequest) == nil {
+			direction, err := models.ParseDirection(startRequest.Direction)
+			if err != nil {
+				lh.sendError(player, err.Error())
+				return
+			}
+			lh.movesMutex.Lock()
+			lh.pendingMoves = append(lh.pendingMoves, MoveIntent{
+				Player:    gamePlayer,
+				Action:    ActionMoveStart,
+				Direction: direction,
+			})
+			lh.movesMutex.Unlock()
+		}
 
-	var moveRequest struct {
-		Direction string `json:"direction"`
-	}
+	case models.MSG_MOVE_STOP:
+		lh.movesMutex.Lock()
+		lh.pendingMoves = append(lh.pendingMoves, MoveIntent{Player: gamePlayer, Action: ActionMoveStop})
+		lh.movesMutex.Unlock()
 
-	if messageData, ok := message.Data.(map[string]interface{}); ok {
-		if direction, exists := messageData["direction"]; exists {
-			if dirStr, ok := direction.(string); ok {
-				moveRequest.Direction = dirStr
+	case models.MSG_PUNCH_BOMB:
+		var punchRequest struct {
+			Direction string `json:"direction"`
+		}
+		dataBytes, _ := json.Marshal(message.Data)
+		if json.Unmarshal(dataBytes, &punchRequest) == nil {
+			direction, err := models.ParseDirection(punchRequest.Direction)
+			if err != nil {
+				lh.sendError(player, err.Error())
+				return
 			}
+			lh.movesMutex.Lock()
+			lh.pendingMoves = append(lh.pendingMoves, MoveIntent{
+				Player:    gamePlayer,
+				Action:    ActionPunch,
+				Direction: direction,
+			})
+			lh.movesMutex.Unlock()
 		}
 	}
+}
 
-	log.Printf("🎮 Player %s moving: %s", player.Name, moveRequest.Direction)
-
-	// TODO: Implement actual game state update logic
-	// For now, just broadcast the move to all players
-	moveUpdate := &models.WebSocketMessage{
-		Type: models.MSG_GAME_UPDATE,
-		Data: map[string]interface{}{
-			"type":      "player_move",
-			"player_id": player.WebSocketID, // Use WebSocketID instead of embedded Player.ID
-			"direction": moveRequest.Direction,
-			"timestamp": time.Now().Unix(),
-		},
+// handlePlayerMove and handlePlaceBomb catch moves and bombs that arrive while the game is
+// starting, before handleMessage routes them to handleGameAction. They used to broadcast the
+// raw input as a game_update event; inputs now always go through the tick, and clients only
+// ever receive game_state_update.
+func (lh *LobbyHandler) handlePlayerMove(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
+	if !lh.lobby.GameStarted {
+		lh.logger.Debugf("Player %s tried to move but game hasn't started", player.Name)
+		return
 	}
-
-	lh.broadcastToLobby("", moveUpdate)
+	lh.handleGameAction(player, message)
 }
 
-// handlePlaceBomb processes bomb placement requests during the game
 func (lh *LobbyHandler) handlePlaceBomb(player *models.WebSocketPlayer, message *models.WebSocketMessage) {
 	if !lh.lobby.GameStarted {
-		log.Printf("Player %s tried to place bomb but game hasn't started", player.Name)
+		lh.logger.Debugf("Player %s tried to place bomb but game hasn't started", player.Name)
 		return
 	}
-
-	log.Printf("💣 Player %s placing bomb", player.Name)
-
-	// TODO: Implement actual bomb placement logic
-	// For now, just broadcast the bomb placement to all players
-	bombUpdate := &models.WebSocketMessage{
-		Type: models.MSG_GAME_UPDATE,
-		Data: map[string]interface{}{
-			"type":      "bomb_placed",
-			"player_id": player.WebSocketID, // Use WebSocketID instead of embedded Player.ID
-			"timestamp": time.Now().Unix(),
-		},
-	}
-
-	lh.broadcastToLobby("", bombUpdate)
+	lh.handleGameAction(player, message)
 }
 
 func generateChatID() string {
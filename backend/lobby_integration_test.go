@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"encoding/json"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// joinClient connects a real WebSocket client, says hello and joins the lobby under nickname.
+func joinClient(t *testing.T, srv *httptest.Server, nickname string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := dialWS(srv, nil)
+	if err != nil {
+		t.Fatalf("%s: dial: %v", nickname, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	readUntil(t, conn, models.MSG_SUCCESS) // Welcome
+	sendJSON(t, conn, models.MSG_HELLO, &models.HelloRequest{Version: models.ProtocolVersion})
+	readUntil(t, conn, models.MSG_HELLO)
+	sendJSON(t, conn, models.MSG_JOIN_LOBBY, map[string]string{"nickname": nickname})
+	readUntil(t, conn, models.MSG_LOBBY_UPDATE)
+	return conn
+}
+
+// readTypesUntil reads a client's messages until one of type last arrives and returns the
+// types seen on the way, last included, keeping only those listed in watch.
+func readTypesUntil(t *testing.T, conn *websocket.Conn, last string, watch ...string) []string {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	var types []string
+	for {
+		var msg testMessage
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("waiting for %s after %v: %v", last, types, err)
+		}
+		if slices.Contains(watch, msg.Type) || msg.Type == last {
+			types = append(types, msg.Type)
+		}
+		if msg.Type == last {
+			return types
+		}
+	}
+}
+
+func TestFullLobbyStartsGame(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	useFakeClock(t, lh, 1000) // Countdowns run through at once
+	lh.lobby.MinPlayers = 4
+	srv := httptest.NewServer(NewServeMux(lh))
+	t.Cleanup(srv.Close)
+
+	var clients []*websocket.Conn
+	for _, name := range []string{"ann", "ben", "cat"} {
+		clients = append(clients, joinClient(t, srv, name))
+	}
+
+	// Chat reaches everyone in the lobby, sender included
+	sendJSON(t, clients[0], models.MSG_CHAT_MESSAGE, &models.ChatMessageRequest{Message: "one more?"})
+	for i, conn := range clients {
+		var chat models.ChatMessage
+		if err := json.Unmarshal(readUntil(t, conn, models.MSG_CHAT_MESSAGE).Data, &chat); err != nil {
+			t.Fatal(err)
+		}
+		if chat.Nickname != "ann" || chat.Message != "one more?" {
+			t.Errorf("client %d got chat %q from %q", i, chat.Message, chat.Nickname)
+		}
+	}
+
+	// The fourth player fills the lobby and the start countdown runs
+	clients = append(clients, joinClient(t, srv, "dan"))
+	for i, conn := range clients {
+		// At least one countdown tick between the preview and the start
+		types := readTypesUntil(t, conn, models.MSG_GAME_START, models.MSG_MAP_PREVIEW, models.MSG_TIMER_UPDATE)
+		if len(types) < 3 || types[0] != models.MSG_MAP_PREVIEW ||
+			slices.ContainsFunc(types[1:len(types)-1], func(s string) bool { return s != models.MSG_TIMER_UPDATE }) {
+			t.Errorf("client %d got %v, want map_preview, then timer_update, then game_start", i, types)
+		}
+	}
+}
@@ -0,0 +1,1323 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestIsOriginAllowed(t *testing.T) {
+	allowed := []string{"https://bomberman.example", "http://localhost:3000"}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://bomberman.example", true},
+		{"https://bomberman.example/", true},
+		{"HTTP://LOCALHOST:3000", true},
+		{"", true}, // Non-browser clients send no Origin
+		{"https://evil.example", false},
+		{"https://bomberman.example.evil.example", false},
+		{"http://localhost:3001", false},
+	}
+	for _, tt := range tests {
+		if got := isOriginAllowed(tt.origin, allowed); got != tt.want {
+			t.Errorf("isOriginAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+
+	if !isOriginAllowed("https://anything.example", []string{"*"}) {
+		t.Error("wildcard should allow any origin")
+	}
+}
+
+func TestLoadAllowedOrigins(t *testing.T) {
+	got := loadAllowedOrigins(" https://a.example/ , ,https://b.example")
+	if len(got) != 2 || got[0] != "https://a.example" || got[1] != "https://b.example" {
+		t.Errorf("loadAllowedOrigins = %v", got)
+	}
+	if got := loadAllowedOrigins(""); len(got) != len(defaultAllowedOrigins) {
+		t.Errorf("empty ALLOWED_ORIGINS should fall back to the defaults, got %v", got)
+	}
+}
+
+func TestWebSocketOriginCheck(t *testing.T) {
+	_, srv := newTestServer(t)
+
+	conn, _, err := dialWS(srv, http.Header{"Origin": {defaultAllowedOrigins[0]}})
+	if err != nil {
+		t.Fatalf("allowed origin was refused: %v", err)
+	}
+	conn.Close()
+
+	_, resp, err := dialWS(srv, http.Header{"Origin": {"https://evil.example"}})
+	if err == nil {
+		t.Fatal("disallowed origin was upgraded")
+	}
+	if resp == nil || resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("disallowed origin got %v, want 403", resp)
+	}
+}
+
+func TestLatencyFromPingPayload(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	sent := now.Add(-150 * time.Millisecond)
+
+	got, ok := latencyFromPingPayload(strconv.FormatInt(sent.UnixNano(), 10), now)
+	if !ok || got != 150 {
+		t.Errorf("latency = %d, %v; want 150, true", got, ok)
+	}
+	if _, ok := latencyFromPingPayload("not a timestamp", now); ok {
+		t.Error("garbage payload accepted")
+	}
+	if _, ok := latencyFromPingPayload(strconv.FormatInt(now.Add(time.Second).UnixNano(), 10), now); ok {
+		t.Error("timestamp from the future accepted")
+	}
+}
+
+// TestPongRecordsLatency sends a pong carrying a send time 120ms in the past and checks the
+// connection's latency, while the game loop side reads it concurrently (run with -race).
+func TestPongRecordsLatency(t *testing.T) {
+	lh, srv := newTestServer(t)
+	conn, _, err := dialWS(srv, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	waitFor(t, "registration", func() bool { return len(connectedPlayers(lh)) == 1 })
+	player := connectedPlayers(lh)[0]
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			_ = player.Latency()
+		}
+	}()
+
+	sent := time.Now().Add(-120 * time.Millisecond)
+	payload := []byte(strconv.FormatInt(sent.UnixNano(), 10))
+	if err := conn.WriteControl(websocket.PongMessage, payload, time.Now().Add(time.Second)); err != nil {
+		t.Fatalf("write pong: %v", err)
+	}
+	waitFor(t, "latency", func() bool { return player.Latency() >= 120 })
+	<-done
+
+	if got := player.Latency(); got > 1000 {
+		t.Errorf("latency = %dms, want about 120ms", got)
+	}
+}
+
+func TestStartGameAllReady(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	useFakeClock(t, lh, 0)
+	host := addTestPlayer(lh, "host", true)
+	guest := addTestPlayer(lh, "guest", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.Host = host.WebSocketID
+	lh.lobby.Mutex.Unlock()
+
+	// Nobody ready yet
+	lh.handleStartGame(host)
+	if errs := messagesOfType(drainMessages(t, host), models.MSG_ERROR); len(errs) != 1 {
+		t.Fatalf("start with nobody ready: got %d errors, want 1", len(errs))
+	}
+
+	lh.handlePlayerReady(host)
+	lh.handlePlayerReady(guest)
+
+	// The guest may not start the game even with everyone ready
+	drainMessages(t, guest)
+	lh.handleStartGame(guest)
+	errs := messagesOfType(drainMessages(t, guest), models.MSG_ERROR)
+	if len(errs) != 1 || !strings.Contains(string(errs[0].Data), "Only the host") {
+		t.Fatalf("non-host start: got %v, want an error", errs)
+	}
+	lh.lobby.Mutex.RLock()
+	status := lh.lobby.Status
+	lh.lobby.Mutex.RUnlock()
+	if status == "starting" {
+		t.Fatal("non-host started the countdown")
+	}
+
+	// The host skips the wait timer and goes straight to the start countdown
+	drainMessages(t, host)
+	lh.handleStartGame(host)
+	waitFor(t, "start countdown", func() bool {
+		lh.lobby.Mutex.RLock()
+		defer lh.lobby.Mutex.RUnlock()
+		return lh.lobby.Status == "starting"
+	})
+	var timers []testMessage
+	waitFor(t, "first timer tick", func() bool {
+		timers = append(timers, messagesOfType(drainMessages(t, host), models.MSG_TIMER_UPDATE)...)
+		return len(timers) > 0
+	})
+	if !strings.Contains(string(timers[0].Data), `"phase":"starting"`) {
+		t.Errorf("first timer tick = %s, want the starting phase", timers[0].Data)
+	}
+}
+
+func TestHostSuccessorIsLongestWaiting(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	start := time.Unix(1700000000, 0)
+	host := addTestPlayer(lh, "host", true)
+	// Map order must not matter: "zed" joined before "abe"
+	zed := addTestPlayer(lh, "zed", true)
+	abe := addTestPlayer(lh, "abe", true)
+	lh.lobby.Mutex.Lock()
+	host.JoinedAt = start
+	zed.JoinedAt = start.Add(time.Second)
+	abe.JoinedAt = start.Add(2 * time.Second)
+	lh.lobby.Host = host.WebSocketID
+	lh.lobby.Mutex.Unlock()
+
+	lh.unregisterPlayer(host)
+
+	lh.lobby.Mutex.RLock()
+	newHost := lh.lobby.Host
+	lh.lobby.Mutex.RUnlock()
+	if newHost != zed.WebSocketID {
+		t.Fatalf("new host = %q, want %q", newHost, zed.WebSocketID)
+	}
+	for _, p := range []*models.WebSocketPlayer{zed, abe} {
+		changed := messagesOfType(drainMessages(t, p), models.MSG_HOST_CHANGED)
+		if len(changed) != 1 {
+			t.Fatalf("%s got %d host_changed messages, want 1", p.WebSocketID, len(changed))
+		}
+		var event models.HostChangedEvent
+		if err := json.Unmarshal(changed[0].Data, &event); err != nil || event.HostID != zed.WebSocketID {
+			t.Errorf("%s: host_changed = %s, want hostId %q", p.WebSocketID, changed[0].Data, zed.WebSocketID)
+		}
+	}
+}
+
+func TestWaitTimerTicks(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	clock := useFakeClock(t, lh, 5)
+	start := clock.Now()
+	player := addTestPlayer(lh, "a", true)
+	addTestPlayer(lh, "b", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.WaitTimer = 5
+	lh.lobby.Status = "waiting_for_players"
+	gen := lh.beginCountdown()
+	lh.lobby.Mutex.Unlock()
+
+	lh.startWaitTimer(gen)
+
+	var ticks []models.TimerUpdate
+	for _, msg := range messagesOfType(drainMessages(t, player), models.MSG_TIMER_UPDATE) {
+		var update models.TimerUpdate
+		if err := json.Unmarshal(msg.Data, &update); err != nil {
+			t.Fatal(err)
+		}
+		ticks = append(ticks, update)
+	}
+	if len(ticks) != 5 {
+		t.Fatalf("got %d timer updates, want 5: %+v", len(ticks), ticks)
+	}
+	for i, tick := range ticks {
+		if tick.Phase != "waiting" || tick.SecondsLeft != 5-i {
+			t.Errorf("update %d = %+v, want waiting with %d seconds left", i, tick, 5-i)
+		}
+	}
+	if elapsed := clock.Now().Sub(start); elapsed != 5*time.Second {
+		t.Errorf("wait timer took %v, want 5s", elapsed)
+	}
+
+	// The wait is over: the start countdown takes over
+	waitFor(t, "start countdown", func() bool {
+		for _, msg := range messagesOfType(drainMessages(t, player), models.MSG_TIMER_UPDATE) {
+			if strings.Contains(string(msg.Data), `"phase":"starting"`) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func TestRapidJoinLeaveSingleCountdown(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	clock := useFakeClock(t, lh, 0)
+	join := func(id string) *models.WebSocketPlayer {
+		p := addTestPlayer(lh, id, true)
+		lh.checkGameStartConditions()
+		return p
+	}
+
+	watcher := join("a")
+	b := join("b")
+	waitFor(t, "first countdown", func() bool { return clock.Settled(1) })
+	lh.unregisterPlayer(b)
+	join("b2")
+	waitFor(t, "second countdown", func() bool { return clock.Settled(2) })
+
+	if n := len(messagesOfType(drainMessages(t, watcher), models.MSG_TIMER_UPDATE)); n != 2 {
+		t.Fatalf("got %d timer updates for two countdown starts, want 2", n)
+	}
+
+	// Let a second pass for both countdowns, the cancelled one first: it must exit silently
+	clock.Allow(2)
+	waitFor(t, "countdowns to settle", func() bool { return clock.Settled(1) })
+	if n := len(messagesOfType(drainMessages(t, watcher), models.MSG_TIMER_UPDATE)); n != 1 {
+		t.Errorf("got %d timer updates for one second, want 1 from the live countdown", n)
+	}
+}
+
+func TestConcurrentStartsStartOneGame(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	lh.maxGameTicks = 1 // End the game on its first tick
+	player := addTestPlayer(lh, "a", true)
+	addTestPlayer(lh, "b", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.Status = "starting"
+	gen := lh.beginCountdown()
+	lh.lobby.Mutex.Unlock()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lh.startGame(gen)
+		}()
+	}
+	wg.Wait()
+
+	var messages []testMessage
+	waitFor(t, "game end", func() bool {
+		messages = append(messages, drainMessages(t, player)...)
+		return len(messagesOfType(messages, models.MSG_GAME_END)) > 0
+	})
+	if n := len(messagesOfType(messages, models.MSG_GAME_START)); n != 1 {
+		t.Errorf("got %d game_start messages, want 1", n)
+	}
+	if n := lh.metrics.gamesStarted.Load(); n != 1 {
+		t.Errorf("gamesStarted = %d, want 1", n)
+	}
+}
+
+func TestStartingLivesSetting(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	host := addTestPlayer(lh, "host", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.Host = host.WebSocketID
+	lh.lobby.Mutex.Unlock()
+
+	lh.handleLobbySettings(host, &models.WebSocketMessage{Type: models.MSG_LOBBY_SETTINGS, Data: map[string]interface{}{"startingLives": 0}})
+	if errs := messagesOfType(drainMessages(t, host), models.MSG_ERROR); len(errs) != 1 {
+		t.Fatalf("0 lives accepted")
+	}
+
+	lh.handleLobbySettings(host, &models.WebSocketMessage{Type: models.MSG_LOBBY_SETTINGS, Data: map[string]interface{}{"startingLives": 1}})
+	lh.lobby.Mutex.RLock()
+	cfg := lh.gameConfig()
+	lh.lobby.Mutex.RUnlock()
+	if cfg.StartingLives != 1 {
+		t.Fatalf("StartingLives = %d, want 1", cfg.StartingLives)
+	}
+
+	// Hardcore: the first hit eliminates
+	gs := NewGame([]*models.Player{NewGamePlayer("a", "a", cfg), NewGamePlayer("b", "b", cfg)}, cfg)
+	burnPlayer(gs, gs.Players[0], "b")
+	if gs.Players[0].Alive {
+		t.Error("one-life player survived a hit")
+	}
+
+	// With the default three lives, a hit costs a life and respawns
+	cfg = DefaultGameConfig()
+	p := NewGamePlayer("c", "c", cfg)
+	p.SpawnPoint = at(1, 1)
+	p.Position = at(5, 5)
+	burnPlayer(NewGame([]*models.Player{p}, cfg), p, "")
+	if !p.Alive || p.Lives != DefaultLives-1 || p.Position != p.SpawnPoint {
+		t.Errorf("after a hit: alive %v, %d lives at %v", p.Alive, p.Lives, p.Position)
+	}
+}
+
+func TestInvalidSettingsChangeNothing(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	host := addTestPlayer(lh, "host", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.Host = host.WebSocketID
+	before, _ := json.Marshal(newLobbyView(lh.lobby))
+	lh.lobby.Mutex.Unlock()
+	drainMessages(t, host)
+
+	// Valid fields listed before and after an invalid one
+	lh.handleLobbySettings(host, &models.WebSocketMessage{Type: models.MSG_LOBBY_SETTINGS, Data: map[string]interface{}{
+		"mode":          ModeTeam,
+		"mapWidth":      MaxMapSize,
+		"startingLives": MaxLives + 1,
+		"bombTimer":     MinBombTimer,
+		"fogOfWar":      true,
+	}})
+	messages := drainMessages(t, host)
+	if errs := messagesOfType(messages, models.MSG_ERROR); len(errs) != 1 || !strings.Contains(string(errs[0].Data), "Lives must be") {
+		t.Fatalf("errors %v, want one about lives", errs)
+	}
+	if updates := messagesOfType(messages, models.MSG_LOBBY_UPDATE); len(updates) != 0 {
+		t.Errorf("rejected settings broadcast %d lobby updates", len(updates))
+	}
+	lh.lobby.Mutex.RLock()
+	after, _ := json.Marshal(newLobbyView(lh.lobby))
+	lh.lobby.Mutex.RUnlock()
+	if string(after) != string(before) {
+		t.Errorf("rejected settings changed the lobby\nbefore: %s\n after: %s", before, after)
+	}
+
+	// The same request without the bad field goes through as a whole, with one update
+	lh.handleLobbySettings(host, &models.WebSocketMessage{Type: models.MSG_LOBBY_SETTINGS, Data: map[string]interface{}{
+		"mode":      ModeTeam,
+		"mapWidth":  MaxMapSize,
+		"bombTimer": MinBombTimer,
+		"fogOfWar":  true,
+	}})
+	if updates := messagesOfType(drainMessages(t, host), models.MSG_LOBBY_UPDATE); len(updates) != 1 {
+		t.Errorf("accepted settings broadcast %d lobby updates, want 1", len(updates))
+	}
+	lh.lobby.Mutex.RLock()
+	defer lh.lobby.Mutex.RUnlock()
+	if lh.lobby.Mode != ModeTeam || lh.lobby.MapWidth != MaxMapSize || lh.lobby.BombTimer != MinBombTimer || !lh.lobby.FogOfWar {
+		t.Errorf("settings not applied: mode %q, width %d, bomb timer %d, fog %v", lh.lobby.Mode, lh.lobby.MapWidth, lh.lobby.BombTimer, lh.lobby.FogOfWar)
+	}
+}
+
+func TestStartingPowerUpsSetting(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	lh.maxGameTicks = 1 // End the game on its first tick
+	host := addTestPlayer(lh, "host", true)
+	addTestPlayer(lh, "guest", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.Host = host.WebSocketID
+	lh.lobby.Mutex.Unlock()
+
+	settings := func(data map[string]interface{}) []testMessage {
+		lh.handleLobbySettings(host, &models.WebSocketMessage{Type: models.MSG_LOBBY_SETTINGS, Data: data})
+		return messagesOfType(drainMessages(t, host), models.MSG_ERROR)
+	}
+	if errs := settings(map[string]interface{}{"startingBombs": 3, "startingFlame": 4, "startingSpeed": 2, "fillWithBots": true}); len(errs) != 0 {
+		t.Fatalf("valid starting power-ups rejected: %s", errs[0].Data)
+	}
+	if errs := settings(map[string]interface{}{"startingSpeed": MaxStartingSpeed + 1}); len(errs) != 1 {
+		t.Errorf("speed past the cap accepted")
+	}
+
+	lh.lobby.Mutex.Lock()
+	lh.lobby.Status = "starting"
+	gen := lh.beginCountdown()
+	lh.lobby.Mutex.Unlock()
+	lh.startGame(gen)
+
+	starts := messagesOfType(drainMessages(t, host), models.MSG_GAME_START)
+	if len(starts) != 1 {
+		t.Fatalf("got %d game starts, want 1", len(starts))
+	}
+	var state struct {
+		Players []models.ClientPlayer `json:"players"`
+	}
+	if err := json.Unmarshal(starts[0].Data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Players) != 4 {
+		t.Fatalf("game started with %d players, want 2 humans and 2 bots", len(state.Players))
+	}
+	for _, p := range state.Players {
+		if p.BombCount != 3 || p.FlameRange != 4 || p.Speed != 2 || p.PowerUpsCollected != 0 {
+			t.Errorf("%s starts with %d bombs, flame %d, speed %d and %d power-ups collected; want 3, 4, 2 and none",
+				p.ID, p.BombCount, p.FlameRange, p.Speed, p.PowerUpsCollected)
+		}
+	}
+}
+
+func TestPlayerStateIsPrivate(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	alice := addTestPlayer(lh, "p1", true)
+	bob := addTestPlayer(lh, "p2", true)
+	lh.GameState = newTestGame(at(1, 1), at(13, 11))
+	lh.GameState.Players[0].CanPunch = true
+	lh.GameState.Players[0].BombCooldown = 3
+
+	lh.sendPlayerStates()
+
+	for _, tc := range []struct {
+		conn      *models.WebSocketPlayer
+		abilities []string
+		cooldown  int
+	}{
+		{alice, []string{"punch_bomb"}, 3},
+		{bob, []string{}, 0},
+	} {
+		states := messagesOfType(drainMessages(t, tc.conn), models.MSG_PLAYER_STATE)
+		if len(states) != 1 {
+			t.Fatalf("%s got %d player states, want exactly its own", tc.conn.WebSocketID, len(states))
+		}
+		var state models.PlayerState
+		if err := json.Unmarshal(states[0].Data, &state); err != nil {
+			t.Fatal(err)
+		}
+		if state.PlayerID != tc.conn.WebSocketID || state.BombCooldown != tc.cooldown ||
+			strings.Join(state.Abilities, ",") != strings.Join(tc.abilities, ",") {
+			t.Errorf("%s got %+v", tc.conn.WebSocketID, state)
+		}
+	}
+
+	// The shared state update leaves the private fields out
+	data, err := json.Marshal(lh.GameState)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, private := range []string{"bombCooldown", "bombsPlaced", "abilities"} {
+		if strings.Contains(string(data), `"`+private+`"`) {
+			t.Errorf("broadcast state contains private field %q", private)
+		}
+	}
+}
+
+func TestStateKeepaliveCadence(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	var throttle stateThrottle
+	if !throttle.due(gs) {
+		t.Fatal("first state not sent")
+	}
+
+	// Nothing changes: only the keepalive goes out, every StateKeepaliveTicks
+	var sent []int
+	for i := 0; i < 3*StateKeepaliveTicks; i++ {
+		gs.Tick++
+		if throttle.due(gs) {
+			sent = append(sent, gs.Tick)
+		}
+	}
+	want := []int{StateKeepaliveTicks, 2 * StateKeepaliveTicks, 3 * StateKeepaliveTicks}
+	if len(sent) != len(want) {
+		t.Fatalf("quiescent game sent updates on ticks %v, want %v", sent, want)
+	}
+	for i := range want {
+		if sent[i] != want[i] {
+			t.Fatalf("quiescent game sent updates on ticks %v, want %v", sent, want)
+		}
+	}
+
+	// A power-up becoming collectible is a visible change even though only the tick moved
+	gs.PowerUps = []*models.ActivePowerUp{{Position: at(3, 1), Type: models.BombUp, RevealTick: gs.Tick + 3}}
+	gs.Tick++
+	if !throttle.due(gs) {
+		t.Fatal("new power-up not sent")
+	}
+	gs.Tick++
+	if throttle.due(gs) {
+		t.Error("update sent though nothing visible changed")
+	}
+	gs.Tick++
+	if !throttle.due(gs) {
+		t.Error("power-up turning collectible not sent")
+	}
+}
+
+func TestServeLobby(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	useFakeClock(t, lh, 0)
+	for _, name := range []string{"Alice", "Bob"} {
+		player := addTestPlayer(lh, strings.ToLower(name), false)
+		lh.handleJoinLobby(player, &models.WebSocketMessage{Type: models.MSG_JOIN_LOBBY, Data: map[string]string{"nickname": name}})
+	}
+	addTestPlayer(lh, "spectator", false) // Connected but not in the lobby
+
+	rec := httptest.NewRecorder()
+	NewServeMux(lh).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/lobby", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	var summary map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body, err)
+	}
+	if summary["playerCount"] != float64(2) || summary["host"] != "alice" || summary["hostNickname"] != "Alice" {
+		t.Errorf("lobby = %s, want 2 players hosted by alice (Alice)", rec.Body)
+	}
+	if status, _ := summary["status"].(string); !strings.HasPrefix(status, "waiting") {
+		t.Errorf("status %q, want a waiting status", status)
+	}
+	for _, internal := range []string{"players", "conn", "send", "Mutex"} {
+		if _, ok := summary[internal]; ok {
+			t.Errorf("lobby summary exposes %q: %s", internal, rec.Body)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	NewServeMux(lh).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/lobby", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /lobby: status %d, want 405", rec.Code)
+	}
+}
+
+func TestLobbyUpdateHasNoConnectionInternals(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	useFakeClock(t, lh, 0)
+	alice := addTestPlayer(lh, "alice", false)
+	lh.handleJoinLobby(alice, &models.WebSocketMessage{Type: models.MSG_JOIN_LOBBY, Data: map[string]string{"nickname": "Alice"}})
+	bob := addTestPlayer(lh, "bob", false)
+	lh.handleJoinLobby(bob, &models.WebSocketMessage{Type: models.MSG_JOIN_LOBBY, Data: map[string]string{"nickname": "Bob"}})
+	drainMessages(t, alice)
+
+	lh.sendLobbyUpdate()
+	updates := messagesOfType(drainMessages(t, alice), models.MSG_LOBBY_UPDATE)
+	if len(updates) != 1 {
+		t.Fatalf("got %d lobby updates, want 1", len(updates))
+	}
+	var update struct {
+		Lobby struct {
+			Players []map[string]json.RawMessage `json:"players"`
+		} `json:"lobby"`
+	}
+	if err := json.Unmarshal(updates[0].Data, &update); err != nil {
+		t.Fatalf("decode %s: %v", updates[0].Data, err)
+	}
+	if len(update.Lobby.Players) != 2 {
+		t.Fatalf("lobby update lists %d players, want 2: %s", len(update.Lobby.Players), updates[0].Data)
+	}
+
+	allowed := map[string]bool{"webSocketId": true, "name": true, "lives": true, "isReady": true, "isConnected": true, "latency": true}
+	for _, player := range update.Lobby.Players {
+		for key := range player {
+			if !allowed[key] {
+				t.Errorf("lobby update exposes player field %q", key)
+			}
+		}
+	}
+	for _, internal := range []string{"connectionId", "lobbyId", "joinedAt", "protocolVersion", "Mutex", "Conn", "Send"} {
+		if strings.Contains(string(updates[0].Data), `"`+internal+`"`) {
+			t.Errorf("lobby update contains %q: %s", internal, updates[0].Data)
+		}
+	}
+}
+
+func TestEmoteReachesAllPlayers(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	p1 := addTestPlayer(lh, "p1", true)
+	p2 := addTestPlayer(lh, "p2", true)
+	watcher := addTestPlayer(lh, "watcher", true) // Joined after the game started
+	lh.GameState = newTestGame(at(1, 1), at(13, 11))
+	lh.lobby.GameStarted = true
+	for _, p := range []*models.WebSocketPlayer{p1, p2, watcher} {
+		drainMessages(t, p)
+	}
+
+	emote := &models.WebSocketMessage{Type: models.MSG_EMOTE, Data: &models.EmoteRequest{Emote: models.EmoteGG}}
+	lh.handleMessage(p2, emote)
+	lh.handleMessage(watcher, emote)
+	// Nothing goes out until the game loop serves the queue
+	if got := messagesOfType(drainMessages(t, p1), models.MSG_EMOTE); len(got) != 0 {
+		t.Fatalf("emote broadcast from the reader before the loop served it: %v", got)
+	}
+
+	lh.serveEmotes()
+	for _, p := range []*models.WebSocketPlayer{p1, p2, watcher} {
+		emotes := messagesOfType(drainMessages(t, p), models.MSG_EMOTE)
+		if len(emotes) != 1 {
+			t.Errorf("%s got %d emotes, want only the one from the playing sender", p.WebSocketID, len(emotes))
+			continue
+		}
+		var event models.EmoteEvent
+		if err := json.Unmarshal(emotes[0].Data, &event); err != nil {
+			t.Fatal(err)
+		}
+		if event.PlayerID != "p2" || event.Emote != models.EmoteGG || event.Position != at(13, 11) {
+			t.Errorf("%s got %+v, want gg from p2 at (13,11)", p.WebSocketID, event)
+		}
+	}
+}
+
+func TestHelloVersionNegotiation(t *testing.T) {
+	lh, srv := newTestServer(t)
+
+	current, _, err := dialWS(srv, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer current.Close()
+	sendJSON(t, current, models.MSG_HELLO, &models.HelloRequest{Version: models.ProtocolVersion})
+	var reply models.HelloResponse
+	if err := json.Unmarshal(readUntil(t, current, models.MSG_HELLO).Data, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Version != models.ProtocolVersion || reply.MinVersion != models.MinProtocolVersion || reply.MaxVersion != models.ProtocolVersion {
+		t.Errorf("hello reply = %+v, want version %d in [%d, %d]", reply, models.ProtocolVersion, models.MinProtocolVersion, models.ProtocolVersion)
+	}
+	players := connectedPlayers(lh)
+	if len(players) != 1 || players[0].ProtocolVersion() != models.ProtocolVersion {
+		t.Fatalf("negotiated version not recorded on the connection")
+	}
+
+	old, _, err := dialWS(srv, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer old.Close()
+	sendJSON(t, old, models.MSG_HELLO, &models.HelloRequest{Version: models.MinProtocolVersion - 1})
+	if errMsg := readUntil(t, old, models.MSG_ERROR); !strings.Contains(string(errMsg.Data), "Unsupported protocol version") {
+		t.Errorf("old client got %s, want an unsupported version error", errMsg.Data)
+	}
+	waitFor(t, "old client to be dropped", func() bool { return len(connectedPlayers(lh)) == 1 })
+}
+
+func TestHelloDuringStateUpdates(t *testing.T) {
+	lh, srv := newTestServer(t)
+	conn, _, err := dialWS(srv, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+	waitFor(t, "registration", func() bool { return len(connectedPlayers(lh)) == 1 })
+	player := connectedPlayers(lh)[0]
+
+	// State updates are encoded for the player while its hello is being handled
+	gs := newTestGame(at(1, 1), at(13, 11))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 200; i++ {
+			lh.broadcastToAll(&models.WebSocketMessage{Type: models.MSG_GAME_STATE_UPDATE, Data: gs})
+		}
+	}()
+	sendJSON(t, conn, models.MSG_HELLO, &models.HelloRequest{Version: models.ProtocolVersion, Encoding: EncodingGob})
+	readUntil(t, conn, models.MSG_HELLO)
+	<-done
+
+	// A second hello can't switch the encoding once updates are flowing
+	sendJSON(t, conn, models.MSG_HELLO, &models.HelloRequest{Version: models.ProtocolVersion, Encoding: EncodingJSON})
+	if errMsg := readUntil(t, conn, models.MSG_ERROR); !strings.Contains(string(errMsg.Data), "already negotiated") {
+		t.Errorf("second hello got %s, want it refused", errMsg.Data)
+	}
+	if player.Encoding() != EncodingGob {
+		t.Errorf("encoding switched to %q by the second hello", player.Encoding())
+	}
+}
+
+func TestJoinRequiresHello(t *testing.T) {
+	lh, srv := newTestServer(t)
+	conn, _, err := dialWS(srv, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	sendJSON(t, conn, models.MSG_JOIN_LOBBY, map[string]string{"nickname": "silent"})
+	if errMsg := readUntil(t, conn, models.MSG_ERROR); !strings.Contains(string(errMsg.Data), "Send hello") {
+		t.Errorf("join without hello got %s, want an error asking for the hello", errMsg.Data)
+	}
+	lh.lobby.Mutex.RLock()
+	players := len(lh.lobby.Players)
+	lh.lobby.Mutex.RUnlock()
+	if players != 0 {
+		t.Fatalf("client that skipped the hello joined the lobby")
+	}
+
+	sendJSON(t, conn, models.MSG_HELLO, &models.HelloRequest{Version: models.ProtocolVersion})
+	sendJSON(t, conn, models.MSG_JOIN_LOBBY, map[string]string{"nickname": "silent"})
+	readUntil(t, conn, models.MSG_LOBBY_UPDATE)
+}
+
+func TestErrorMessageUsesDataKey(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	player := addTestPlayer(lh, "p1", false)
+	drainMessages(t, player)
+
+	lh.sendErrorWithHint(player, "Something went wrong", JoinHintWait)
+	var envelope map[string]json.RawMessage
+	select {
+	case raw := <-player.Send:
+		if err := json.Unmarshal(raw, &envelope); err != nil {
+			t.Fatalf("decode %s: %v", raw, err)
+		}
+	default:
+		t.Fatal("no error message sent")
+	}
+	if _, ok := envelope["payload"]; ok {
+		t.Errorf("error message uses a payload key: %v", envelope)
+	}
+	var errResp models.ErrorResponse
+	if err := json.Unmarshal(envelope["data"], &errResp); err != nil {
+		t.Fatalf("decode data %s: %v", envelope["data"], err)
+	}
+	if string(envelope["type"]) != `"`+models.MSG_ERROR+`"` || errResp.Message != "Something went wrong" || errResp.Hint != JoinHintWait {
+		t.Errorf("error message = type %s data %+v, want the message and hint under data", envelope["type"], errResp)
+	}
+}
+
+func TestKickPlayer(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	host := addTestPlayer(lh, "host", true)
+	guest := addTestPlayer(lh, "guest", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.Host = host.WebSocketID
+	lh.lobby.Mutex.Unlock()
+	drainMessages(t, host)
+	drainMessages(t, guest)
+
+	// A guest may not kick anyone
+	lh.handleKickPlayer(guest, &models.WebSocketMessage{Type: models.MSG_KICK_PLAYER, Data: &models.KickPlayerRequest{PlayerID: "host"}})
+	errs := messagesOfType(drainMessages(t, guest), models.MSG_ERROR)
+	if len(errs) != 1 || !strings.Contains(string(errs[0].Data), "Only the host") {
+		t.Fatalf("non-host kick: got %v, want an error", errs)
+	}
+	if len(messagesOfType(drainMessages(t, host), models.MSG_ERROR)) != 0 {
+		t.Fatal("host was told about a kick that was denied")
+	}
+
+	// The host can, and the kicked player is told why before being dropped
+	lh.handleKickPlayer(host, &models.WebSocketMessage{Type: models.MSG_KICK_PLAYER, Data: &models.KickPlayerRequest{PlayerID: "guest"}})
+	select {
+	case data := <-guest.Send:
+		if msg := decodeTestMessage(t, data); msg.Type != models.MSG_ERROR || !strings.Contains(string(msg.Data), "kicked") {
+			t.Errorf("kicked player got %s %s, want a kick notice", msg.Type, msg.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("kicked player was not told")
+	}
+	waitFor(t, "kicked player to leave the lobby", func() bool {
+		lh.lobby.Mutex.RLock()
+		defer lh.lobby.Mutex.RUnlock()
+		_, stillThere := lh.lobby.Players["guest"]
+		return !stillThere
+	})
+	if len(connectedPlayers(lh)) != 1 {
+		t.Errorf("%d connections left, want only the host", len(connectedPlayers(lh)))
+	}
+}
+
+func TestInvalidDirectionRejected(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	player := addTestPlayer(lh, "p1", true)
+	lh.GameState = newTestGame(at(1, 1), at(13, 11))
+	lh.lobby.GameStarted = true
+	drainMessages(t, player)
+
+	cases := []struct {
+		direction string
+		err       string
+	}{
+		{"diagonal", "invalid direction"},
+		{"", "direction is required"},
+		{"up-left", "can't be combined"},
+	}
+	for _, c := range cases {
+		lh.handleMessage(player, &models.WebSocketMessage{Type: models.MSG_PLAYER_MOVE, Data: map[string]string{"direction": c.direction}})
+		errs := messagesOfType(drainMessages(t, player), models.MSG_ERROR)
+		if len(errs) != 1 || !strings.Contains(string(errs[0].Data), c.err) {
+			t.Errorf("direction %q: got errors %v, want one mentioning %q", c.direction, errs, c.err)
+		}
+	}
+	lh.movesMutex.Lock()
+	queued := len(lh.pendingMoves)
+	lh.movesMutex.Unlock()
+	if queued != 0 {
+		t.Errorf("%d moves queued from invalid directions", queued)
+	}
+
+	lh.handleMessage(player, &models.WebSocketMessage{Type: models.MSG_PLAYER_MOVE, Data: map[string]string{"direction": "down"}})
+	if errs := messagesOfType(drainMessages(t, player), models.MSG_ERROR); len(errs) != 0 {
+		t.Errorf("valid direction rejected: %v", errs)
+	}
+	lh.movesMutex.Lock()
+	defer lh.movesMutex.Unlock()
+	if len(lh.pendingMoves) != 1 || lh.pendingMoves[0].Direction != models.DirDown {
+		t.Errorf("pending moves %+v, want one move down", lh.pendingMoves)
+	}
+}
+
+func TestMapPreviewMatchesGameMap(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	lh.maxGameTicks = 1 // End the game on its first tick
+	player := addTestPlayer(lh, "a", true)
+	addTestPlayer(lh, "b", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.MapSeed = 0 // Random, so a second generation would give a different map
+	lh.lobby.Status = "starting"
+	gen := lh.beginCountdown()
+	lh.lobby.Mutex.Unlock()
+
+	lh.broadcastMapPreview(gen)
+	lh.startGame(gen)
+
+	var messages []testMessage
+	waitFor(t, "game end", func() bool {
+		messages = append(messages, drainMessages(t, player)...)
+		return len(messagesOfType(messages, models.MSG_GAME_END)) > 0
+	})
+	previews := messagesOfType(messages, models.MSG_MAP_PREVIEW)
+	starts := messagesOfType(messages, models.MSG_GAME_START)
+	if len(previews) != 1 || len(starts) != 1 {
+		t.Fatalf("got %d previews and %d game starts, want one of each", len(previews), len(starts))
+	}
+	var preview, start struct {
+		Map json.RawMessage `json:"map"`
+	}
+	if err := json.Unmarshal(previews[0].Data, &preview); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(starts[0].Data, &start); err != nil {
+		t.Fatal(err)
+	}
+	if string(preview.Map) != string(start.Map) {
+		t.Errorf("previewed map differs from the game map\npreview: %s\n   game: %s", preview.Map, start.Map)
+	}
+}
+
+func TestLeaveMidGameDeclaresWinner(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	stayer := addTestPlayer(lh, "a", true)
+	leaver := addTestPlayer(lh, "b", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.Status = "starting"
+	gen := lh.beginCountdown()
+	lh.lobby.Mutex.Unlock()
+	lh.startGame(gen)
+
+	// The leave is queued for the game loop rather than applied from this goroutine
+	lh.unregisterPlayer(leaver)
+
+	var messages []testMessage
+	waitFor(t, "game end", func() bool {
+		messages = append(messages, drainMessages(t, stayer)...)
+		return len(messagesOfType(messages, models.MSG_GAME_END)) > 0
+	})
+	var end models.GameEndEvent
+	if err := json.Unmarshal(messagesOfType(messages, models.MSG_GAME_END)[0].Data, &end); err != nil {
+		t.Fatal(err)
+	}
+	if end.Winner == nil || end.Winner.ID != "a" || end.Draw {
+		t.Errorf("game ended with winner %v (draw %v), want the remaining player", end.Winner, end.Draw)
+	}
+	if len(end.EliminationOrder) != 1 || end.EliminationOrder[0].PlayerID != "b" || end.EliminationOrder[0].Reason != models.EliminationLeft {
+		t.Errorf("elimination order %+v, want only b, for leaving", end.EliminationOrder)
+	}
+
+	lh.lobby.Mutex.RLock()
+	defer lh.lobby.Mutex.RUnlock()
+	if lh.gameRunning {
+		t.Error("game still marked running after it ended")
+	}
+	if lh.lobby.Status != "playing" || !lh.lobby.GameStarted {
+		t.Errorf("lobby status %q after the game, want it left on the finished game rather than reset", lh.lobby.Status)
+	}
+}
+
+func TestResyncReturnsCurrentState(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	player := addTestPlayer(lh, "p1", true)
+	addTestPlayer(lh, "p2", true)
+	lh.GameState = newTestGame(at(1, 1), at(13, 11))
+	lh.lobby.GameStarted = true
+	for i := 0; i < 5; i++ {
+		GameTick(lh.GameState)
+	}
+	drainMessages(t, player)
+
+	lh.handleMessage(player, &models.WebSocketMessage{Type: models.MSG_RESYNC, Data: &models.ResyncRequest{LastSeq: 2}})
+	if got := messagesOfType(drainMessages(t, player), models.MSG_RESYNC); len(got) != 0 {
+		t.Fatalf("resync answered before the game loop served it: %v", got)
+	}
+
+	GameTick(lh.GameState)
+	lh.serveResyncs()
+	replies := messagesOfType(drainMessages(t, player), models.MSG_RESYNC)
+	if len(replies) != 1 {
+		t.Fatalf("got %d resync replies, want 1", len(replies))
+	}
+	var reply struct {
+		Seq   int             `json:"seq"`
+		State json.RawMessage `json:"state"`
+	}
+	if err := json.Unmarshal(replies[0].Data, &reply); err != nil {
+		t.Fatal(err)
+	}
+	if reply.Seq != lh.GameState.Tick {
+		t.Errorf("resync seq %d, want the current tick %d", reply.Seq, lh.GameState.Tick)
+	}
+	want, _ := json.Marshal(lh.GameState)
+	if string(reply.State) != string(want) {
+		t.Errorf("resync state differs from the server's\n got: %s\nwant: %s", reply.State, want)
+	}
+}
+
+func TestNicknameUniqueness(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	useFakeClock(t, lh, 0)
+	join := func(player *models.WebSocketPlayer, nickname string) []testMessage {
+		lh.handleJoinLobby(player, &models.WebSocketMessage{Type: models.MSG_JOIN_LOBBY, Data: map[string]string{"nickname": nickname}})
+		return messagesOfType(drainMessages(t, player), models.MSG_ERROR)
+	}
+	inLobby := func(player *models.WebSocketPlayer) bool {
+		lh.lobby.Mutex.RLock()
+		defer lh.lobby.Mutex.RUnlock()
+		_, ok := lh.lobby.Players[player.WebSocketID]
+		return ok
+	}
+
+	first := addTestPlayer(lh, "first", false)
+	second := addTestPlayer(lh, "second", false)
+	if errs := join(first, "Bob"); len(errs) != 0 {
+		t.Fatalf("first join failed: %s", errs[0].Data)
+	}
+	if errs := join(second, "bob"); len(errs) != 1 || !strings.Contains(string(errs[0].Data), "already taken") || inLobby(second) {
+		t.Errorf("\"bob\" joined next to \"Bob\" (errors %v)", errs)
+	}
+	if errs := join(first, "BOB"); len(errs) != 0 {
+		t.Errorf("re-joining under one's own name in other case rejected: %s", errs[0].Data)
+	}
+
+	// The name is free as soon as its holder disconnects
+	lh.unregisterPlayer(first)
+	if errs := join(second, "bob"); len(errs) != 0 || !inLobby(second) {
+		t.Errorf("name not reusable after its holder left (errors %v)", errs)
+	}
+}
+
+func TestDeadPlayerSpectates(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	dead := addTestPlayer(lh, "p1", true)
+	addTestPlayer(lh, "p2", true)
+	addTestPlayer(lh, "p3", true)
+	lh.GameState = newTestGame(at(1, 1), at(13, 11), at(1, 11))
+	lh.lobby.GameStarted = true
+	gamePlayer := lh.GameState.Players[0]
+	drainMessages(t, dead)
+
+	KillPlayer(lh.GameState, gamePlayer, "p2")
+	lh.notifyEliminated(lh.GameState.EliminationOrder)
+	if got := messagesOfType(drainMessages(t, dead), models.MSG_YOU_DIED); len(got) != 1 {
+		t.Fatalf("got %d you_died events, want 1", len(got))
+	}
+
+	// Inputs from the spectator reach the queue but change nothing
+	lh.handleMessage(dead, &models.WebSocketMessage{Type: models.MSG_PLAYER_MOVE, Data: map[string]string{"direction": "right"}})
+	lh.handleMessage(dead, &models.WebSocketMessage{Type: models.MSG_PLACE_BOMB})
+	lh.movesMutex.Lock()
+	moves := lh.pendingMoves
+	lh.pendingMoves = nil
+	lh.movesMutex.Unlock()
+	position := gamePlayer.Position
+	ResolveMoves(lh.GameState, moves)
+	GameTick(lh.GameState)
+	if gamePlayer.Position != position || len(lh.GameState.Bombs) != 0 {
+		t.Errorf("dead player's input applied: at %v (was %v), %d bombs", gamePlayer.Position, position, len(lh.GameState.Bombs))
+	}
+
+	lh.broadcastToLobby("", &models.WebSocketMessage{Type: models.MSG_GAME_STATE_UPDATE, Data: lh.GameState})
+	if got := messagesOfType(drainMessages(t, dead), models.MSG_GAME_STATE_UPDATE); len(got) != 1 {
+		t.Errorf("dead player got %d state updates, want 1", len(got))
+	}
+}
+
+func TestLegacyInputHandlersEmitCanonicalUpdate(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	p1 := addTestPlayer(lh, "a", true)
+	p2 := addTestPlayer(lh, "b", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.Status = "starting"
+	gen := lh.beginCountdown()
+	lh.lobby.Mutex.Unlock()
+	lh.startGame(gen)
+	defer func() {
+		// Emptying the lobby makes the game loop abandon the game
+		lh.unregisterPlayer(p1)
+		lh.unregisterPlayer(p2)
+	}()
+
+	lh.handlePlaceBomb(p1, &models.WebSocketMessage{Type: models.MSG_PLACE_BOMB})
+	lh.handlePlayerMove(p1, &models.WebSocketMessage{Type: models.MSG_PLAYER_MOVE, Data: map[string]string{"direction": "right"}})
+
+	var messages []testMessage
+	waitFor(t, "a state update with the bomb", func() bool {
+		messages = append(messages, drainMessages(t, p2)...)
+		for _, update := range messagesOfType(messages, models.MSG_GAME_STATE_UPDATE) {
+			var state struct {
+				Bombs []json.RawMessage `json:"bombs"`
+			}
+			if json.Unmarshal(update.Data, &state) == nil && len(state.Bombs) > 0 {
+				return true
+			}
+		}
+		return false
+	})
+	if legacy := messagesOfType(messages, models.MSG_GAME_UPDATE); len(legacy) != 0 {
+		t.Errorf("got %d deprecated %s messages, want only %s", len(legacy), models.MSG_GAME_UPDATE, models.MSG_GAME_STATE_UPDATE)
+	}
+}
+
+func TestEmptiedLobbyIsReset(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	a := addTestPlayer(lh, "a", true)
+	b := addTestPlayer(lh, "b", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.MapSeed = 42
+	lh.lobby.SeriesWins = 2
+	lh.lobby.SeriesScore = map[string]int{"a": 1}
+	lh.lobby.Messages = append(lh.lobby.Messages, models.ChatMessage{Nickname: "a", Message: "gg"})
+	lh.lobby.Status = "starting"
+	gen := lh.beginCountdown()
+	lh.lobby.Mutex.Unlock()
+	lh.startGame(gen)
+
+	lh.unregisterPlayer(a)
+	lh.unregisterPlayer(b)
+
+	// The game loop notices on its next tick and stops
+	waitFor(t, "the abandoned game loop to stop", func() bool {
+		lh.lobby.Mutex.RLock()
+		defer lh.lobby.Mutex.RUnlock()
+		return !lh.gameRunning
+	})
+
+	lh.lobby.Mutex.RLock()
+	defer lh.lobby.Mutex.RUnlock()
+	if lh.lobby.Status != "waiting" || lh.lobby.GameStarted || lh.lobby.Round != 0 {
+		t.Errorf("lobby %q (started %v, round %d), want a fresh waiting lobby", lh.lobby.Status, lh.lobby.GameStarted, lh.lobby.Round)
+	}
+	if len(lh.lobby.SeriesScore) != 0 || len(lh.lobby.Messages) != 0 || lh.lobby.Host != "" {
+		t.Errorf("series score %v, %d chat messages and host %q left behind", lh.lobby.SeriesScore, len(lh.lobby.Messages), lh.lobby.Host)
+	}
+	if lh.countdownGen == gen || lh.previewMap != nil || len(lh.bots) != 0 {
+		t.Error("countdown, previewed map or bots survived the reset")
+	}
+	lh.movesMutex.Lock()
+	queued := len(lh.pendingMoves) + len(lh.pendingResyncs) + len(lh.pendingStats) + len(lh.pendingEmotes)
+	lh.movesMutex.Unlock()
+	if queued != 0 {
+		t.Errorf("%d queued requests survived the reset", queued)
+	}
+	if lh.lobby.MapSeed != 42 || lh.lobby.SeriesWins != 2 {
+		t.Errorf("settings lost: map seed %d, series wins %d", lh.lobby.MapSeed, lh.lobby.SeriesWins)
+	}
+}
+
+func TestMyStatsAfterPowerUps(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	player := addTestPlayer(lh, "p1", true)
+	addTestPlayer(lh, "p2", true)
+	watcher := addTestPlayer(lh, "watcher", true) // Joined after the game started
+	lh.GameState = newTestGame(at(1, 1), at(13, 11))
+	lh.lobby.GameStarted = true
+	gs := lh.GameState
+	walker := gs.Players[0]
+	before := *walker
+	gs.PowerUps = []*models.ActivePowerUp{
+		{Position: at(2, 1), Type: models.BombUp},
+		{Position: at(3, 1), Type: models.FlameUp},
+		{Position: at(4, 1), Type: models.SpeedUp},
+	}
+
+	ResolveMoves(gs, []MoveIntent{{Player: walker, Action: ActionMoveStart, Direction: models.DirRight}})
+	for i := 0; i < 100 && walker.Position != at(4, 1); i++ {
+		GameTick(gs)
+	}
+	ResolveMoves(gs, []MoveIntent{{Player: walker, Action: ActionMoveStop}})
+	if len(gs.PowerUps) != 0 {
+		t.Fatalf("%d power-ups left on the map, want all three collected", len(gs.PowerUps))
+	}
+	drainMessages(t, player)
+	drainMessages(t, watcher)
+
+	lh.handleMessage(player, &models.WebSocketMessage{Type: models.MSG_GET_MY_STATS})
+	lh.handleMessage(watcher, &models.WebSocketMessage{Type: models.MSG_GET_MY_STATS})
+	lh.serveStats()
+
+	replies := messagesOfType(drainMessages(t, player), models.MSG_MY_STATS)
+	if len(replies) != 1 {
+		t.Fatalf("got %d my_stats replies, want 1", len(replies))
+	}
+	var stats models.PlayerStats
+	if err := json.Unmarshal(replies[0].Data, &stats); err != nil {
+		t.Fatal(err)
+	}
+	if stats.BombCount != before.BombCount+1 || stats.FlameRange != before.FlameRange+1 || stats.Speed != before.Speed+1 {
+		t.Errorf("stats bombs %d, flame %d, speed %d; want one more of each than %d, %d, %d",
+			stats.BombCount, stats.FlameRange, stats.Speed, before.BombCount, before.FlameRange, before.Speed)
+	}
+	if stats.PlayerID != "p1" || stats.Tick != gs.Tick || stats.Lives != before.Lives || !stats.Alive {
+		t.Errorf("stats %+v, want p1's as of tick %d", stats, gs.Tick)
+	}
+
+	if errs := messagesOfType(drainMessages(t, watcher), models.MSG_ERROR); len(errs) != 1 || !strings.Contains(string(errs[0].Data), "not playing") {
+		t.Errorf("lobby member outside the game got errors %v, want one saying they are not playing", errs)
+	}
+}
+
+func TestJoinDuringGameRejected(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	addTestPlayer(lh, "p1", true)
+	addTestPlayer(lh, "p2", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.Host = "p1"
+	lh.lobby.Status = "playing"
+	lh.lobby.GameStarted = true
+	lh.gameRunning = true
+	lh.GameState = newTestGame(at(1, 1), at(13, 11))
+	lh.lobby.Mutex.Unlock()
+	before, _ := json.Marshal(lh.GameState)
+
+	late := addTestPlayer(lh, "late", false)
+	drainMessages(t, late)
+	lh.handleJoinLobby(late, &models.WebSocketMessage{Type: models.MSG_JOIN_LOBBY, Data: map[string]string{"nickname": "late"}})
+
+	errs := messagesOfType(drainMessages(t, late), models.MSG_ERROR)
+	if len(errs) != 1 {
+		t.Fatalf("got %d errors, want 1", len(errs))
+	}
+	var errResp models.ErrorResponse
+	if err := json.Unmarshal(errs[0].Data, &errResp); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(errResp.Message, "in progress") || errResp.Hint != JoinHintWait {
+		t.Errorf("error %+v, want a game in progress message with hint %q", errResp, JoinHintWait)
+	}
+
+	lh.lobby.Mutex.RLock()
+	defer lh.lobby.Mutex.RUnlock()
+	if _, ok := lh.lobby.Players["late"]; ok || len(lh.lobby.Players) != 2 || late.LobbyID != "" {
+		t.Errorf("late joiner added to the lobby: %d players, lobby ID %q", len(lh.lobby.Players), late.LobbyID)
+	}
+	if lh.lobby.Host != "p1" || lh.lobby.Status != "playing" || !lh.lobby.GameStarted || !lh.gameRunning {
+		t.Errorf("lobby changed: host %q, status %q, started %v, running %v", lh.lobby.Host, lh.lobby.Status, lh.lobby.GameStarted, lh.gameRunning)
+	}
+	if after, _ := json.Marshal(lh.GameState); string(after) != string(before) {
+		t.Errorf("running game changed by the join\nbefore: %s\n after: %s", before, after)
+	}
+}
+
+func TestCountdownEndsOnDeadline(t *testing.T) {
+	const seconds = 10
+	for _, tc := range []struct {
+		name  string
+		extra func(step int) time.Duration // How late each sleep wakes up
+	}{
+		{"exact", func(int) time.Duration { return 0 }},
+		{"late wake-ups", func(int) time.Duration { return 150 * time.Millisecond }},
+		{"long pause", func(step int) time.Duration {
+			if step == 3 {
+				return 2500 * time.Millisecond
+			}
+			return 0
+		}},
+	} {
+		lh := NewLobbyHandler(testLogger())
+		start := time.Unix(1700000000, 0)
+		now, step := start, 0
+		lh.now = func() time.Time { return now }
+		lh.sleep = func(d time.Duration) {
+			now = now.Add(d + tc.extra(step))
+			step++
+		}
+
+		var ticks []int
+		if !lh.runCountdown(seconds, func(secondsLeft int) bool {
+			ticks = append(ticks, secondsLeft)
+			return true
+		}) {
+			t.Fatalf("%s: countdown reported as cancelled", tc.name)
+		}
+
+		if elapsed := now.Sub(start); elapsed < seconds*time.Second || elapsed > seconds*time.Second+300*time.Millisecond {
+			t.Errorf("%s: countdown took %v, want %ds within 300ms", tc.name, elapsed, seconds)
+		}
+		if len(ticks) == 0 || ticks[0] != seconds || ticks[len(ticks)-1] != 1 {
+			t.Errorf("%s: broadcast %v, want %d down to 1", tc.name, ticks, seconds)
+		}
+		for i := 1; i < len(ticks); i++ {
+			if ticks[i] >= ticks[i-1] {
+				t.Errorf("%s: broadcast %v does not count down", tc.name, ticks)
+				break
+			}
+		}
+		if tc.name == "exact" && len(ticks) != seconds {
+			t.Errorf("exact: broadcast %v, want every second", ticks)
+		}
+	}
+}
+
+func TestZeroStartTimerClamped(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	useFakeClock(t, lh, 100)
+	lh.maxGameTicks = 1 // End the game on its first tick
+	player := addTestPlayer(lh, "a", true)
+	addTestPlayer(lh, "b", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.StartTimer = 0
+	lh.lobby.Status = "starting"
+	gen := lh.beginCountdown()
+	lh.lobby.Mutex.Unlock()
+
+	lh.startGameCountdown(gen)
+
+	var messages []testMessage
+	waitFor(t, "game end", func() bool {
+		messages = append(messages, drainMessages(t, player)...)
+		return len(messagesOfType(messages, models.MSG_GAME_END)) > 0
+	})
+	var ticks []int
+	for _, m := range messagesOfType(messages, models.MSG_TIMER_UPDATE) {
+		var update models.TimerUpdate
+		if err := json.Unmarshal(m.Data, &update); err != nil {
+			t.Fatal(err)
+		}
+		ticks = append(ticks, update.SecondsLeft)
+	}
+	if len(ticks) != MinStartTimer || ticks[0] != MinStartTimer {
+		t.Errorf("countdown broadcast %v, want %d seconds down to 1", ticks, MinStartTimer)
+	}
+	if n := len(messagesOfType(messages, models.MSG_GAME_START)); n != 1 {
+		t.Errorf("got %d game_start messages, want 1", n)
+	}
+}
+
+func TestPlayerDiedNamesKiller(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	watcher := addTestPlayer(lh, "p1", true)
+	addTestPlayer(lh, "p2", true)
+	addTestPlayer(lh, "p3", true)
+	addTestPlayer(lh, "p4", true)
+	lh.GameState = newTestGame(at(1, 1), at(3, 1), at(13, 11), at(1, 11))
+	lh.lobby.GameStarted = true
+	gs := lh.GameState
+	owner, victim, leaver := gs.Players[0], gs.Players[1], gs.Players[2]
+	owner.Lives, victim.Lives = 1, 1
+	// The owner's bomb catches both the owner and the player next to it
+	gs.Bombs = []*models.Bomb{{Position: at(2, 1), OwnerID: owner.ID, Timer: 1, FlameRange: 1}}
+	// Leaving is an elimination too, but not a death
+	ResolveMoves(gs, []MoveIntent{{Player: leaver, Action: ActionLeave}})
+	GameTick(gs)
+	if owner.Alive || victim.Alive {
+		t.Fatalf("after the blast owner alive=%v, victim alive=%v, want both dead", owner.Alive, victim.Alive)
+	}
+	drainMessages(t, watcher)
+
+	lh.broadcastDeaths(gs.EliminationOrder)
+	deaths := map[string]models.PlayerDiedEvent{}
+	for _, m := range messagesOfType(drainMessages(t, watcher), models.MSG_PLAYER_DIED) {
+		var event models.PlayerDiedEvent
+		if err := json.Unmarshal(m.Data, &event); err != nil {
+			t.Fatal(err)
+		}
+		deaths[event.PlayerID] = event
+	}
+	if len(deaths) != 2 {
+		t.Fatalf("player_died events %+v, want one each for %s and %s", deaths, owner.ID, victim.ID)
+	}
+	if got := deaths[owner.ID]; got.KillerID != owner.ID || !got.SelfKill || got.Position != at(1, 1) || got.Tick != gs.Tick {
+		t.Errorf("owner's death %+v, want a self-kill at (1,1) on tick %d", got, gs.Tick)
+	}
+	if got := deaths[victim.ID]; got.KillerID != owner.ID || got.SelfKill || got.Position != at(3, 1) {
+		t.Errorf("victim's death %+v, want a kill by %s at (3,1)", got, owner.ID)
+	}
+}
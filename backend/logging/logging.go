@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+)
+
+type Level int
+
+const (
+	Debug Level = iota
+	Info
+	Warn
+	Error
+)
+
+// String returns the tag printed in front of each log line.
+func (l Level) String() string {
+	switch l {
+	case Debug:
+		return "DEBUG"
+	case Info:
+		return "INFO"
+	case Warn:
+		return "WARN"
+	case Error:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(l))
+}
+
+// ParseLevel converts a level name ("debug", "info", "warn", "error") to a Level.
+// Unknown or empty names fall back to Info.
+func ParseLevel(name string) Level {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "debug":
+		return Debug
+	case "warn", "warning":
+		return Warn
+	case "error":
+		return Error
+	}
+	return Info
+}
+
+// Logger is a minimal leveled logger. Messages below the configured level are dropped.
+type Logger struct {
+	level Level
+	out   *log.Logger
+}
+
+// New creates a Logger that writes messages at or above level to w.
+func New(w io.Writer, level Level) *Logger {
+	return &Logger{
+		level: level,
+		out:   log.New(w, "", log.LstdFlags),
+	}
+}
+
+// Default returns a Logger writing to stderr, with the level taken from the LOG_LEVEL env variable.
+func Default() *Logger {
+	return New(os.Stderr, ParseLevel(os.Getenv("LOG_LEVEL")))
+}
+
+// Enabled reports whether messages at the given level are written.
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	l.out.Printf("[%s] %s", level, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) Debugf(format string, args ...interface{}) { l.logf(Debug, format, args...) }
+func (l *Logger) Infof(format string, args ...interface{})  { l.logf(Info, format, args...) }
+func (l *Logger) Warnf(format string, args ...interface{})  { l.logf(Warn, format, args...) }
+func (l *Logger) Errorf(format string, args ...interface{}) { l.logf(Error, format, args...) }
@@ -0,0 +1,45 @@
+package logging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLoggerLevelFiltering(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, Warn)
+
+	l.Debugf("debug %d", 1)
+	l.Infof("info %d", 2)
+	l.Warnf("warn %d", 3)
+	l.Errorf("error %d", 4)
+
+	out := buf.String()
+	for _, dropped := range []string{"debug 1", "info 2"} {
+		if strings.Contains(out, dropped) {
+			t.Errorf("message %q below Warn was written: %q", dropped, out)
+		}
+	}
+	for _, kept := range []string{"[WARN] warn 3", "[ERROR] error 4"} {
+		if !strings.Contains(out, kept) {
+			t.Errorf("missing %q in %q", kept, out)
+		}
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := map[string]Level{
+		"debug":   Debug,
+		" INFO ":  Info,
+		"warning": Warn,
+		"Error":   Error,
+		"":        Info,
+		"verbose": Info,
+	}
+	for name, want := range tests {
+		if got := ParseLevel(name); got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -1,19 +1,43 @@
 package main
 
 import (
+	"bomberman-dom/logging"
 	"log"
 	"net/http"
 )
 
 func main() {
+	// LOG_LEVEL=debug|info|warn|error controls how chatty the server is
+	logger := logging.Default()
+	gameLogger = logger
+
 	// Create a new lobby handler which manages the game
-	lobbyHandler := NewLobbyHandler()
+	lobbyHandler := NewLobbyHandler(logger)
+
+	logger.Infof("Bomberman Backend Server starting on :8080")
+	log.Fatal(http.ListenAndServe(":8080", NewServeMux(lobbyHandler)))
+}
+
+// NewServeMux registers every HTTP endpoint of the server on a fresh mux, so the whole server
+// can also be mounted elsewhere, for example on an httptest.Server driven by real WebSocket clients.
+func NewServeMux(lobbyHandler *LobbyHandler) *http.ServeMux {
+	mux := http.NewServeMux()
 
 	// Set up WebSocket endpoint
-	http.HandleFunc("/ws", lobbyHandler.ServeWS)
+	mux.HandleFunc("/ws", lobbyHandler.ServeWS)
+
+	// Monitoring endpoints
+	mux.HandleFunc("/healthz", lobbyHandler.ServeHealth)
+	mux.HandleFunc("/metrics", lobbyHandler.ServeMetrics)
+
+	// Lobby state for lobby browsers / dashboards
+	mux.HandleFunc("/lobby", lobbyHandler.ServeLobby)
+
+	// Admin endpoints, enabled by setting ADMIN_TOKEN
+	mux.HandleFunc("/admin/motd", lobbyHandler.ServeAdminMOTD)
 
 	// Add CORS headers for development
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
 		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
@@ -26,6 +50,5 @@ func main() {
 		w.Write([]byte("Bomberman Backend Server is running!"))
 	})
 
-	log.Println("🎮 Bomberman Backend Server starting on :8080")
-	log.Fatal(http.ListenAndServe(":8080", nil))
+	return mux
 }
@@ -2,33 +2,202 @@ package main
 
 import (
 	"bomberman-dom/models"
+	"fmt"
 	"math/rand"
+	"time"
 )
 
 const (
-	MapWidth      = 15
-	MapHeight     = 13
-	TotalBlocks   = 80
-	SpeedPowerUps = 5
-	FlamePowerUps = 5
-	BombPowerUps  = 5
+	MapWidth   = 15 // Default map width
+	MapHeight  = 13 // Default map height
+	MinMapSize = 7  // Smallest width/height that still fits four separate spawn corners
+	MaxMapSize = 31
+
+	// DefaultPowerUpDropRate is the percentage of blocks hiding a power-up (about 16 of 80).
+	DefaultPowerUpDropRate = 20
 )
 
-// GenerateMap creates a new map by calling helper functions to create the walls and blocks.
-func GenerateMap(width, height int) *models.Map {
-	// Seed the random number generator once.
+// PowerUpWeights sets how likely each type is once a block has rolled a power-up.
+var PowerUpWeights = []struct {
+	Type   models.PowerUpType
+	Weight int
+}{
+	{models.SpeedUp, 3},
+	{models.FlameUp, 3},
+	{models.BombUp, 3},
+	{models.CurseReverse, 1},
+	{models.CurseAutoBomb, 1},
+	{models.Shield, 1},
+	{models.PunchBomb, 1},
+	{models.LavaBomb, 1},
+}
 
-	walls := GenerateWalls(width, height)
-	blocks := GenerateBlocks(width, height, walls)
+// NewRNG returns a clock-seeded random source for map generation. Anything that needs the same
+// map twice passes GenerateMap a source built from a fixed seed instead.
+func NewRNG() *rand.Rand {
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+// MapRNG returns the random source for a map: seeded with seed, so the same seed and config
+// always give the same map, or from the clock when seed is 0.
+func MapRNG(seed int64) *rand.Rand {
+	if seed == 0 {
+		return NewRNG()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// rollHiddenPowerUp returns a power-up of a weighted random type with probability dropChance, nil otherwise.
+func rollHiddenPowerUp(rng *rand.Rand, dropChance float64) *models.PowerUp {
+	if rng.Float64() >= dropChance {
+		return nil
+	}
+
+	total := 0
+	for _, pu := range PowerUpWeights {
+		total += pu.Weight
+	}
+	if total <= 0 {
+		return nil
+	}
+	n := rng.Intn(total)
+	for _, pu := range PowerUpWeights {
+		if n < pu.Weight {
+			return &models.PowerUp{Type: pu.Type}
+		}
+		n -= pu.Weight
+	}
+	return nil
+}
+
+// Block densities: the share of the free tiles in the pattern's region that get a block.
+// Normal fills 80% of them, which is the classic 80 blocks on the default 15x13 map.
+const (
+	BlockDensitySparse = "sparse"
+	BlockDensityNormal = "normal"
+	BlockDensityDense  = "dense"
+)
+
+var blockDensityPercent = map[string]int{
+	BlockDensitySparse: 40,
+	BlockDensityNormal: 80,
+	BlockDensityDense:  95,
+}
+
+// Block patterns: which free tiles may hold a block.
+const (
+	BlockPatternRandom = "random" // Anywhere outside the spawn areas
+	BlockPatternRing   = "ring"   // A band along the border, leaving the middle open
+	BlockPatternCross  = "cross"  // Three-tile-wide bands through the centre row and column
+)
+
+// BlockDensities and BlockPatterns list the layout options, for capabilities and validation.
+var (
+	BlockDensities = []string{BlockDensitySparse, BlockDensityNormal, BlockDensityDense}
+	BlockPatterns  = []string{BlockPatternRandom, BlockPatternRing, BlockPatternCross}
+)
+
+// GenerateMap creates a new map of cfg's size by calling helper functions to create the walls and blocks.
+// With cfg.SymmetricMap, blocks and power-ups are mirrored so every spawn corner faces the same layout.
+// cfg.BlockDensity and cfg.BlockPattern decide how many blocks there are and where.
+// All randomness comes from rng, so the same seed and config always give the same map.
+func GenerateMap(cfg models.GameConfig, rng *rand.Rand) *models.Map {
+	walls := GenerateWalls(cfg.MapWidth, cfg.MapHeight)
+	var blocks []*models.Block
+	if cfg.SymmetricMap {
+		blocks = GenerateSymmetricBlocks(cfg, walls, rng)
+	} else {
+		blocks = GenerateBlocks(cfg, walls, rng)
+	}
 
 	return &models.Map{
-		Width:  width,
-		Height: height,
+		Width:  cfg.MapWidth,
+		Height: cfg.MapHeight,
 		Walls:  walls,
 		Blocks: blocks,
 	}
 }
 
+// ValidateBlockLayout checks the density and pattern names, and that the pattern leaves room
+// for at least one block on a map of the given size. Empty names mean normal and random.
+func ValidateBlockLayout(width, height int, density, pattern string) error {
+	if density != "" && blockDensityPercent[density] == 0 {
+		return fmt.Errorf("unknown block density %q", density)
+	}
+	if pattern != "" && pattern != BlockPatternRandom && pattern != BlockPatternRing && pattern != BlockPatternCross {
+		return fmt.Errorf("unknown block pattern %q", pattern)
+	}
+	candidates := blockCandidates(width, height, GenerateWalls(width, height), pattern)
+	if blockCount(density, len(candidates)) == 0 {
+		return fmt.Errorf("a %s %s layout leaves no room for blocks on a %dx%d map", density, pattern, width, height)
+	}
+	return nil
+}
+
+// blockCount is how many of the available tiles get a block at the given density.
+func blockCount(density string, available int) int {
+	percent, ok := blockDensityPercent[density]
+	if !ok {
+		percent = blockDensityPercent[BlockDensityNormal]
+	}
+	return available * percent / 100
+}
+
+// inBlockPattern reports whether a tile belongs to the region a pattern fills.
+func inBlockPattern(pos models.Position, width, height int, pattern string) bool {
+	switch pattern {
+	case BlockPatternRing:
+		depth := min(width, height) / 4
+		return min(pos.X-1, pos.Y-1, width-2-pos.X, height-2-pos.Y) < depth
+	case BlockPatternCross:
+		cx, cy := (width-1)/2, (height-1)/2
+		return abs(pos.X-cx) <= 1 || abs(pos.Y-cy) <= 1
+	default:
+		return true
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// blockCandidates returns the free tiles outside the spawn areas that the pattern allows.
+func blockCandidates(width, height int, walls []*models.Wall, pattern string) []models.Position {
+	wallMap := make(map[models.Position]bool)
+	for _, wall := range walls {
+		wallMap[wall.Position] = true
+	}
+
+	candidates := []models.Position{}
+	for y := 1; y < height-1; y++ {
+		for x := 1; x < width-1; x++ {
+			pos := models.Position{X: x, Y: y}
+			if !wallMap[pos] && !IsSpawnArea(x, y, width, height) && inBlockPattern(pos, width, height, pattern) {
+				candidates = append(candidates, pos)
+			}
+		}
+	}
+	return candidates
+}
+
+// ValidateMapSize checks that the dimensions are odd (so the wall grid closes evenly)
+// and within [MinMapSize, MaxMapSize] so the four spawn corners fit.
+func ValidateMapSize(width, height int) error {
+	if width < MinMapSize || height < MinMapSize {
+		return fmt.Errorf("map must be at least %dx%d, got %dx%d", MinMapSize, MinMapSize, width, height)
+	}
+	if width > MaxMapSize || height > MaxMapSize {
+		return fmt.Errorf("map must be at most %dx%d, got %dx%d", MaxMapSize, MaxMapSize, width, height)
+	}
+	if width%2 == 0 || height%2 == 0 {
+		return fmt.Errorf("map dimensions must be odd, got %dx%d", width, height)
+	}
+	return nil
+}
+
 // generateWalls creates the indestructible walls in a fixed pattern.
 // This includes the outer border and the inner grid, classic to Bomberman.
 func GenerateWalls(width, height int) []*models.Wall {
@@ -48,68 +217,101 @@ func GenerateWalls(width, height int) []*models.Wall {
 	return walls
 }
 
-// generateBlocks places a fixed number of destructible blocks and power-ups randomly on the map.
-func GenerateBlocks(width, height int, walls []*models.Wall) []*models.Block {
+// generateBlocks places destructible blocks randomly within the pattern's region, as many as
+// the density asks for, each hiding a power-up with probability cfg.PowerUpChance.
+func GenerateBlocks(cfg models.GameConfig, walls []*models.Wall, rng *rand.Rand) []*models.Block {
 	// 1. Find all possible positions for blocks.
-	wallMap := make(map[models.Position]bool)
-	for _, wall := range walls {
-		wallMap[wall.Position] = true
-	}
-
-	availablePositions := []models.Position{}
-	for y := 1; y < height-1; y++ {
-		for x := 1; x < width-1; x++ {
-			pos := models.Position{X: x, Y: y}
-			if !wallMap[pos] && !IsSpawnArea(x, y, width, height) {
-				availablePositions = append(availablePositions, pos)
-			}
-		}
-	}
+	availablePositions := blockCandidates(cfg.MapWidth, cfg.MapHeight, walls, cfg.BlockPattern)
 
 	// 2. Shuffle the available positions to randomize block placement.
-	rand.Shuffle(len(availablePositions), func(i, j int) {
+	rng.Shuffle(len(availablePositions), func(i, j int) {
 		availablePositions[i], availablePositions[j] = availablePositions[j], availablePositions[i]
 	})
 
-	// 3. Create the list of power-ups to be placed.
-	powerUps := []*models.PowerUp{}
-	for i := 0; i < SpeedPowerUps; i++ {
-		powerUps = append(powerUps, &models.PowerUp{Type: models.SpeedUp})
-	}
-	for i := 0; i < FlamePowerUps; i++ {
-		powerUps = append(powerUps, &models.PowerUp{Type: models.FlameUp})
-	}
-	for i := 0; i < BombPowerUps; i++ {
-		powerUps = append(powerUps, &models.PowerUp{Type: models.BombUp})
-	}
-
-	// 4. Create the blocks and assign power-ups.
+	// 3. Create the blocks; each one independently rolls for a hidden power-up.
 	var blocks []*models.Block
-	numBlocks := TotalBlocks
-	if numBlocks > len(availablePositions) {
-		numBlocks = len(availablePositions) // Ensure we don't place more blocks than available spots.
-	}
+	numBlocks := blockCount(cfg.BlockDensity, len(availablePositions))
 
 	for i := 0; i < numBlocks; i++ {
-		block := &models.Block{
-			Position:  availablePositions[i],
-			Destroyed: false,
-		}
-		// Assign a power-up to the first N blocks, where N is the total number of power-ups.
-		if i < len(powerUps) {
-			block.HiddenPowerUp = powerUps[i]
+		blocks = append(blocks, &models.Block{
+			Position:      availablePositions[i],
+			Destroyed:     false,
+			HiddenPowerUp: rollHiddenPowerUp(rng, cfg.PowerUpChance),
+		})
+	}
+
+	return blocks
+}
+
+// GenerateSymmetricBlocks places blocks in the top-left quadrant and mirrors them across both axes,
+// so all four quadrants hold an identical layout. Power-ups are mirrored along with their blocks.
+// Every pattern is symmetric, so mirroring keeps the blocks inside the pattern's region.
+func GenerateSymmetricBlocks(cfg models.GameConfig, walls []*models.Wall, rng *rand.Rand) []*models.Block {
+	width, height := cfg.MapWidth, cfg.MapHeight
+	candidates := blockCandidates(width, height, walls, cfg.BlockPattern)
+	total := blockCount(cfg.BlockDensity, len(candidates))
+
+	// 1. Collect the candidate tiles of the top-left quadrant (including the center lines).
+	quadrant := []models.Position{}
+	for _, pos := range candidates {
+		if pos.X <= (width-1)/2 && pos.Y <= (height-1)/2 {
+			quadrant = append(quadrant, pos)
 		}
-		blocks = append(blocks, block)
 	}
 
-	// Shuffle the final block list so power-ups aren't always in the first blocks created.
-	rand.Shuffle(len(blocks), func(i, j int) {
-		blocks[i], blocks[j] = blocks[j], blocks[i]
+	rng.Shuffle(len(quadrant), func(i, j int) {
+		quadrant[i], quadrant[j] = quadrant[j], quadrant[i]
 	})
 
+	// 2. Expand each quadrant tile into its mirrored group until the density's count is reached.
+	var groups [][]models.Position
+	placed := 0
+	for _, pos := range quadrant {
+		group := mirrorPositions(pos, width, height)
+		if placed+len(group) > total {
+			continue
+		}
+		groups = append(groups, group)
+		placed += len(group)
+	}
+
+	// 3. Roll once per mirrored group so each quadrant gets the same power-ups.
+	var blocks []*models.Block
+	for _, group := range groups {
+		hidden := rollHiddenPowerUp(rng, cfg.PowerUpChance)
+		for _, pos := range group {
+			block := &models.Block{Position: pos}
+			if hidden != nil {
+				block.HiddenPowerUp = &models.PowerUp{Type: hidden.Type}
+			}
+			blocks = append(blocks, block)
+		}
+	}
+
 	return blocks
 }
 
+// mirrorPositions returns pos and its mirror images across the vertical and horizontal center lines,
+// without duplicates for tiles that lie on a center line.
+func mirrorPositions(pos models.Position, width, height int) []models.Position {
+	candidates := []models.Position{
+		pos,
+		{X: width - 1 - pos.X, Y: pos.Y},
+		{X: pos.X, Y: height - 1 - pos.Y},
+		{X: width - 1 - pos.X, Y: height - 1 - pos.Y},
+	}
+
+	seen := make(map[models.Position]bool)
+	group := []models.Position{}
+	for _, p := range candidates {
+		if !seen[p] {
+			seen[p] = true
+			group = append(group, p)
+		}
+	}
+	return group
+}
+
 // isSpawnArea checks if a position is a player spawn point or an adjacent tile
 // to ensure players have a safe starting zone.
 func IsSpawnArea(x, y, width, height int) bool {
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"encoding/json"
+	"math/rand"
+	"testing"
+)
+
+func TestSymmetricMapQuadrants(t *testing.T) {
+	cfg := DefaultGameConfig()
+	cfg.SymmetricMap = true
+	for seed := int64(1); seed <= 5; seed++ {
+		m := GenerateMap(cfg, MapRNG(seed))
+		m.Reindex()
+		cx, cy := (m.Width-1)/2, (m.Height-1)/2
+
+		// Quadrants exclude the centre lines, which mirror onto themselves
+		var counts [4]int
+		for _, b := range m.Blocks {
+			x, y := b.Position.X, b.Position.Y
+			switch {
+			case x < cx && y < cy:
+				counts[0]++
+			case x > cx && y < cy:
+				counts[1]++
+			case x < cx && y > cy:
+				counts[2]++
+			case x > cx && y > cy:
+				counts[3]++
+			}
+
+			for _, mirror := range mirrorPositions(b.Position, m.Width, m.Height) {
+				other := m.BlockAt(mirror)
+				if other == nil {
+					t.Fatalf("seed %d: block at %v has no mirror at %v", seed, b.Position, mirror)
+				}
+				if (b.HiddenPowerUp == nil) != (other.HiddenPowerUp == nil) ||
+					b.HiddenPowerUp != nil && b.HiddenPowerUp.Type != other.HiddenPowerUp.Type {
+					t.Errorf("seed %d: power-ups at %v and %v differ", seed, b.Position, mirror)
+				}
+			}
+		}
+		if counts[0] == 0 || counts[0] != counts[1] || counts[0] != counts[2] || counts[0] != counts[3] {
+			t.Errorf("seed %d: quadrant block counts %v, want four equal non-zero counts", seed, counts)
+		}
+	}
+}
+
+func TestGenerateMapSizes(t *testing.T) {
+	for _, size := range [][2]int{{11, 11}, {21, 17}} {
+		cfg := DefaultGameConfig()
+		cfg.MapWidth, cfg.MapHeight = size[0], size[1]
+		cfg.BlockDensity = BlockDensityDense
+		m := GenerateMap(cfg, MapRNG(3))
+		m.Reindex()
+
+		if m.Width != size[0] || m.Height != size[1] {
+			t.Fatalf("map is %dx%d, want %dx%d", m.Width, m.Height, size[0], size[1])
+		}
+		for y := 0; y < m.Height; y++ {
+			for x := 0; x < m.Width; x++ {
+				border := x == 0 || y == 0 || x == m.Width-1 || y == m.Height-1
+				wantWall := border || x%2 == 0 && y%2 == 0
+				if got := m.WallAt(at(x, y)); got != wantWall {
+					t.Errorf("%dx%d: wall at (%d,%d) = %v, want %v", m.Width, m.Height, x, y, got, wantWall)
+				}
+				if IsSpawnArea(x, y, m.Width, m.Height) && (m.WallAt(at(x, y)) || m.BlockAt(at(x, y)) != nil) {
+					t.Errorf("%dx%d: spawn area tile (%d,%d) is not free", m.Width, m.Height, x, y)
+				}
+			}
+		}
+		if len(m.Blocks) == 0 {
+			t.Errorf("%dx%d: no blocks generated", m.Width, m.Height)
+		}
+	}
+}
+
+// mapJSON is a map's full layout, hidden power-ups included, for comparing maps.
+func mapJSON(t *testing.T, m *models.Map) string {
+	t.Helper()
+	data, err := json.Marshal(m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestSameSeedSameMap(t *testing.T) {
+	for _, symmetric := range []bool{false, true} {
+		cfg := DefaultGameConfig()
+		cfg.SymmetricMap = symmetric
+		first := GenerateMap(cfg, rand.New(rand.NewSource(99)))
+		second := GenerateMap(cfg, rand.New(rand.NewSource(99)))
+		if mapJSON(t, first) != mapJSON(t, second) {
+			t.Errorf("symmetric %v: two generators seeded alike made different maps:\n%s\n%s", symmetric, first, second)
+		}
+
+		hidden := 0
+		for _, b := range first.Blocks {
+			if b.HiddenPowerUp != nil {
+				hidden++
+			}
+		}
+		if hidden == 0 {
+			t.Errorf("symmetric %v: no hidden power-ups to compare", symmetric)
+		}
+
+		other := GenerateMap(cfg, rand.New(rand.NewSource(100)))
+		if mapJSON(t, first) == mapJSON(t, other) {
+			t.Errorf("symmetric %v: seeds 99 and 100 made the same map", symmetric)
+		}
+	}
+}
+
+func TestMapSeedSetting(t *testing.T) {
+	newMap := func(seed int64) string {
+		cfg := DefaultGameConfig()
+		cfg.MapSeed = seed
+		return mapJSON(t, NewGame(nil, cfg).Map)
+	}
+	if newMap(0) == newMap(0) {
+		t.Error("two unseeded games got the same map")
+	}
+	if newMap(7) != newMap(7) {
+		t.Error("two games seeded alike got different maps")
+	}
+}
+
+func TestBlockDensity(t *testing.T) {
+	counts := map[string]int{}
+	for _, density := range BlockDensities {
+		cfg := DefaultGameConfig()
+		cfg.BlockDensity = density
+		counts[density] = len(GenerateMap(cfg, MapRNG(1)).Blocks)
+	}
+	if !(counts[BlockDensitySparse] < counts[BlockDensityNormal] && counts[BlockDensityNormal] < counts[BlockDensityDense]) {
+		t.Errorf("block counts %v, want sparse < normal < dense", counts)
+	}
+	if counts[BlockDensityNormal] != 80 {
+		t.Errorf("normal density gave %d blocks on the default map, want the classic 80", counts[BlockDensityNormal])
+	}
+
+	if err := ValidateBlockLayout(MapWidth, MapHeight, "packed", BlockPatternRandom); err == nil {
+		t.Error("unknown density accepted")
+	}
+	if err := ValidateBlockLayout(MapWidth, MapHeight, BlockDensityDense, BlockPatternCross); err != nil {
+		t.Errorf("dense cross layout rejected on the default map: %v", err)
+	}
+}
+
+func TestBlockPatternRegions(t *testing.T) {
+	cx, cy := (MapWidth-1)/2, (MapHeight-1)/2
+	for _, c := range []struct {
+		pattern string
+		allowed func(p models.Position) bool
+	}{
+		// Ring: within three tiles of the border on the 15x13 map, so the middle stays open
+		{BlockPatternRing, func(p models.Position) bool {
+			return p.X <= 3 || p.Y <= 3 || p.X >= MapWidth-4 || p.Y >= MapHeight-4
+		}},
+		// Cross: the three rows and columns through the centre
+		{BlockPatternCross, func(p models.Position) bool {
+			return p.X >= cx-1 && p.X <= cx+1 || p.Y >= cy-1 && p.Y <= cy+1
+		}},
+	} {
+		for _, symmetric := range []bool{false, true} {
+			cfg := DefaultGameConfig()
+			cfg.BlockPattern = c.pattern
+			cfg.BlockDensity = BlockDensityDense
+			cfg.SymmetricMap = symmetric
+			m := GenerateMap(cfg, MapRNG(1))
+			if len(m.Blocks) == 0 {
+				t.Errorf("%s (symmetric %v): no blocks generated", c.pattern, symmetric)
+			}
+			for _, b := range m.Blocks {
+				if !c.allowed(b.Position) {
+					t.Errorf("%s (symmetric %v): block at %v outside the pattern", c.pattern, symmetric, b.Position)
+				}
+			}
+		}
+	}
+}
+
+func TestPowerUpDropRateExtremes(t *testing.T) {
+	for _, symmetric := range []bool{false, true} {
+		for _, chance := range []float64{0, 1} {
+			cfg := DefaultGameConfig()
+			cfg.SymmetricMap = symmetric
+			cfg.PowerUpChance = chance
+			for seed := int64(1); seed <= 5; seed++ {
+				m := GenerateMap(cfg, MapRNG(seed))
+				if len(m.Blocks) == 0 {
+					t.Fatalf("seed %d generated no blocks", seed)
+				}
+				hidden := 0
+				for _, b := range m.Blocks {
+					if b.HiddenPowerUp != nil {
+						hidden++
+					}
+				}
+				if want := int(chance) * len(m.Blocks); hidden != want {
+					t.Errorf("symmetric=%v chance %.1f seed %d: %d of %d blocks hide a power-up, want %d",
+						symmetric, chance, seed, hidden, len(m.Blocks), want)
+				}
+			}
+		}
+	}
+}
+
+// linearWallAt and linearBlockAt are the slice scans the map index replaced.
+func linearWallAt(m *models.Map, pos models.Position) bool {
+	for _, wall := range m.Walls {
+		if wall.Position == pos {
+			return true
+		}
+	}
+	return false
+}
+
+func linearBlockAt(m *models.Map, pos models.Position) *models.Block {
+	for _, block := range m.Blocks {
+		if block.Position == pos && !block.Destroyed {
+			return block
+		}
+	}
+	return nil
+}
+
+// largeTestMap is a generated 21x17 map, the size the index was meant to speed up.
+func largeTestMap() *models.Map {
+	cfg := DefaultGameConfig()
+	cfg.MapWidth, cfg.MapHeight = 21, 17
+	return GenerateMap(cfg, MapRNG(1))
+}
+
+func TestMapIndexMatchesLinearScan(t *testing.T) {
+	m := largeTestMap()
+	check := func(stage string) {
+		t.Helper()
+		// One tile beyond every edge too, where both must find nothing
+		for y := -1; y <= m.Height; y++ {
+			for x := -1; x <= m.Width; x++ {
+				pos := at(x, y)
+				if got, want := m.WallAt(pos), linearWallAt(m, pos); got != want {
+					t.Errorf("%s: WallAt%v = %v, linear scan says %v", stage, pos, got, want)
+				}
+				if got, want := m.BlockAt(pos), linearBlockAt(m, pos); got != want {
+					t.Errorf("%s: BlockAt%v = %v, linear scan says %v", stage, pos, got, want)
+				}
+			}
+		}
+	}
+	check("fresh map")
+
+	for i, block := range m.Blocks {
+		if i%2 == 0 {
+			m.DestroyBlock(block)
+		}
+	}
+	check("after destroying half the blocks")
+}
+
+func BenchmarkMapLookup(b *testing.B) {
+	m := largeTestMap()
+	lookups := []struct {
+		name  string
+		solid func(pos models.Position) bool
+	}{
+		{"indexed", func(pos models.Position) bool { return m.WallAt(pos) || m.BlockAt(pos) != nil }},
+		{"linear", func(pos models.Position) bool { return linearWallAt(m, pos) || linearBlockAt(m, pos) != nil }},
+	}
+	for _, lookup := range lookups {
+		b.Run(lookup.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for y := 0; y < m.Height; y++ {
+					for x := 0; x < m.Width; x++ {
+						lookup.solid(at(x, y))
+					}
+				}
+			}
+		})
+	}
+}
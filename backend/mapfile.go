@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+// mapFile is the on-disk JSON format of a custom map.
+type mapFile struct {
+	Width  int               `json:"width"`
+	Height int               `json:"height"`
+	Walls  []models.Position `json:"walls"`
+	Blocks []struct {
+		X       int    `json:"x"`
+		Y       int    `json:"y"`
+		PowerUp string `json:"powerUp,omitempty"` // "speed_up", "flame_up", "bomb_up"
+	} `json:"blocks"`
+	Spawns []models.Position `json:"spawns"`
+}
+
+var mapNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// mapsDir returns the directory custom maps are loaded from (MAPS_DIR, default "maps").
+func mapsDir() string {
+	if dir := os.Getenv("MAPS_DIR"); dir != "" {
+		return dir
+	}
+	return "maps"
+}
+
+// LoadNamedMap loads <mapsDir>/<name>.json. Names are restricted to letters, digits, '_' and '-'.
+func LoadNamedMap(name string) (*models.Map, error) {
+	if !mapNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid map name %q", name)
+	}
+	f, err := os.Open(filepath.Join(mapsDir(), name+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("map %q not found", name)
+	}
+	defer f.Close()
+	return LoadMap(f)
+}
+
+// LoadMap parses a JSON map definition and validates it: the border must be closed by walls,
+// walls and blocks may not overlap, and every spawn must be inside the map and clear.
+func LoadMap(r io.Reader) (*models.Map, error) {
+	var def mapFile
+	if err := json.NewDecoder(r).Decode(&def); err != nil {
+		return nil, fmt.Errorf("invalid map JSON: %w", err)
+	}
+
+	if err := ValidateMapSize(def.Width, def.Height); err != nil {
+		return nil, err
+	}
+
+	inBounds := func(pos models.Position) bool {
+		return pos.X >= 0 && pos.X < def.Width && pos.Y >= 0 && pos.Y < def.Height
+	}
+
+	m := &models.Map{Width: def.Width, Height: def.Height}
+	wallMap := make(map[models.Position]bool)
+	for _, pos := range def.Walls {
+		if !inBounds(pos) {
+			return nil, fmt.Errorf("wall %v is outside the map", pos)
+		}
+		if wallMap[pos] {
+			continue
+		}
+		wallMap[pos] = true
+		m.Walls = append(m.Walls, &models.Wall{Position: pos})
+	}
+
+	// The outer border must be fully walled so nobody can leave the map.
+	for x := 0; x < def.Width; x++ {
+		for _, y := range []int{0, def.Height - 1} {
+			if !wallMap[models.Position{X: x, Y: y}] {
+				return nil, fmt.Errorf("border is open at %v", models.Position{X: x, Y: y})
+			}
+		}
+	}
+	for y := 0; y < def.Height; y++ {
+		for _, x := range []int{0, def.Width - 1} {
+			if !wallMap[models.Position{X: x, Y: y}] {
+				return nil, fmt.Errorf("border is open at %v", models.Position{X: x, Y: y})
+			}
+		}
+	}
+
+	blockMap := make(map[models.Position]bool)
+	for _, b := range def.Blocks {
+		pos := models.Position{X: b.X, Y: b.Y}
+		if !inBounds(pos) {
+			return nil, fmt.Errorf("block %v is outside the map", pos)
+		}
+		if wallMap[pos] {
+			return nil, fmt.Errorf("block %v overlaps a wall", pos)
+		}
+		if blockMap[pos] {
+			return nil, fmt.Errorf("block %v is defined twice", pos)
+		}
+		blockMap[pos] = true
+
+		block := &models.Block{Position: pos}
+		if b.PowerUp != "" {
+			powerUpType, ok := models.ParsePowerUpType(b.PowerUp)
+			if !ok {
+				return nil, fmt.Errorf("block %v has unknown power-up %q", pos, b.PowerUp)
+			}
+			block.HiddenPowerUp = &models.PowerUp{Type: powerUpType}
+		}
+		m.Blocks = append(m.Blocks, block)
+	}
+
+	if len(def.Spawns) > 0 {
+		if err := validateSpawnPoints(def.Spawns, m); err != nil {
+			return nil, err
+		}
+		m.SpawnPoints = def.Spawns
+	} else if err := validateSpawnPoints(SpawnPoints(m.Width, m.Height), m); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// testMapJSON returns a 7x7 map definition with the standard walls, a block at (3,1) and the
+// given extra walls and blocks, dropping the wall at skipWall if it is set.
+func testMapJSON(t *testing.T, skipWall *[2]int, extraBlocks ...[2]int) string {
+	t.Helper()
+	type xy struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+	def := struct {
+		Width  int  `json:"width"`
+		Height int  `json:"height"`
+		Walls  []xy `json:"walls"`
+		Blocks []xy `json:"blocks"`
+	}{Width: 7, Height: 7, Blocks: []xy{{3, 1}}}
+	for _, w := range GenerateWalls(7, 7) {
+		if skipWall != nil && w.Position.X == skipWall[0] && w.Position.Y == skipWall[1] {
+			continue
+		}
+		def.Walls = append(def.Walls, xy{w.Position.X, w.Position.Y})
+	}
+	for _, b := range extraBlocks {
+		def.Blocks = append(def.Blocks, xy{b[0], b[1]})
+	}
+	data, err := json.Marshal(def)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestLoadMap(t *testing.T) {
+	m, err := LoadMap(strings.NewReader(testMapJSON(t, nil)))
+	if err != nil {
+		t.Fatalf("valid map rejected: %v", err)
+	}
+	if m.Width != 7 || m.Height != 7 || len(m.Blocks) != 1 || !m.WallAt(at(0, 3)) {
+		t.Errorf("loaded map %dx%d with %d blocks", m.Width, m.Height, len(m.Blocks))
+	}
+
+	if _, err := LoadNamedMap("cross"); err != nil {
+		t.Errorf("bundled map cross: %v", err)
+	}
+	if _, err := LoadNamedMap("../cross"); err == nil {
+		t.Error("map name with a path was accepted")
+	}
+}
+
+func TestLoadMapRejectsInvalid(t *testing.T) {
+	tests := map[string]struct {
+		json string
+		want string
+	}{
+		"open border":         {testMapJSON(t, &[2]int{0, 3}), "border is open"},
+		"block on a wall":     {testMapJSON(t, nil, [2]int{2, 2}), "overlaps a wall"},
+		"block on a spawn":    {testMapJSON(t, nil, [2]int{1, 1}), "covered by a block"},
+		"even size":           {`{"width": 8, "height": 7}`, "must be odd"},
+		"malformed JSON text": {`{"width": `, "invalid map JSON"},
+	}
+	for name, tt := range tests {
+		_, err := LoadMap(strings.NewReader(tt.json))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: err = %v, want it to mention %q", name, err, tt.want)
+		}
+	}
+}
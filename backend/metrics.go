@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// TickWindow is how many recent game ticks the loop duration average and max cover.
+	TickWindow = 100
+
+	// OverloadTickStreak is how many consecutive ticks over TickInterval trigger an overload warning.
+	OverloadTickStreak = 5
+)
+
+// Metrics holds server counters. Counters are updated atomically so any goroutine may touch them;
+// the tick window has its own mutex.
+type Metrics struct {
+	startedAt         time.Time
+	activeConnections atomic.Int64
+	totalConnections  atomic.Int64
+	messagesReceived  atomic.Int64
+	messagesSent      atomic.Int64
+	gamesStarted      atomic.Int64
+	messagesPerSec    atomic.Int64 // Messages received during the last full second
+
+	tickMu        sync.Mutex
+	tickDurations [TickWindow]time.Duration // Ring buffer of recent game loop durations
+	tickCount     int                       // Ticks recorded, capped at TickWindow
+	tickNext      int                       // Ring index of the next sample
+	slowStreak    int                       // Consecutive ticks over TickInterval
+}
+
+// RecordTick stores how long one game loop iteration took. It returns true every
+// OverloadTickStreak consecutive ticks that overran TickInterval, so the caller can warn.
+func (m *Metrics) RecordTick(d time.Duration) bool {
+	m.tickMu.Lock()
+	defer m.tickMu.Unlock()
+
+	m.tickDurations[m.tickNext] = d
+	m.tickNext = (m.tickNext + 1) % TickWindow
+	if m.tickCount < TickWindow {
+		m.tickCount++
+	}
+
+	if d <= TickInterval {
+		m.slowStreak = 0
+		return false
+	}
+	m.slowStreak++
+	return m.slowStreak%OverloadTickStreak == 0
+}
+
+// TickDurations returns the average and maximum loop duration over the last TickWindow ticks.
+func (m *Metrics) TickDurations() (avg, max time.Duration) {
+	m.tickMu.Lock()
+	defer m.tickMu.Unlock()
+
+	if m.tickCount == 0 {
+		return 0, 0
+	}
+	var total time.Duration
+	for _, d := range m.tickDurations[:m.tickCount] {
+		total += d
+		if d > max {
+			max = d
+		}
+	}
+	return total / time.Duration(m.tickCount), max
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{startedAt: time.Now()}
+	go m.sampleRates()
+	return m
+}
+
+// sampleRates updates messagesPerSec once a second from the received-message counter.
+func (m *Metrics) sampleRates() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	last := m.messagesReceived.Load()
+	for range ticker.C {
+		current := m.messagesReceived.Load()
+		m.messagesPerSec.Store(current - last)
+		last = current
+	}
+}
+
+// ServeHealth answers /healthz with 200 and a short status summary.
+func (lh *LobbyHandler) ServeHealth(w http.ResponseWriter, r *http.Request) {
+	lh.lobby.Mutex.RLock()
+	playerCount := len(lh.lobby.Players)
+	status := lh.lobby.Status
+	lh.lobby.Mutex.RUnlock()
+
+	writeJSON(w, map[string]interface{}{
+		"status":      "ok",
+		"playerCount": playerCount,
+		"lobbyStatus": status,
+		"uptime":      int64(time.Since(lh.metrics.startedAt).Seconds()),
+	})
+}
+
+// ServeMetrics answers /metrics with the current counters as JSON.
+func (lh *LobbyHandler) ServeMetrics(w http.ResponseWriter, r *http.Request) {
+	lh.lobby.Mutex.RLock()
+	playerCount := len(lh.lobby.Players)
+	gamesInProgress := 0
+	if lh.gameRunning {
+		gamesInProgress = 1
+	}
+	lh.lobby.Mutex.RUnlock()
+
+	m := lh.metrics
+	tickAvg, tickMax := m.TickDurations()
+	writeJSON(w, map[string]interface{}{
+		"activeConnections": m.activeConnections.Load(),
+		"totalConnections":  m.totalConnections.Load(),
+		"activeLobbies":     1, // The server runs a single lobby
+		"lobbyPlayers":      playerCount,
+		"gamesInProgress":   gamesInProgress,
+		"gamesStarted":      m.gamesStarted.Load(),
+		"messagesReceived":  m.messagesReceived.Load(),
+		"messagesSent":      m.messagesSent.Load(),
+		"messagesPerSec":    m.messagesPerSec.Load(),
+		"tickAvgMs":         float64(tickAvg) / float64(time.Millisecond),
+		"tickMaxMs":         float64(tickMax) / float64(time.Millisecond),
+		"uptime":            int64(time.Since(m.startedAt).Seconds()),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServeHealth(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	addTestPlayer(lh, "a", true)
+	addTestPlayer(lh, "b", true)
+	addTestPlayer(lh, "spectator", false) // Connected but not in the lobby
+
+	rec := httptest.NewRecorder()
+	NewServeMux(lh).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200", rec.Code)
+	}
+	var health struct {
+		Status      string `json:"status"`
+		PlayerCount int    `json:"playerCount"`
+		LobbyStatus string `json:"lobbyStatus"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &health); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body, err)
+	}
+	if health.Status != "ok" || health.PlayerCount != 2 || health.LobbyStatus != "waiting" {
+		t.Errorf("health = %+v, want ok with 2 players waiting", health)
+	}
+}
+
+func TestSlowTicksTriggerOverloadWarning(t *testing.T) {
+	m := NewMetrics()
+	slow, fast := 2*TickInterval, TickInterval/2
+
+	// A slow streak broken by a normal tick starts over
+	for i := 0; i < OverloadTickStreak-1; i++ {
+		if m.RecordTick(slow) {
+			t.Fatalf("warned after only %d slow ticks", i+1)
+		}
+	}
+	if m.RecordTick(fast) {
+		t.Fatal("warned on a normal tick")
+	}
+
+	var warnedAt []int
+	for i := 1; i <= 2*OverloadTickStreak; i++ {
+		if m.RecordTick(slow) {
+			warnedAt = append(warnedAt, i)
+		}
+	}
+	if len(warnedAt) != 2 || warnedAt[0] != OverloadTickStreak || warnedAt[1] != 2*OverloadTickStreak {
+		t.Errorf("warned after slow ticks %v, want every %d in a row", warnedAt, OverloadTickStreak)
+	}
+
+	if _, peak := m.TickDurations(); peak != slow {
+		t.Errorf("max tick duration %v, want %v", peak, slow)
+	}
+}
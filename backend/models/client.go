@@ -0,0 +1,208 @@
+package models
+
+import "encoding/json"
+
+// The Client* types are the wire format of a game state, as sent in game_start and
+// game_state_update. GameState also carries server-only bookkeeping (grace counters,
+// cooldowns, AFK and length caps, pending inputs, hidden power-ups) that never leaves
+// the server; adding a field to GameState does not change what clients receive.
+
+// ClientGameState is the client schema of a GameState.
+type ClientGameState struct {
+	Tick             int             `json:"tick"`
+	Status           GameStatus      `json:"status"`
+	Players          []ClientPlayer  `json:"players"`
+	Map              *ClientMap      `json:"map"`
+	Bombs            []ClientBomb    `json:"bombs"`
+	Flames           []Position      `json:"flames"`  // Tiles currently on fire
+	Hazards          []Position      `json:"hazards"` // Lava tiles, impassable until they cool
+	PowerUps         []ClientPowerUp `json:"powerUps"`
+	Winner           *ClientPlayer   `json:"winner"` // nil until the game is finished
+	WinningTeam      int             `json:"winningTeam"`
+	Draw             bool            `json:"draw"`
+	TimedOut         bool            `json:"timedOut"`
+	TeamMode         bool            `json:"teamMode"`
+	FogOfWar         bool            `json:"fogOfWar"`
+	VisionRadius     int             `json:"visionRadius"`
+	BombTimer        int             `json:"bombTimer"` // Full fuse length, to draw how far along a bomb is
+	PixelMovement    bool            `json:"pixelMovement"`
+	EliminationOrder []Elimination   `json:"eliminationOrder"`
+}
+
+// ClientPlayer is the public part of a player. Bomb counts and cooldowns go to the owner
+// alone through MSG_PLAYER_STATE.
+type ClientPlayer struct {
+	ID                string         `json:"id"`
+	Name              string         `json:"name"`
+	Position          Position       `json:"position"`
+	Lives             int            `json:"lives"`
+	Alive             bool           `json:"alive"`
+	Score             int            `json:"score"`
+	Speed             int            `json:"speed"`
+	BombCount         int            `json:"bombCount"`
+	FlameRange        int            `json:"flameRange"`
+	Invincible        int            `json:"invincible"`
+	Shielded          int            `json:"shielded"`
+	TeamID            int            `json:"teamId"`
+	IsBot             bool           `json:"isBot"`
+	BlocksDestroyed   int            `json:"blocksDestroyed"`
+	PowerUpsCollected int            `json:"powerUpsCollected"`
+	Statuses          []StatusEffect `json:"statuses"`
+	Latency           int64          `json:"latency"`
+	SubPosition       *Position      `json:"subPosition,omitempty"` // Fixed-point position, only with pixel movement
+}
+
+// ClientBomb is a bomb as clients see it.
+type ClientBomb struct {
+	Position   Position   `json:"position"`
+	OwnerID    string     `json:"ownerId"`
+	Timer      int        `json:"timer"`
+	FlameRange int        `json:"flameRange"`
+	Imminent   bool       `json:"imminent"`
+	BlastTiles []Position `json:"blastTiles"`
+	Airborne   int        `json:"airborne"` // Tiles left to fly after a punch, 0 on the ground
+}
+
+// ClientPowerUp is a revealed power-up lying on the map.
+type ClientPowerUp struct {
+	Position    Position    `json:"position"`
+	Type        PowerUpType `json:"type"`
+	Collectible bool        `json:"collectible"` // False during the short delay after it is uncovered
+}
+
+// ClientMap is the map without the power-ups still hidden in blocks.
+type ClientMap struct {
+	Width       int           `json:"width"`
+	Height      int           `json:"height"`
+	Walls       []Position    `json:"walls"`
+	Blocks      []ClientBlock `json:"blocks"`
+	SpawnPoints []Position    `json:"spawnPoints,omitempty"`
+}
+
+// ClientBlock is a destructible block. Destroyed blocks are kept so clients can animate them.
+type ClientBlock struct {
+	Position  Position `json:"position"`
+	Destroyed bool     `json:"destroyed"`
+}
+
+// MarshalJSON sends a game state in the client schema.
+func (gs *GameState) MarshalJSON() ([]byte, error) {
+	return json.Marshal(NewClientGameState(gs))
+}
+
+// NewClientGameState copies the client-visible parts of a game state.
+func NewClientGameState(gs *GameState) *ClientGameState {
+	c := &ClientGameState{
+		Tick:             gs.Tick,
+		Status:           gs.Status,
+		Players:          make([]ClientPlayer, 0, len(gs.Players)),
+		Map:              NewClientMap(gs.Map),
+		Bombs:            make([]ClientBomb, 0, len(gs.Bombs)),
+		Flames:           make([]Position, 0, len(gs.Flames)),
+		Hazards:          make([]Position, 0, len(gs.Hazards)),
+		PowerUps:         make([]ClientPowerUp, 0, len(gs.PowerUps)),
+		WinningTeam:      gs.WinningTeam,
+		Draw:             gs.Draw,
+		TimedOut:         gs.TimedOut,
+		TeamMode:         gs.Config.TeamMode,
+		FogOfWar:         gs.Config.FogOfWar,
+		VisionRadius:     gs.Config.VisionRadius,
+		BombTimer:        gs.Config.BombTimer,
+		PixelMovement:    gs.Config.PixelMovement,
+		EliminationOrder: gs.EliminationOrder,
+	}
+	if c.EliminationOrder == nil {
+		c.EliminationOrder = []Elimination{}
+	}
+
+	for _, p := range gs.Players {
+		player := newClientPlayer(p)
+		if gs.Config.PixelMovement {
+			sub := p.SubPosition
+			player.SubPosition = &sub
+		}
+		c.Players = append(c.Players, player)
+	}
+	c.Winner = NewClientPlayer(gs.Winner)
+	for _, b := range gs.Bombs {
+		c.Bombs = append(c.Bombs, ClientBomb{
+			Position:   b.Position,
+			OwnerID:    b.OwnerID,
+			Timer:      b.Timer,
+			FlameRange: b.FlameRange,
+			Imminent:   b.Imminent,
+			BlastTiles: b.BlastTiles,
+			Airborne:   b.Airborne,
+		})
+	}
+	for _, f := range gs.Flames {
+		c.Flames = append(c.Flames, f.Position)
+	}
+	for _, h := range gs.Hazards {
+		c.Hazards = append(c.Hazards, h.Position)
+	}
+	for _, pu := range gs.PowerUps {
+		c.PowerUps = append(c.PowerUps, ClientPowerUp{
+			Position:    pu.Position,
+			Type:        pu.Type,
+			Collectible: gs.Tick >= pu.RevealTick,
+		})
+	}
+	return c
+}
+
+// NewClientPlayer returns the client schema of a player, or nil for a nil player.
+func NewClientPlayer(p *Player) *ClientPlayer {
+	if p == nil {
+		return nil
+	}
+	player := newClientPlayer(p)
+	return &player
+}
+
+func newClientPlayer(p *Player) ClientPlayer {
+	statuses := p.Statuses
+	if statuses == nil {
+		statuses = []StatusEffect{}
+	}
+	return ClientPlayer{
+		ID:                p.ID,
+		Name:              p.Name,
+		Position:          p.Position,
+		Lives:             p.Lives,
+		Alive:             p.Alive,
+		Score:             p.Score,
+		Speed:             p.Speed,
+		BombCount:         p.BombCount,
+		FlameRange:        p.FlameRange,
+		Invincible:        p.Invincible,
+		Shielded:          p.Shielded,
+		TeamID:            p.TeamID,
+		IsBot:             p.IsBot,
+		BlocksDestroyed:   p.BlocksDestroyed,
+		PowerUpsCollected: p.PowerUpsCollected,
+		Statuses:          statuses,
+		Latency:           p.PlayerLatency,
+	}
+}
+
+// NewClientMap copies a map for clients, leaving out hidden power-ups. A nil map gives nil.
+func NewClientMap(m *Map) *ClientMap {
+	if m == nil {
+		return nil
+	}
+	c := &ClientMap{
+		Width:       m.Width,
+		Height:      m.Height,
+		Walls:       make([]Position, 0, len(m.Walls)),
+		Blocks:      make([]ClientBlock, 0, len(m.Blocks)),
+		SpawnPoints: m.SpawnPoints,
+	}
+	for _, w := range m.Walls {
+		c.Walls = append(c.Walls, w.Position)
+	}
+	for _, b := range m.Blocks {
+		c.Blocks = append(c.Blocks, ClientBlock{Position: b.Position, Destroyed: b.Destroyed})
+	}
+	return c
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// jsonKeys returns the sorted keys of a JSON object.
+func jsonKeys(t *testing.T, data json.RawMessage) string {
+	t.Helper()
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		t.Fatalf("not a JSON object: %s", data)
+	}
+	keys := make([]string, 0, len(object))
+	for k := range object {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, ",")
+}
+
+func TestGameStateClientSchema(t *testing.T) {
+	player := &Player{ID: "p1", Name: "Alice", Alive: true, Lives: 3, BombsPlaced: 1, BombCooldown: 4, PendingDirection: DirUp}
+	gs := &GameState{
+		Tick:   7,
+		Status: Finished,
+		Map: &Map{
+			Width: 3, Height: 3,
+			Walls:  []*Wall{{Position: Position{X: 0, Y: 0}}},
+			Blocks: []*Block{{Position: Position{X: 1, Y: 0}, HiddenPowerUp: &PowerUp{Type: FlameUp}}},
+		},
+		Players:  []*Player{player},
+		Bombs:    []*Bomb{{Position: Position{X: 1, Y: 1}, OwnerID: "p1", Timer: 30, Lava: true}},
+		Flames:   []*Flame{{Position: Position{X: 2, Y: 1}, Timer: 5, OwnerID: "p1"}},
+		Hazards:  []*Hazard{{Position: Position{X: 2, Y: 2}, Timer: 9}},
+		PowerUps: []*ActivePowerUp{{Position: Position{X: 1, Y: 2}, Type: SpeedUp, RevealTick: 3}},
+		Winner:   player,
+	}
+	data, err := json.Marshal(gs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var state struct {
+		Players  []json.RawMessage `json:"players"`
+		Map      json.RawMessage   `json:"map"`
+		Bombs    []json.RawMessage `json:"bombs"`
+		Flames   []json.RawMessage `json:"flames"`
+		PowerUps []json.RawMessage `json:"powerUps"`
+		Winner   json.RawMessage   `json:"winner"`
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	var blocks struct {
+		Blocks []json.RawMessage `json:"blocks"`
+	}
+	if err := json.Unmarshal(state.Map, &blocks); err != nil {
+		t.Fatal(err)
+	}
+	var end struct {
+		Winner json.RawMessage `json:"winner"`
+	}
+	endData, err := json.Marshal(&GameEndEvent{Winner: NewClientPlayer(player)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(endData, &end); err != nil {
+		t.Fatal(err)
+	}
+
+	playerKeys := "alive,blocksDestroyed,bombCount,flameRange,id,invincible,isBot,latency,lives,name," +
+		"position,powerUpsCollected,score,shielded,speed,statuses,teamId"
+	for _, c := range []struct {
+		what string
+		data json.RawMessage
+		want string
+	}{
+		{"game state", data, "bombTimer,bombs,draw,eliminationOrder,flames,fogOfWar,hazards,map,pixelMovement," +
+			"players,powerUps,status,teamMode,tick,timedOut,visionRadius,winner,winningTeam"},
+		{"player", state.Players[0], playerKeys},
+		{"winner", state.Winner, playerKeys},
+		{"game end winner", end.Winner, playerKeys},
+		{"map", state.Map, "blocks,height,walls,width"},
+		{"block", blocks.Blocks[0], "destroyed,position"},
+		{"bomb", state.Bombs[0], "airborne,blastTiles,flameRange,imminent,ownerId,position,timer"},
+		{"flame", state.Flames[0], "x,y"},
+		{"power-up", state.PowerUps[0], "collectible,position,type"},
+	} {
+		if got := jsonKeys(t, c.data); got != c.want {
+			t.Errorf("%s keys:\n got %s\nwant %s", c.what, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Direction is one of the four grid directions a player can move in.
+type Direction string
+
+const (
+	DirUp    Direction = "up"
+	DirDown  Direction = "down"
+	DirLeft  Direction = "left"
+	DirRight Direction = "right"
+)
+
+// Directions lists the valid directions in a fixed order.
+var Directions = []Direction{DirUp, DirDown, DirLeft, DirRight}
+
+// ParseDirection validates a direction received from a client. Movement is orthogonal only,
+// so anything naming two directions (e.g. "up-left") is rejected along with unknown values.
+func ParseDirection(s string) (Direction, error) {
+	for _, d := range Directions {
+		if s == string(d) {
+			return d, nil
+		}
+	}
+
+	named := 0
+	lower := strings.ToLower(s)
+	for _, d := range Directions {
+		if strings.Contains(lower, string(d)) {
+			named++
+		}
+	}
+	switch {
+	case s == "":
+		return "", fmt.Errorf("direction is required")
+	case named > 1:
+		return "", fmt.Errorf("directions can't be combined, got %q; send one of up, down, left, right", s)
+	}
+	return "", fmt.Errorf("invalid direction %q; send one of up, down, left, right", s)
+}
+
+// Delta returns the one-tile offset for the direction.
+func (d Direction) Delta() Position {
+	switch d {
+	case DirUp:
+		return Position{X: 0, Y: -1}
+	case DirDown:
+		return Position{X: 0, Y: 1}
+	case DirLeft:
+		return Position{X: -1, Y: 0}
+	case DirRight:
+		return Position{X: 1, Y: 0}
+	}
+	return Position{}
+}
+
+// Reverse returns the opposite direction.
+func (d Direction) Reverse() Direction {
+	switch d {
+	case DirUp:
+		return DirDown
+	case DirDown:
+		return DirUp
+	case DirLeft:
+		return DirRight
+	case DirRight:
+		return DirLeft
+	}
+	return d
+}
@@ -0,0 +1,61 @@
+package models
+
+// mapIndex gives O(1) lookups of walls and intact blocks by position.
+// Walls never change during a game; blocks leave the index when destroyed.
+// Players are not indexed: a game has at most four, so scanning them beats a map lookup,
+// and they move one after another within a tick, which would leave a per-tick index stale.
+type mapIndex struct {
+	walls  map[Position]bool
+	blocks map[Position]*Block
+}
+
+// Reindex rebuilds the position index from Walls and Blocks. Call it after editing
+// those slices directly; DestroyBlock keeps the index up to date on its own.
+func (m *Map) Reindex() {
+	index := &mapIndex{
+		walls:  make(map[Position]bool, len(m.Walls)),
+		blocks: make(map[Position]*Block, len(m.Blocks)),
+	}
+	for _, wall := range m.Walls {
+		index.walls[wall.Position] = true
+	}
+	for _, block := range m.Blocks {
+		if !block.Destroyed {
+			index.blocks[block.Position] = block
+		}
+	}
+	m.index = index
+}
+
+// ensureIndex builds the index on first use, so maps built or decoded anywhere work unchanged.
+func (m *Map) ensureIndex() {
+	if m.index == nil {
+		m.Reindex()
+	}
+}
+
+// WallAt reports whether an indestructible wall is at pos. A nil map has no walls.
+func (m *Map) WallAt(pos Position) bool {
+	if m == nil {
+		return false
+	}
+	m.ensureIndex()
+	return m.index.walls[pos]
+}
+
+// BlockAt returns the intact block at pos, or nil.
+func (m *Map) BlockAt(pos Position) *Block {
+	if m == nil {
+		return nil
+	}
+	m.ensureIndex()
+	return m.index.blocks[pos]
+}
+
+// DestroyBlock marks a block destroyed and drops it from the index.
+func (m *Map) DestroyBlock(block *Block) {
+	block.Destroyed = true
+	if m != nil && m.index != nil {
+		delete(m.index.blocks, block.Position)
+	}
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"github.com/gorilla/websocket"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -16,67 +17,229 @@ const (
 )
 
 type GameState struct {
-	Players   []*Player
-	Map       *Map
-	Bombs     []*Bomb
-	Flames    []*Flame
-	PowerUps  []*ActivePowerUp
-	Status    GameStatus
-	Winner    *Player // nil until game is Finished
-	Countdown int     // for game start countdown
+	Players   []*Player        `json:"players"`
+	Map       *Map             `json:"map"`
+	Bombs     []*Bomb          `json:"bombs"`
+	Flames    []*Flame         `json:"flames"`
+	Hazards   []*Hazard        `json:"hazards"` // Lava left behind by LavaBomb flames
+	PowerUps  []*ActivePowerUp `json:"powerUps"`
+	Status    GameStatus       `json:"status"`
+	Winner    *Player          `json:"winner"`    // nil until game is Finished
+	Countdown int              `json:"countdown"` // for game start countdown
+
+	Config GameConfig `json:"config"` // Rules of this game, fixed when it is created
+
+	WinningTeam    int  `json:"winningTeam"`    // TeamID of the winners in team mode, -1 until decided
+	Draw           bool `json:"draw"`           // True when the game finished with nobody (or no team) left standing
+	GraceTicksLeft int  `json:"graceTicksLeft"` // Remaining grace ticks, -1 while the win condition hasn't been met
+	TimedOut       bool `json:"timedOut"`       // The game was finished by MaxTicks rather than by eliminations
+
+	Tick             int           `json:"tick"`             // Number of ticks processed so far
+	EliminationOrder []Elimination `json:"eliminationOrder"` // Players in the order they were knocked out
+
+	DestroyedBlocks []DestroyedBlock `json:"-"` // Blocks destroyed during the last tick, announced by the lobby
+}
+
+// DestroyedBlock is a block that a flame destroyed, with the power-up it uncovered if any.
+type DestroyedBlock struct {
+	Position Position     `json:"position"`
+	PowerUp  *PowerUpType `json:"powerUp,omitempty"` // Revealed power-up, omitted when the block was empty
+}
+
+// BlocksDestroyedEvent lists every block destroyed on one tick, so clients can animate them.
+type BlocksDestroyedEvent struct {
+	Tick   int              `json:"tick"`
+	Blocks []DestroyedBlock `json:"blocks"`
+}
+
+// GameConfig holds the rules of one game. All game logic reads its tunables from
+// GameState.Config; the server's defaults come from DefaultGameConfig in the main package.
+type GameConfig struct {
+	MapWidth      int     `json:"mapWidth"`
+	MapHeight     int     `json:"mapHeight"`
+	MapSeed       int64   `json:"mapSeed"`       // Seed for the generated map, 0 for a fresh random one
+	SymmetricMap  bool    `json:"symmetricMap"`  // Mirror blocks so every spawn corner is balanced
+	BlockDensity  string  `json:"blockDensity"`  // "sparse", "normal" or "dense"; empty means normal
+	BlockPattern  string  `json:"blockPattern"`  // "random", "ring" or "cross"; empty means random
+	PowerUpChance float64 `json:"powerUpChance"` // Probability (0 to 1) that a block hides a power-up
+	StartingLives int     `json:"startingLives"`
+
+	// Stats every player starts with, for practice or custom modes
+	StartingBombs int `json:"startingBombs"`
+	StartingFlame int `json:"startingFlame"`
+	StartingSpeed int `json:"startingSpeed"`
+
+	BombTimer     int `json:"bombTimer"`     // Fuse length of new bombs, in ticks
+	FlameTime     int `json:"flameTime"`     // How long flames stay lit, in ticks
+	BombCooldown  int `json:"bombCooldown"`  // Minimum ticks between two bombs from the same player
+	GameOverGrace int `json:"gameOverGrace"` // Ticks to wait after the win condition is met before finalizing
+
+	TeamMode     bool `json:"teamMode"`           // Players on the same TeamID win together
+	FriendlyFire bool `json:"friendlyFire"`       // Whether flames hurt teammates in team mode
+	FogOfWar     bool `json:"fogOfWar"`           // Players only receive what is within VisionRadius of them
+	VisionRadius int  `json:"visionRadius"`       // Tiles a player sees in fog of war
+	PassThrough  bool `json:"passThroughPlayers"` // Players may walk through each other; bombs and walls still block
+
+	AFKTimeout int `json:"afkTimeout"` // Ticks without input after which a player surrenders, 0 to never
+	MaxTicks   int `json:"maxTicks"`   // Hard cap on the game's length in ticks, 0 for none
+
+	PixelMovement bool `json:"pixelMovement"` // Sub-tile movement with hitbox collisions instead of whole-tile steps
+}
+
+// Elimination records when a player was knocked out. Players eliminated on the same
+// tick share a Rank; the next rank skips accordingly (1, 2, 2, 4).
+type Elimination struct {
+	PlayerID string `json:"playerId"`
+	Tick     int    `json:"tick"`
+	Rank     int    `json:"rank"`
+	Reason   string `json:"reason,omitempty"` // Empty when knocked out by a flame, otherwise EliminationAFK or EliminationLeft
+
+	// Where the player fell, and for deaths by fire the owner of the bomb; a self-kill names the victim
+	KillerID string   `json:"killerId,omitempty"`
+	Position Position `json:"position"`
+}
+
+// EliminationAFK is the Elimination.Reason of a player who surrendered by not sending input.
+const EliminationAFK = "afk"
+
+// EliminationLeft is the Elimination.Reason of a player who disconnected from a running game.
+const EliminationLeft = "left"
+
+// SurrenderEvent tells the lobby a player was eliminated without being hit, and why.
+type SurrenderEvent struct {
+	PlayerID string `json:"playerId"`
+	Name     string `json:"name"`
+	Reason   string `json:"reason"`
+}
+
+// PlayerDiedEvent tells the lobby a player lost their last life to a flame, for death
+// animations and the kill feed. KillerID is the bomb owner, which is the victim on a self-kill,
+// and empty when nobody can be credited.
+type PlayerDiedEvent struct {
+	PlayerID string   `json:"playerId"`
+	KillerID string   `json:"killerId,omitempty"`
+	SelfKill bool     `json:"selfKill"`
+	Position Position `json:"position"`
+	Tick     int      `json:"tick"`
+}
+
+// YouDiedEvent tells a player they were eliminated. They stay in the game as a spectator:
+// state updates keep coming and their inputs are ignored.
+type YouDiedEvent struct {
+	Tick   int    `json:"tick"`
+	Rank   int    `json:"rank"`
+	Reason string `json:"reason,omitempty"`
 }
 
 type Map struct {
-	Width  int
-	Height int
-	Walls  []*Wall
-	Blocks []*Block
+	Width       int        `json:"width"`
+	Height      int        `json:"height"`
+	Walls       []*Wall    `json:"walls"`
+	Blocks      []*Block   `json:"blocks"`
+	SpawnPoints []Position `json:"spawnPoints"` // Custom spawns from a map file; empty means the four corners
+
+	index *mapIndex // Position lookup for walls and blocks, built on first use
 }
 
 type Block struct {
-	Position      Position
-	Destroyed     bool
-	HiddenPowerUp *PowerUp // nil if no power-up
+	Position      Position `json:"position"`
+	Destroyed     bool     `json:"destroyed"`
+	HiddenPowerUp *PowerUp `json:"hiddenPowerUp,omitempty"` // nil if no power-up
 }
 
 type Wall struct {
-	Position Position
+	Position Position `json:"position"`
 }
 
 type Player struct {
-	ID          string // Unique identifier for the player
-	Name        string
-	Lives       int
-	Position    Position
-	SpawnPoint  Position
-	BombsPlaced int
-	Alive       bool
-	Score       int
-	Speed       int
-	BombCount   int
-	FlameRange  int
-	Invincible  int
+	ID           string   `json:"id"` // Unique identifier for the player
+	Name         string   `json:"name"`
+	Lives        int      `json:"lives"`
+	Position     Position `json:"position"`
+	SpawnPoint   Position `json:"spawnPoint"`
+	BombsPlaced  int      `json:"-"` // Private, sent only to the owner via MSG_PLAYER_STATE
+	Alive        bool     `json:"alive"`
+	Score        int      `json:"score"`
+	Speed        int      `json:"speed"`
+	BombCount    int      `json:"bombCount"`
+	FlameRange   int      `json:"flameRange"`
+	Invincible   int      `json:"invincible"` // Ticks left without fire damage after a respawn; clients flash the player
+	Shielded     int      `json:"shielded"`   // Ticks left on a Shield power-up, which also keeps fire off the player
+	BombCooldown int      `json:"-"`          // Ticks until the player may place another bomb (private)
+	TeamID       int      `json:"teamId"`     // Team index in team mode, 0 otherwise
+	IsBot        bool     `json:"isBot"`      // Controlled by the server instead of a WebSocket client
+
+	BlocksDestroyed   int `json:"blocksDestroyed"`   // Blocks destroyed by this player's bombs, a ranking tiebreaker
+	PowerUpsCollected int `json:"powerUpsCollected"` // Power-ups picked up, the tiebreaker after BlocksDestroyed
+
+	Statuses []StatusEffect `json:"statuses"` // Timed effects currently on the player, e.g. curses
+	CanPunch bool           `json:"canPunch"` // Picked up PunchBomb; lasts for the rest of the game
+	HasLava  bool           `json:"hasLava"`  // Picked up LavaBomb; lasts for the rest of the game
+
+	// SubPosition is the fixed-point position in SubTile units, used with pixel movement
+	SubPosition Position `json:"subPosition"`
+
+	PendingDirection Direction `json:"-"` // Last move that was blocked, retried every tick
+	MoveDirection    Direction `json:"-"` // Direction held down for continuous movement, empty when standing still
+	MoveCooldown     int       `json:"-"` // Ticks until the player may move another tile, see MoveInterval
+	LastInputTick    int       `json:"-"` // Tick of the player's last input, for AFK detection
+
+	// PlayerLatency is the last measured round-trip time to the client, in milliseconds
+	PlayerLatency int64 `json:"latency"`
+}
+
+// Status effect types
+const (
+	StatusReverseControls = "reverse_controls"
+	StatusAutoBomb        = "auto_bomb"
+)
+
+// StatusEffect is a temporary effect on a player that wears off after TicksLeft ticks.
+type StatusEffect struct {
+	Type      string `json:"type"`
+	TicksLeft int    `json:"ticksLeft"`
 }
 
+// SubTile is the number of fixed-point units per tile in a Player.SubPosition.
+const SubTile = 16
+
 type Position struct {
-	X int
-	Y int
+	X int `json:"x"`
+	Y int `json:"y"`
 }
 
 type Bomb struct {
-	Position   Position
-	OwnerID    string
-	Timer      int
-	FlameRange int
+	Position   Position   `json:"position"`
+	OwnerID    string     `json:"ownerId"`
+	Timer      int        `json:"timer"`
+	FlameRange int        `json:"flameRange"`
+	Imminent   bool       `json:"imminent"`   // True once the timer drops below the pre-detonation threshold
+	BlastTiles []Position `json:"blastTiles"` // Tiles the explosion would cover given the current walls and blocks
+
+	Airborne  int       `json:"airborne"`            // Tiles left to fly after a punch, 0 on the ground
+	Direction Direction `json:"direction,omitempty"` // Flight direction of a punched bomb
+
+	Lava bool `json:"lava"` // Placed by a LavaBomb holder; its flames leave lava when they clear
 }
 
 type Flame struct {
-	Position Position
-	Timer    int
+	Position Position `json:"position"`
+	Timer    int      `json:"timer"`
+	Lava     bool     `json:"lava,omitempty"` // Turns into a Hazard when it expires
+	OwnerID  string   `json:"ownerId"`        // Owner of the bomb that made the flame, credited for kills
+	Tick     int      `json:"tick"`           // Tick the flame was lit on
+}
+
+// Hazard is a lava tile left where a LavaBomb flame cleared. Until Timer runs out it blocks
+// movement and burns anyone standing in it.
+type Hazard struct {
+	Position Position `json:"position"`
+	Timer    int      `json:"timer"`
+	OwnerID  string   `json:"ownerId"` // Owner of the LavaBomb that left it, credited for kills
 }
 
 type PowerUp struct {
-	Type PowerUpType
+	Type PowerUpType `json:"type"`
 }
 
 type PowerUpType int
@@ -86,11 +249,58 @@ const (
 	SpeedUp
 	FlameUp
 	BombUp
+	CurseReverse  // Reverses the player's controls for a while
+	CurseAutoBomb // Makes the player drop bombs nonstop for a while
+	Shield        // Temporary invincibility against flames
+	PunchBomb     // Lets the player punch adjacent bombs over obstacles
+	LavaBomb      // The player's explosions leave lava behind for a while
 )
 
+// PowerUpTypes lists every type that can appear on the map.
+var PowerUpTypes = []PowerUpType{SpeedUp, FlameUp, BombUp, CurseReverse, CurseAutoBomb, Shield, PunchBomb, LavaBomb}
+
+// IsCurse reports whether picking the power-up up harms the player.
+func (t PowerUpType) IsCurse() bool {
+	return t == CurseReverse || t == CurseAutoBomb
+}
+
+// String returns the name used for the power-up type in client-facing payloads.
+func (t PowerUpType) String() string {
+	switch t {
+	case SpeedUp:
+		return "speed_up"
+	case FlameUp:
+		return "flame_up"
+	case BombUp:
+		return "bomb_up"
+	case CurseReverse:
+		return "curse_reverse"
+	case CurseAutoBomb:
+		return "curse_auto_bomb"
+	case Shield:
+		return "shield"
+	case PunchBomb:
+		return "punch_bomb"
+	case LavaBomb:
+		return "lava_bomb"
+	}
+	return "none"
+}
+
+// ParsePowerUpType is the inverse of PowerUpType.String.
+func ParsePowerUpType(name string) (PowerUpType, bool) {
+	for _, t := range PowerUpTypes {
+		if t.String() == name {
+			return t, true
+		}
+	}
+	return None, false
+}
+
 type ActivePowerUp struct {
-	Position Position
-	Type     PowerUpType
+	Position   Position    `json:"position"`
+	Type       PowerUpType `json:"type"`
+	RevealTick int         `json:"revealTick"` // Tick from which the power-up can be collected
 }
 
 // Main WebSocket player struct - handles both connection and game data
@@ -99,11 +309,106 @@ type WebSocketPlayer struct {
 	WebSocketID  string          `json:"webSocketId"` // WebSocket-specific ID (different from game Player.ID)
 	ConnectionID string          `json:"connectionId"`
 	LobbyID      string          `json:"lobbyId"`
+	RemoteIP     string          `json:"-"` // Client address, for the per-IP connection limit
 	Conn         *websocket.Conn `json:"-"` // WebSocket connection
 	Send         chan []byte     `json:"-"` // Send channel
 	IsConnected  bool            `json:"isConnected"`
 	IsActive     bool            `json:"isActive"`
+	IsReady      bool            `json:"isReady"`
 	JoinedAt     time.Time       `json:"joinedAt"`
+	// State holds at most one unsent game_state_update. A newer update replaces it instead of
+	// queueing behind it, so a slow client skips stale states while Send stays reliable.
+	State chan []byte `json:"-"`
+
+	closeOnce  sync.Once                 // Guards close(Send)
+	evicting   atomic.Bool               // Set once an eviction has been requested
+	latency    atomic.Int64              // Last measured round trip in milliseconds, see SetLatency
+	lastActive atomic.Int64              // UnixNano of the last message received from the client, see Touch
+	handshake  atomic.Pointer[handshake] // Set once by the accepted hello, see SetHandshake
+}
+
+// handshake is what a client and the server agreed on in the hello.
+type handshake struct {
+	version  int
+	encoding string
+}
+
+// CloseSend closes the Send channel exactly once, no matter how many teardown paths reach it.
+func (p *WebSocketPlayer) CloseSend() {
+	p.closeOnce.Do(func() {
+		close(p.Send)
+	})
+}
+
+// OfferState puts a state update in the State slot, replacing any update still waiting there.
+// It reports false when the player has no State slot and the update must go through Send.
+// Only the game loop sends state updates, so the slot has a single producer.
+func (p *WebSocketPlayer) OfferState(data []byte) bool {
+	if p.State == nil {
+		return false
+	}
+	for {
+		select {
+		case p.State <- data:
+			return true
+		default:
+			select {
+			case <-p.State:
+			default:
+			}
+		}
+	}
+}
+
+// SetLatency records a measured round trip in milliseconds. The pong handler on the read
+// goroutine writes it while the game loop and lobby views read it, so it is stored atomically.
+func (p *WebSocketPlayer) SetLatency(ms int64) {
+	p.latency.Store(ms)
+}
+
+// Latency returns the last round trip recorded with SetLatency, in milliseconds.
+func (p *WebSocketPlayer) Latency() int64 {
+	return p.latency.Load()
+}
+
+// SetHandshake records the protocol version and encoding agreed in the hello. It reports false,
+// changing nothing, if a hello was already accepted, so the encoding can't switch mid-stream.
+// readPump writes it while the game loop and broadcasts encode for the player, so it is stored atomically.
+func (p *WebSocketPlayer) SetHandshake(version int, encoding string) bool {
+	return p.handshake.CompareAndSwap(nil, &handshake{version: version, encoding: encoding})
+}
+
+// ProtocolVersion returns the version agreed in the hello, or 0 if the client never sent one.
+func (p *WebSocketPlayer) ProtocolVersion() int {
+	if h := p.handshake.Load(); h != nil {
+		return h.version
+	}
+	return 0
+}
+
+// Encoding returns the encoding agreed in the hello: "gob" when the client asked for binary
+// state updates, otherwise JSON. It is empty if the client never sent a hello.
+func (p *WebSocketPlayer) Encoding() string {
+	if h := p.handshake.Load(); h != nil {
+		return h.encoding
+	}
+	return ""
+}
+
+// Touch records t as the time the client was last heard from. readPump writes it while
+// the idle sweeper reads it, so it is stored atomically as UnixNano.
+func (p *WebSocketPlayer) Touch(t time.Time) {
+	p.lastActive.Store(t.UnixNano())
+}
+
+// LastActive returns the time recorded by the last Touch.
+func (p *WebSocketPlayer) LastActive() time.Time {
+	return time.Unix(0, p.lastActive.Load())
+}
+
+// MarkEvicting reports whether this call is the first to request eviction of the player.
+func (p *WebSocketPlayer) MarkEvicting() bool {
+	return p.evicting.CompareAndSwap(false, true)
 }
 
 type ChatMessage struct {
@@ -112,16 +417,68 @@ type ChatMessage struct {
 	Nickname  string    `json:"nickname"`
 	Message   string    `json:"message"`
 	Timestamp time.Time `json:"timestamp"`
-	Type      string    `json:"type"` // "chat", "system", "join"
+	Type      string    `json:"type"`    // "chat", "system", "join"
+	Channel   string    `json:"channel"` // "lobby", or "global" for server-wide chat
 }
 
+// WebSocketMessage is the envelope for every message in both directions.
+// The payload always travels under the "data" key; there is no other field name.
 type WebSocketMessage struct {
 	Type string      `json:"type"`
-	Data interface{} `json:"data"` // ← Should be "data"
+	Data interface{} `json:"data"`
+}
+
+type KickPlayerRequest struct {
+	PlayerID string `json:"playerId"` // WebSocketID of the player to remove
+}
+
+type HelloRequest struct {
+	Version  int    `json:"version"`
+	Encoding string `json:"encoding,omitempty"` // "json" (default) or "gob" for binary state updates
+}
+
+// HelloResponse confirms the negotiated protocol version and the range the server accepts.
+type HelloResponse struct {
+	Version    int    `json:"version"`
+	MinVersion int    `json:"minVersion"`
+	MaxVersion int    `json:"maxVersion"`
+	Encoding   string `json:"encoding"` // Encoding the server will use for state updates
 }
 
 type ChatMessageRequest struct {
 	Message string `json:"message"`
+	Channel string `json:"channel,omitempty"` // "lobby" (the default) or "global"
+}
+
+// Emotes players can send during a match
+const (
+	EmoteGG   = "gg"
+	EmoteOops = "oops"
+	EmoteHelp = "help"
+	EmoteNice = "nice"
+)
+
+type EmoteRequest struct {
+	Emote string `json:"emote"`
+}
+
+// ResyncRequest asks for the full game state. LastSeq is the last update the client
+// applied; the sequence of a game state update is its tick.
+type ResyncRequest struct {
+	LastSeq int `json:"lastSeq"`
+}
+
+// ResyncResponse carries the authoritative game state and its sequence number.
+type ResyncResponse struct {
+	Seq   int        `json:"seq"`
+	State *GameState `json:"state"`
+}
+
+// EmoteEvent is broadcast for the client to show briefly above the sender.
+type EmoteEvent struct {
+	PlayerID string   `json:"playerId"`
+	Emote    string   `json:"emote"`
+	Position Position `json:"position"`
 }
 
 type Hub struct {
@@ -141,32 +498,211 @@ type ErrorResponse struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
 	Type    string `json:"type"`
+	Hint    string `json:"hint,omitempty"` // What the client can do about it, e.g. "wait_for_game_end"
 }
 
 type Lobby struct {
-	ID          string                      `json:"id"`
-	Name        string                      `json:"name"`
-	Players     map[string]*WebSocketPlayer `json:"players"`
-	MaxPlayers  int                         `json:"maxPlayers"`
-	MinPlayers  int                         `json:"minPlayers"`
-	GameStarted bool                        `json:"gameStarted"`
-	CreatedAt   time.Time                   `json:"createdAt"`
-	Messages    []ChatMessage               `json:"messages"`
-	WaitTimer   int                         `json:"waitTimer"`
-	StartTimer  int                         `json:"startTimer"`
-	Host        string                      `json:"host"`
-	Status      string                      `json:"status"` // "waiting", "starting", "playing"
-	Mutex       sync.RWMutex                `json:"-"`
+	ID               string                      `json:"id"`
+	Name             string                      `json:"name"`
+	Players          map[string]*WebSocketPlayer `json:"players"`
+	MaxPlayers       int                         `json:"maxPlayers"`
+	MinPlayers       int                         `json:"minPlayers"`
+	GameStarted      bool                        `json:"gameStarted"`
+	CreatedAt        time.Time                   `json:"createdAt"`
+	Messages         []ChatMessage               `json:"messages"`
+	WaitTimer        int                         `json:"waitTimer"`
+	StartTimer       int                         `json:"startTimer"`
+	Host             string                      `json:"host"`
+	Status           string                      `json:"status"` // "waiting", "starting", "playing"
+	Mode             string                      `json:"mode"`   // "classic", "team"
+	FriendlyFire     bool                        `json:"friendlyFire"`
+	SpawnArrangement string                      `json:"spawnArrangement"` // "corners", "team_adjacent"
+	SymmetricMap     bool                        `json:"symmetricMap"`     // Mirror blocks so all corners are balanced
+	BlockDensity     string                      `json:"blockDensity"`     // "sparse", "normal", "dense"
+	BlockPattern     string                      `json:"blockPattern"`     // "random", "ring", "cross"
+	MapWidth         int                         `json:"mapWidth"`
+	MapHeight        int                         `json:"mapHeight"`
+	MapSeed          int64                       `json:"mapSeed"`             // Reproduces a generated map; 0 picks a new one every game
+	CustomMap        string                      `json:"customMap,omitempty"` // Name of a map in MAPS_DIR, empty for procedural
+	FillWithBots     bool                        `json:"fillWithBots"`        // Fill empty slots with bots when the game starts
+	StartingLives    int                         `json:"startingLives"`
+	StartingBombs    int                         `json:"startingBombs"`
+	StartingFlame    int                         `json:"startingFlame"`
+	StartingSpeed    int                         `json:"startingSpeed"`
+	FogOfWar         bool                        `json:"fogOfWar"`
+	PassThrough      bool                        `json:"passThroughPlayers"`
+	PixelMovement    bool                        `json:"pixelMovement"`
+	BombTimer        int                         `json:"bombTimer"`       // Fuse length in ticks
+	FlameTime        int                         `json:"flameTime"`       // Flame duration in ticks
+	PowerUpDropRate  int                         `json:"powerUpDropRate"` // Percent of blocks hiding a power-up
+	AFKTimeout       int                         `json:"afkTimeout"`      // Idle ticks before a player surrenders, 0 to never
+	SeriesWins       int                         `json:"seriesWins"`      // Round wins needed to take the series; 1 plays single games
+	Round            int                         `json:"round"`           // Current round of the series, 0 outside a series
+	SeriesScore      map[string]int              `json:"seriesScore"`     // Round wins by player ID in the current series
+	Mutex            sync.RWMutex                `json:"-"`
 }
 
 type LobbyUpdate struct {
-	Lobby       *Lobby `json:"lobby"`
-	PlayerCount int    `json:"playerCount"`
-	TimeLeft    int    `json:"timeLeft,omitempty"`
-	Status      string `json:"status"` // "waiting", "starting", "playing"
+	Lobby       *LobbyView `json:"lobby"`
+	PlayerCount int        `json:"playerCount"`
+	TimeLeft    int        `json:"timeLeft,omitempty"`
+	Status      string     `json:"status"` // "waiting", "starting", "playing"
+}
+
+// LobbyView is the client-facing copy of a Lobby: no connections, channels or mutex,
+// and players as a list in join order instead of the internal map.
+type LobbyView struct {
+	ID               string            `json:"id"`
+	Name             string            `json:"name"`
+	Players          []LobbyPlayerView `json:"players"`
+	MaxPlayers       int               `json:"maxPlayers"`
+	MinPlayers       int               `json:"minPlayers"`
+	GameStarted      bool              `json:"gameStarted"`
+	Messages         []ChatMessage     `json:"messages"`
+	WaitTimer        int               `json:"waitTimer"`
+	StartTimer       int               `json:"startTimer"`
+	Host             string            `json:"host"`
+	Status           string            `json:"status"`
+	Mode             string            `json:"mode"`
+	FriendlyFire     bool              `json:"friendlyFire"`
+	SpawnArrangement string            `json:"spawnArrangement"`
+	SymmetricMap     bool              `json:"symmetricMap"`
+	BlockDensity     string            `json:"blockDensity"`
+	BlockPattern     string            `json:"blockPattern"`
+	MapWidth         int               `json:"mapWidth"`
+	MapHeight        int               `json:"mapHeight"`
+	MapSeed          int64             `json:"mapSeed"`
+	CustomMap        string            `json:"customMap,omitempty"`
+	FillWithBots     bool              `json:"fillWithBots"`
+	StartingLives    int               `json:"startingLives"`
+	StartingBombs    int               `json:"startingBombs"`
+	StartingFlame    int               `json:"startingFlame"`
+	StartingSpeed    int               `json:"startingSpeed"`
+	FogOfWar         bool              `json:"fogOfWar"`
+	PassThrough      bool              `json:"passThroughPlayers"`
+	PixelMovement    bool              `json:"pixelMovement"`
+	BombTimer        int               `json:"bombTimer"`
+	FlameTime        int               `json:"flameTime"`
+	PowerUpDropRate  int               `json:"powerUpDropRate"`
+	AFKTimeout       int               `json:"afkTimeout"`
+	SeriesWins       int               `json:"seriesWins"`
+	Round            int               `json:"round"`
+	SeriesScore      map[string]int    `json:"seriesScore"`
+}
+
+// LobbyPlayerView is what other clients see of a lobby member.
+type LobbyPlayerView struct {
+	WebSocketID string `json:"webSocketId"`
+	Name        string `json:"name"`
+	Lives       int    `json:"lives"`
+	IsReady     bool   `json:"isReady"`
+	IsConnected bool   `json:"isConnected"`
+	Latency     int64  `json:"latency"`
+}
+
+// LobbySummary is the public view of the lobby served over HTTP at /lobby.
+type LobbySummary struct {
+	ID           string `json:"id"`
+	Name         string `json:"name"`
+	PlayerCount  int    `json:"playerCount"`
+	MaxPlayers   int    `json:"maxPlayers"`
+	Status       string `json:"status"`
+	Host         string `json:"host"`
+	HostNickname string `json:"hostNickname,omitempty"`
+	Mode         string `json:"mode"`
+}
+
+// SettingRange describes the inclusive bounds and default of a numeric lobby setting.
+type SettingRange struct {
+	Min     int `json:"min"`
+	Max     int `json:"max"`
+	Default int `json:"default"`
+}
+
+type PowerUpInfo struct {
+	ID   PowerUpType `json:"id"`
+	Name string      `json:"name"`
+}
+
+// Capabilities lists the game modes, setting ranges and power-ups the server supports.
+type Capabilities struct {
+	Modes             []string                `json:"modes"`
+	SpawnArrangements []string                `json:"spawnArrangements"`
+	BlockDensities    []string                `json:"blockDensities"`
+	BlockPatterns     []string                `json:"blockPatterns"`
+	Settings          map[string]SettingRange `json:"settings"`
+	PowerUps          []PowerUpInfo           `json:"powerUps"`
+}
+
+// TimerUpdate is sent every second while the lobby counts down.
+// Phase is "waiting" (waiting for more players) or "starting" (game about to begin).
+type TimerUpdate struct {
+	Phase       string `json:"phase"`
+	SecondsLeft int    `json:"secondsLeft"`
+}
+
+// MapPreview is the map the upcoming game will be played on, sent when the start countdown begins.
+// SpawnPoints are the spawn candidates; which player gets which is decided at game start.
+type MapPreview struct {
+	Map         *ClientMap `json:"map"`
+	SpawnPoints []Position `json:"spawnPoints"`
+}
+
+// PlayerState is the private part of a player's state, sent only to that player.
+type PlayerState struct {
+	PlayerID       string   `json:"playerId"`
+	BombCount      int      `json:"bombCount"`
+	BombsPlaced    int      `json:"bombsPlaced"`
+	BombsRemaining int      `json:"bombsRemaining"`
+	BombCooldown   int      `json:"bombCooldown"`
+	FlameRange     int      `json:"flameRange"`
+	Speed          int      `json:"speed"`
+	Invincible     int      `json:"invincible"`
+	Shielded       int      `json:"shielded"`
+	Abilities      []string `json:"abilities"` // Special abilities held, e.g. "punch_bomb"
+}
+
+// PlayerStats answers MSG_GET_MY_STATS with the requesting player's stats as of Tick.
+type PlayerStats struct {
+	PlayerState
+	Tick     int            `json:"tick"`
+	Lives    int            `json:"lives"`
+	Alive    bool           `json:"alive"`
+	Score    int            `json:"score"`
+	Statuses []StatusEffect `json:"statuses"`
 }
 
 // Request structs
+
+// LobbySettingsRequest is sent by the host to change lobby options. Nil fields are left unchanged.
+type LobbySettingsRequest struct {
+	Mode             *string `json:"mode,omitempty"`
+	FriendlyFire     *bool   `json:"friendlyFire,omitempty"`
+	SpawnArrangement *string `json:"spawnArrangement,omitempty"`
+	SymmetricMap     *bool   `json:"symmetricMap,omitempty"`
+	BlockDensity     *string `json:"blockDensity,omitempty"`
+	BlockPattern     *string `json:"blockPattern,omitempty"`
+	MapWidth         *int    `json:"mapWidth,omitempty"`
+	MapHeight        *int    `json:"mapHeight,omitempty"`
+	MapSeed          *int64  `json:"mapSeed,omitempty"`
+	CustomMap        *string `json:"customMap,omitempty"`
+	FillWithBots     *bool   `json:"fillWithBots,omitempty"`
+	StartingLives    *int    `json:"startingLives,omitempty"`
+	StartingBombs    *int    `json:"startingBombs,omitempty"`
+	StartingFlame    *int    `json:"startingFlame,omitempty"`
+	StartingSpeed    *int    `json:"startingSpeed,omitempty"`
+	FogOfWar         *bool   `json:"fogOfWar,omitempty"`
+	PassThrough      *bool   `json:"passThroughPlayers,omitempty"`
+	PixelMovement    *bool   `json:"pixelMovement,omitempty"`
+	BombTimer        *int    `json:"bombTimer,omitempty"`
+	FlameTime        *int    `json:"flameTime,omitempty"`
+	PowerUpDropRate  *int    `json:"powerUpDropRate,omitempty"`
+	AFKTimeout       *int    `json:"afkTimeout,omitempty"`
+	SeriesWins       *int    `json:"seriesWins,omitempty"`
+	WaitTimer        *int    `json:"waitTimer,omitempty"`
+	StartTimer       *int    `json:"startTimer,omitempty"`
+}
+
 type JoinLobbyRequest struct {
 	Nickname string `json:"nickname"`
 	LobbyID  string `json:"lobbyId,omitempty"`
@@ -180,6 +716,48 @@ type PlayerJoinedEvent struct {
 	Message     string           `json:"message"`
 }
 
+// GameEndEvent is broadcast once when a game finishes. Draw is set instead of a winner
+// when the last players (or teams) were eliminated on the same tick.
+// TimedOut means the game hit its length cap and was decided on the standings at that moment.
+type GameEndEvent struct {
+	Winner           *ClientPlayer `json:"winner"` // nil on a draw
+	WinningTeam      int           `json:"winningTeam"`
+	Draw             bool          `json:"draw"`
+	TimedOut         bool          `json:"timedOut"`
+	EliminationOrder []Elimination `json:"eliminationOrder"`
+	Standings        []Standing    `json:"standings"`
+}
+
+// SeriesUpdate is broadcast after each round of a best-of series.
+type SeriesUpdate struct {
+	Round      int              `json:"round"`
+	WinsNeeded int              `json:"winsNeeded"`
+	Standings  []SeriesStanding `json:"standings"`
+	Finished   bool             `json:"finished"` // Someone reached WinsNeeded; the lobby goes back to waiting
+}
+
+// SeriesStanding is a player's round-win tally in the current series.
+type SeriesStanding struct {
+	PlayerID string `json:"playerId"`
+	Name     string `json:"name"`
+	Wins     int    `json:"wins"`
+	Winner   bool   `json:"winner"`
+}
+
+// Standing is a player's final place, 1 being the best. Players tied on every criterion share a place.
+type Standing struct {
+	PlayerID          string `json:"playerId"`
+	Place             int    `json:"place"`
+	Score             int    `json:"score"`
+	BlocksDestroyed   int    `json:"blocksDestroyed"`
+	PowerUpsCollected int    `json:"powerUpsCollected"`
+}
+
+type HostChangedEvent struct {
+	HostID   string `json:"hostId"`
+	Nickname string `json:"nickname"`
+}
+
 type PlayerLeftEvent struct {
 	PlayerID    string `json:"playerId"`
 	Nickname    string `json:"nickname"`
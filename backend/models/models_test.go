@@ -0,0 +1,41 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPlayerJSONKeys(t *testing.T) {
+	player := &Player{
+		ID:          "p1",
+		Name:        "Alice",
+		Position:    Position{X: 1, Y: 1},
+		BombsPlaced: 1,
+		Statuses:    []StatusEffect{{Type: StatusReverseControls, TicksLeft: 10}},
+	}
+	data, err := json.Marshal(player)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every key is lowerCamel; private counters and input bookkeeping stay out
+	want := "alive,blocksDestroyed,bombCount,canPunch,flameRange,hasLava,id,invincible,isBot,latency,lives," +
+		"name,position,powerUpsCollected,score,shielded,spawnPoint,speed,statuses,subPosition,teamId"
+	if got := jsonKeys(t, data); got != want {
+		t.Errorf("player keys:\n got %s\nwant %s", got, want)
+	}
+
+	var nested struct {
+		Position json.RawMessage   `json:"position"`
+		Statuses []json.RawMessage `json:"statuses"`
+	}
+	if err := json.Unmarshal(data, &nested); err != nil {
+		t.Fatal(err)
+	}
+	if got := jsonKeys(t, nested.Position); got != "x,y" {
+		t.Errorf("position keys %s, want x,y", got)
+	}
+	if got := jsonKeys(t, nested.Statuses[0]); got != "ticksLeft,type" {
+		t.Errorf("status keys %s, want ticksLeft,type", got)
+	}
+}
@@ -1,26 +1,63 @@
 package models
 
+// Protocol versions the server speaks. Bump ProtocolVersion on any change to message
+// payloads, and raise MinProtocolVersion once old clients can no longer be served.
+//
+// Version 1 is the protocol from before the hello handshake. Its clients wait for game_update,
+// which the server no longer sends, so they cannot be served. A client must send a hello
+// before it can join the lobby, so one that never sends one is refused rather than served
+// as some assumed version.
+const (
+	ProtocolVersion    = 2
+	MinProtocolVersion = 2
+)
+
 const (
 	// Lobby related messages
 	MSG_JOIN_LOBBY = "join_lobby"
 
-	MSG_LOBBY_UPDATE  = "lobby_update"
-	MSG_PLAYER_JOINED = "player_joined"
-	MSG_PLAYER_LEFT   = "player_left"
-	MSG_LOBBY_STATUS  = "lobby_status"
+	MSG_LOBBY_UPDATE   = "lobby_update"
+	MSG_PLAYER_JOINED  = "player_joined"
+	MSG_PLAYER_LEFT    = "player_left"
+	MSG_HOST_CHANGED   = "host_changed"
+	MSG_LOBBY_STATUS   = "lobby_status"
+	MSG_PLAYER_READY   = "player_ready"   // Toggle the sender's ready state
+	MSG_START_GAME     = "start_game"     // Host asks to start once everyone is ready
+	MSG_LOBBY_SETTINGS = "lobby_settings" // Host changes mode / team options
+	MSG_TIMER_UPDATE   = "timer_update"   // Wait / start countdown ticks
+	MSG_KICK_PLAYER    = "kick_player"    // Host removes a player before the game starts
+
+	// Protocol version handshake, sent by the client right after connecting
+	MSG_HELLO = "hello"
+
+	// Server capability discovery
+	MSG_GET_CAPABILITIES = "get_capabilities"
 
 	// Chat related messages
 	MSG_CHAT_MESSAGE = "chat_message"
 
 	// Game related messages
-	MSG_GAME_START        = "game_start"
-	MSG_GAME_STATE_UPDATE = "game_state_update" // Full game state updates
-	MSG_GAME_UPDATE       = "game_update"       // Individual game events (movement, bombs)
-	MSG_GAME_END          = "game_end"
+	MSG_MAP_PREVIEW        = "map_preview" // Map of the upcoming game, sent when the start countdown begins
+	MSG_GAME_START         = "game_start"
+	MSG_GAME_STATE_UPDATE  = "game_state_update" // Full game state updates, the only type the server sends for the running game
+	MSG_GAME_END           = "game_end"
+	MSG_SERIES_UPDATE      = "series_update"      // Round wins after each round of a best-of series
+	MSG_BLOCKS_DESTROYED   = "blocks_destroyed"   // Blocks destroyed on a tick, for destruction animations
+	MSG_PLAYER_STATE       = "player_state"       // Private per-player state, sent only to that player
+	MSG_EMOTE              = "emote"              // Quick-chat shown over a player during a match, not kept in chat history
+	MSG_PLAYER_SURRENDERED = "player_surrendered" // A player was eliminated for being idle too long
+	MSG_RESYNC             = "resync"             // Client asks for the full game state after missing updates; the reply has the same type
+	MSG_YOU_DIED           = "you_died"           // Sent only to an eliminated player, who keeps receiving updates as a spectator
+	MSG_PLAYER_DIED        = "player_died"        // Broadcast when a player loses their last life to a flame, with who killed them
+	MSG_GET_MY_STATS       = "get_my_stats"       // Client asks for its own live stats, answered with MSG_MY_STATS
+	MSG_MY_STATS           = "my_stats"
 
 	// Player action messages
 	MSG_PLAYER_MOVE = "player_move"
+	MSG_MOVE_START  = "move_start" // Keep moving in a direction until move_stop or a collision
+	MSG_MOVE_STOP   = "move_stop"
 	MSG_PLACE_BOMB  = "place_bomb"
+	MSG_PUNCH_BOMB  = "punch_bomb" // Punch the adjacent bomb in a direction; needs the PunchBomb power-up
 
 	// System messages
 	MSG_ERROR   = "error"
@@ -28,3 +65,7 @@ const (
 	MSG_PING    = "ping"
 	MSG_PONG    = "pong"
 )
+
+// Deprecated: MSG_GAME_UPDATE is the old name of the game update message. The server no longer
+// sends it; clients should treat it like MSG_GAME_STATE_UPDATE until it is removed next release.
+const MSG_GAME_UPDATE = "game_update"
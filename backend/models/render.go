@@ -0,0 +1,104 @@
+package models
+
+import "strings"
+
+// Characters used by the ASCII renderer.
+const (
+	RenderFloor = '.'
+	RenderWall  = '#'
+	RenderBlock = '+'
+	RenderBomb  = 'o'
+	RenderFlame = '*'
+)
+
+// powerUpRune returns the character a power-up is drawn with.
+func powerUpRune(t PowerUpType) rune {
+	switch t {
+	case SpeedUp:
+		return 's'
+	case FlameUp:
+		return 'f'
+	case BombUp:
+		return 'b'
+	case CurseReverse, CurseAutoBomb:
+		return 'c'
+	case Shield:
+		return 'v'
+	}
+	return '?'
+}
+
+// newGrid returns a Height x Width grid filled with floor tiles.
+func newGrid(width, height int) [][]rune {
+	grid := make([][]rune, height)
+	for y := range grid {
+		grid[y] = []rune(strings.Repeat(string(RenderFloor), width))
+	}
+	return grid
+}
+
+func setCell(grid [][]rune, pos Position, r rune) {
+	if pos.Y < 0 || pos.Y >= len(grid) || pos.X < 0 || pos.X >= len(grid[pos.Y]) {
+		return
+	}
+	grid[pos.Y][pos.X] = r
+}
+
+func joinGrid(grid [][]rune) string {
+	var sb strings.Builder
+	for _, row := range grid {
+		sb.WriteString(string(row))
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// drawMap draws walls and intact blocks onto the grid.
+func (m *Map) drawMap(grid [][]rune) {
+	for _, wall := range m.Walls {
+		setCell(grid, wall.Position, RenderWall)
+	}
+	for _, block := range m.Blocks {
+		if !block.Destroyed {
+			setCell(grid, block.Position, RenderBlock)
+		}
+	}
+}
+
+// String renders the map as an ASCII grid, one line per row:
+// '#' wall, '+' block, '.' floor.
+func (m *Map) String() string {
+	if m == nil {
+		return ""
+	}
+	grid := newGrid(m.Width, m.Height)
+	m.drawMap(grid)
+	return joinGrid(grid)
+}
+
+// Render draws the whole game as an ASCII grid. On top of the map it shows power-ups
+// ('s' speed, 'f' flame, 'b' bomb), flames '*', bombs 'o' and living players as
+// their 1-based index in Players. Later layers win when entities share a tile.
+func (gs *GameState) Render() string {
+	if gs == nil || gs.Map == nil {
+		return ""
+	}
+	grid := newGrid(gs.Map.Width, gs.Map.Height)
+	gs.Map.drawMap(grid)
+
+	for _, powerUp := range gs.PowerUps {
+		setCell(grid, powerUp.Position, powerUpRune(powerUp.Type))
+	}
+	for _, flame := range gs.Flames {
+		setCell(grid, flame.Position, RenderFlame)
+	}
+	for _, bomb := range gs.Bombs {
+		setCell(grid, bomb.Position, RenderBomb)
+	}
+	for i, player := range gs.Players {
+		if player.Alive && i < 9 {
+			setCell(grid, player.Position, rune('1'+i))
+		}
+	}
+	return joinGrid(grid)
+}
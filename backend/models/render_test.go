@@ -0,0 +1,48 @@
+package models
+
+import "testing"
+
+func TestGameStateRender(t *testing.T) {
+	m := &Map{Width: 5, Height: 5}
+	for y := 0; y < 5; y++ {
+		for x := 0; x < 5; x++ {
+			if x == 0 || y == 0 || x == 4 || y == 4 || x == 2 && y == 2 {
+				m.Walls = append(m.Walls, &Wall{Position: Position{X: x, Y: y}})
+			}
+		}
+	}
+	m.Blocks = []*Block{
+		{Position: Position{X: 3, Y: 1}},
+		{Position: Position{X: 1, Y: 3}, Destroyed: true},
+	}
+	gs := &GameState{
+		Map: m,
+		Players: []*Player{
+			{Position: Position{X: 1, Y: 1}, Alive: true},
+			{Position: Position{X: 3, Y: 3}, Alive: false},
+		},
+		Bombs:    []*Bomb{{Position: Position{X: 1, Y: 2}}},
+		Flames:   []*Flame{{Position: Position{X: 3, Y: 2}}, {Position: Position{X: 3, Y: 3}}},
+		PowerUps: []*ActivePowerUp{{Position: Position{X: 2, Y: 3}, Type: FlameUp}},
+	}
+
+	want := "" +
+		"#####\n" +
+		"#1.+#\n" +
+		"#o#*#\n" +
+		"#.f*#\n" +
+		"#####\n"
+	if got := gs.Render(); got != want {
+		t.Errorf("Render() =\n%s\nwant\n%s", got, want)
+	}
+
+	wantMap := "" +
+		"#####\n" +
+		"#..+#\n" +
+		"#.#.#\n" +
+		"#...#\n" +
+		"#####\n"
+	if got := m.String(); got != wantMap {
+		t.Errorf("Map.String() =\n%s\nwant\n%s", got, wantMap)
+	}
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// MaxMOTDLength caps the message of the day, in bytes.
+const MaxMOTDLength = 500
+
+// MOTD is the server's message of the day, sent to every player when they connect.
+// It starts from the MOTD env variable; MOTD_AS_CHAT=true also shows it as a system chat line.
+type MOTD struct {
+	text   string
+	asChat bool
+	mu     sync.RWMutex
+}
+
+func NewMOTD(text string, asChat bool) *MOTD {
+	return &MOTD{text: text, asChat: asChat}
+}
+
+// motdFromEnv reads the startup MOTD from MOTD and MOTD_AS_CHAT.
+func motdFromEnv() *MOTD {
+	return NewMOTD(os.Getenv("MOTD"), os.Getenv("MOTD_AS_CHAT") == "true")
+}
+
+// Get returns the current message and whether it is also sent as a chat line.
+func (m *MOTD) Get() (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.text, m.asChat
+}
+
+// Set replaces the message. An empty text turns the MOTD off.
+func (m *MOTD) Set(text string, asChat bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.text = text
+	m.asChat = asChat
+}
+
+// ServeAdminMOTD answers GET /admin/motd with the current MOTD and replaces it on POST with
+// {"motd": "...", "asChat": true}. Requests need "Authorization: Bearer <ADMIN_TOKEN>";
+// with ADMIN_TOKEN unset the endpoint is disabled.
+func (lh *LobbyHandler) ServeAdminMOTD(w http.ResponseWriter, r *http.Request) {
+	if !isAdmin(r) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var update struct {
+			MOTD   string `json:"motd"`
+			AsChat bool   `json:"asChat"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 4096)).Decode(&update); err != nil {
+			http.Error(w, "invalid MOTD data", http.StatusBadRequest)
+			return
+		}
+		update.MOTD = strings.TrimSpace(update.MOTD)
+		if len(update.MOTD) > MaxMOTDLength {
+			http.Error(w, "MOTD is too long", http.StatusBadRequest)
+			return
+		}
+		lh.motd.Set(update.MOTD, update.AsChat)
+		lh.logger.Infof("MOTD updated by admin")
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	text, asChat := lh.motd.Get()
+	writeJSON(w, map[string]interface{}{"motd": text, "asChat": asChat})
+}
+
+// isAdmin checks the request's bearer token against ADMIN_TOKEN.
+func isAdmin(r *http.Request) bool {
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		return false
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
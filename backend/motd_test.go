@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// welcomeMOTD returns the motd field of a player's welcome message.
+func welcomeMOTD(t *testing.T, messages []testMessage) string {
+	t.Helper()
+	if len(messages) == 0 || messages[0].Type != models.MSG_SUCCESS {
+		t.Fatalf("first message %v, want the welcome", messages)
+	}
+	var welcome struct {
+		MOTD string `json:"motd"`
+	}
+	if err := json.Unmarshal(messages[0].Data, &welcome); err != nil {
+		t.Fatal(err)
+	}
+	return welcome.MOTD
+}
+
+func TestMOTDInWelcome(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	lh.motd = NewMOTD("Tournament tonight at 8", true)
+
+	messages := drainMessages(t, addTestPlayer(lh, "p1", false))
+	if got := welcomeMOTD(t, messages); got != "Tournament tonight at 8" {
+		t.Errorf("welcome motd %q, want the configured one", got)
+	}
+	if chat := messagesOfType(messages, models.MSG_CHAT_MESSAGE); len(chat) != 1 || !strings.Contains(string(chat[0].Data), "Tournament tonight") {
+		t.Errorf("got chat lines %v, want the MOTD as one system line", chat)
+	}
+	if len(lh.lobby.Messages) != 0 {
+		t.Errorf("MOTD entered the chat history: %v", lh.lobby.Messages)
+	}
+
+	// An admin update reaches the next player to connect
+	t.Setenv("ADMIN_TOKEN", "secret")
+	req := httptest.NewRequest(http.MethodPost, "/admin/motd", strings.NewReader(`{"motd": "Server restart at midnight"}`))
+	req.Header.Set("Authorization", "Bearer secret")
+	rec := httptest.NewRecorder()
+	NewServeMux(lh).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("MOTD update: status %d, want 200", rec.Code)
+	}
+	messages = drainMessages(t, addTestPlayer(lh, "p2", false))
+	if got := welcomeMOTD(t, messages); got != "Server restart at midnight" {
+		t.Errorf("welcome motd %q after the update, want the new one", got)
+	}
+	if chat := messagesOfType(messages, models.MSG_CHAT_MESSAGE); len(chat) != 0 {
+		t.Errorf("MOTD sent as chat after asChat was turned off: %v", chat)
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import "bomberman-dom/models"
+
+// Pixel movement is the optional finer movement model, turned on by GameConfig.PixelMovement.
+// Players then carry a fixed-point SubPosition in models.SubTile units per tile and move a few
+// units per tick while holding a direction. Collisions use a square hitbox centred on
+// SubPosition instead of whole tiles. Player.Position stays the tile under the hitbox centre,
+// so bombs, flames and power-ups keep working per tile.
+const (
+	PixelHitbox    = 12 // Side of the player hitbox in SubTile units, a little smaller than a tile
+	PixelBaseSpeed = 2  // SubTile units moved per tick at Speed 0, about the pace of continuous tile moves
+	PixelSpeedStep = 1  // Extra units per tick for each Speed level
+	PixelCornerPad = 5  // How far off-centre a player can be and still be nudged around a corner
+)
+
+// tileCenter returns the fixed-point centre of a tile.
+func tileCenter(tile models.Position) models.Position {
+	return models.Position{
+		X: tile.X*models.SubTile + models.SubTile/2,
+		Y: tile.Y*models.SubTile + models.SubTile/2,
+	}
+}
+
+// tileAt returns the tile containing a fixed-point point.
+func tileAt(sub models.Position) models.Position {
+	return models.Position{X: floorDiv(sub.X, models.SubTile), Y: floorDiv(sub.Y, models.SubTile)}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && a < 0 {
+		q--
+	}
+	return q
+}
+
+// syncSubPosition recentres a player's SubPosition on their tile if something outside pixel
+// movement (a spawn, a respawn, a whole-tile move) put them on another tile.
+func syncSubPosition(player *models.Player) {
+	if tileAt(player.SubPosition) != player.Position {
+		player.SubPosition = tileCenter(player.Position)
+	}
+}
+
+// hitboxTiles returns the range of tiles a hitbox centred on sub overlaps, inclusive.
+func hitboxTiles(sub models.Position) (minTile, maxTile models.Position) {
+	half := PixelHitbox / 2
+	minTile = tileAt(models.Position{X: sub.X - half, Y: sub.Y - half})
+	maxTile = tileAt(models.Position{X: sub.X + half - 1, Y: sub.Y + half - 1})
+	return minTile, maxTile
+}
+
+// hitboxesOverlap reports whether two hitboxes centred on a and b intersect.
+func hitboxesOverlap(a, b models.Position) bool {
+	dx, dy := a.X-b.X, a.Y-b.Y
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return dx < PixelHitbox && dy < PixelHitbox
+}
+
+// isSubPositionValid checks a hitbox centred on sub against the map, bombs and other players.
+// A bomb only blocks a player whose hitbox is clear of it, so players can walk off the bomb
+// they just placed but not back onto it.
+func isSubPositionValid(sub models.Position, player *models.Player, gs *models.GameState) bool {
+	minTile, maxTile := hitboxTiles(sub)
+	if minTile.X < 0 || minTile.Y < 0 || maxTile.X >= gs.Map.Width || maxTile.Y >= gs.Map.Height {
+		return false
+	}
+
+	curMin, curMax := hitboxTiles(player.SubPosition)
+	for y := minTile.Y; y <= maxTile.Y; y++ {
+		for x := minTile.X; x <= maxTile.X; x++ {
+			tile := models.Position{X: x, Y: y}
+			if gs.Map.WallAt(tile) || gs.Map.BlockAt(tile) != nil || hazardAt(gs, tile) {
+				return false
+			}
+			for _, bomb := range gs.Bombs {
+				inside := x >= curMin.X && x <= curMax.X && y >= curMin.Y && y <= curMax.Y
+				if bomb.Position == tile && bomb.Airborne == 0 && !inside {
+					return false
+				}
+			}
+		}
+	}
+
+	if gs.Config.PassThrough {
+		return true
+	}
+	for _, other := range gs.Players {
+		if other.ID == player.ID || !other.Alive {
+			continue
+		}
+		// Players already overlapping may separate but not push further in
+		if hitboxesOverlap(sub, other.SubPosition) && !hitboxesOverlap(player.SubPosition, other.SubPosition) {
+			return false
+		}
+	}
+	return true
+}
+
+// stepPixels moves a player up to 1 + Speed steps of PixelBaseSpeed units in a direction, one
+// unit at a time so nothing is tunnelled through. A player slightly off the lane is nudged
+// towards the centre of their tile to round corners. It reports whether the player moved.
+func stepPixels(player *models.Player, direction models.Direction, gs *models.GameState) bool {
+	if HasStatus(player, models.StatusReverseControls) {
+		direction = direction.Reverse()
+	}
+	delta := direction.Delta()
+	if delta == (models.Position{}) {
+		return false
+	}
+	syncSubPosition(player)
+
+	moved := false
+	for i := 0; i < PixelBaseSpeed+player.Speed*PixelSpeedStep; i++ {
+		next := models.Position{X: player.SubPosition.X + delta.X, Y: player.SubPosition.Y + delta.Y}
+		if !isSubPositionValid(next, player, gs) {
+			next = cornerNudge(player, delta, gs)
+			if next == player.SubPosition {
+				break
+			}
+		}
+		player.SubPosition = next
+		moved = true
+
+		if tile := tileAt(player.SubPosition); tile != player.Position {
+			player.Position = tile
+			checkPlayerPowerUpPickup(player, gs)
+		}
+	}
+	return moved
+}
+
+// cornerNudge returns the player's SubPosition moved one unit towards the centre of their tile
+// across the direction of travel, if they are blocked, within PixelCornerPad of the centre,
+// and the tile ahead of that centre is open. Otherwise it returns SubPosition unchanged.
+func cornerNudge(player *models.Player, delta models.Position, gs *models.GameState) models.Position {
+	center := tileCenter(player.Position)
+	offset := player.SubPosition.X - center.X
+	if delta.X != 0 {
+		offset = player.SubPosition.Y - center.Y
+	}
+	if offset == 0 || offset > PixelCornerPad || offset < -PixelCornerPad {
+		return player.SubPosition
+	}
+
+	ahead := models.Position{X: player.Position.X + delta.X, Y: player.Position.Y + delta.Y}
+	if !isPositionValid(ahead, player, gs) {
+		return player.SubPosition
+	}
+
+	step := -1
+	if offset < 0 {
+		step = 1
+	}
+	next := player.SubPosition
+	if delta.X != 0 {
+		next.Y += step
+	} else {
+		next.X += step
+	}
+	if !isSubPositionValid(next, player, gs) {
+		return player.SubPosition
+	}
+	return next
+}
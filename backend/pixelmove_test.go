@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"testing"
+)
+
+func TestPixelHitboxAgainstWall(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	gs.Config.PixelMovement = true
+	player := gs.Players[0]
+	player.SubPosition = tileCenter(player.Position)
+
+	// The border wall above ends at y=16; the hitbox top may touch that edge but not cross it
+	if !isSubPositionValid(models.Position{X: 24, Y: 22}, player, gs) {
+		t.Error("hitbox flush against the wall rejected")
+	}
+	if isSubPositionValid(models.Position{X: 24, Y: 21}, player, gs) {
+		t.Error("hitbox one unit into the wall accepted")
+	}
+
+	for i := 0; i < 5; i++ {
+		stepPixels(player, models.DirUp, gs)
+	}
+	if player.SubPosition != (models.Position{X: 24, Y: 22}) || player.Position != at(1, 1) {
+		t.Errorf("walking into the wall: at %v on tile %v, want stopped flush at (24,22) on (1,1)", player.SubPosition, player.Position)
+	}
+}
+
+func TestPixelHitboxAgainstCorner(t *testing.T) {
+	// The wall at (2,2) catches the right edge of a hitbox walking down from (1,1) off-centre
+	for _, c := range []struct {
+		x        int
+		wantTile models.Position
+	}{
+		{31, at(1, 1)}, // Too far off the lane to be nudged: stopped a unit short of the wall
+		{28, at(1, 3)}, // Within the corner pad: nudged back to the lane and through
+	} {
+		gs := newTestGame(at(1, 1), at(13, 11))
+		gs.Config.PixelMovement = true
+		player := gs.Players[0]
+		player.SubPosition = models.Position{X: c.x, Y: 24}
+
+		for i := 0; i < 20; i++ {
+			stepPixels(player, models.DirDown, gs)
+		}
+		if player.Position != c.wantTile {
+			t.Errorf("from x=%d: ended on tile %v at %v, want tile %v", c.x, player.Position, player.SubPosition, c.wantTile)
+		}
+		if c.wantTile == at(1, 1) && player.SubPosition != (models.Position{X: c.x, Y: 26}) {
+			t.Errorf("from x=%d: stopped at %v, want (%d,26) with the hitbox flush against the wall", c.x, player.SubPosition, c.x)
+		}
+	}
+}
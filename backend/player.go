@@ -1,81 +1,294 @@
 package main
 
-import "bomberman-dom/models"
+import (
+	"bomberman-dom/models"
+	"fmt"
+	"slices"
+	"sort"
+)
 
-// MovePlayer updates a player's position based on their input and speed.
-// It moves the player one step at a time for the total move amount,
-// checking for collisions at each step to prevent "tunneling" through objects.
-// If preciseMov is true, the player moves only 1 step regardless of speed boosts.
-func MovePlayer(player *models.Player, direction string, gs *models.GameState, preciseMove ...bool) {
+// MovePlayer moves a player one tile in a direction. Speed doesn't add tiles per move; it
+// shortens the cooldown between tiles (see MoveInterval), so fast players move more often
+// instead of jumping.
+// A move that is blocked outright or comes during the cooldown is buffered in PendingDirection
+// and retried every tick by RetryPendingMoves until it goes through or another move replaces it.
+func MovePlayer(player *models.Player, direction models.Direction, gs *models.GameState) {
 	if !player.Alive {
 		return // Dead players can't move
 	}
+	if gs == nil || gs.Map == nil {
+		return // Nowhere to move on a game without a map
+	}
+
+	if stepPlayer(player, direction, gs) {
+		player.PendingDirection = ""
+	} else {
+		player.PendingDirection = direction
+	}
+}
+
+// NewGamePlayer creates a living game Player with the lives and stats cfg starts everyone with.
+// Power-ups raise the stats from there; starting stats don't count as collected power-ups.
+func NewGamePlayer(id, name string, cfg models.GameConfig) *models.Player {
+	return &models.Player{
+		ID:         id,
+		Name:       name,
+		Lives:      cfg.StartingLives,
+		Alive:      true,
+		BombCount:  cfg.StartingBombs,
+		FlameRange: cfg.StartingFlame,
+		Speed:      cfg.StartingSpeed,
+	}
+}
+
+// RetryPendingMoves tries each living player's buffered move once more.
+func RetryPendingMoves(gs *models.GameState) {
+	if gs.Map == nil {
+		return
+	}
+	var waiting []*models.Player
+	for _, player := range gs.Players {
+		if player.PendingDirection == "" {
+			continue
+		}
+		if !player.Alive {
+			player.PendingDirection = ""
+			continue
+		}
+		waiting = append(waiting, player)
+	}
+	settleSteps(waiting, func(p *models.Player) bool { return stepPending(p, gs) })
+}
+
+// stepPending tries a player's buffered move and clears it once it goes through.
+func stepPending(player *models.Player, gs *models.GameState) bool {
+	if player.PendingDirection == "" || !stepPlayer(player, player.PendingDirection, gs) {
+		return false
+	}
+	player.PendingDirection = ""
+	return true
+}
+
+// settleSteps runs step for every player, over and over, until a pass moves nobody. A player
+// blocked only by someone who leaves their tile later in the same tick then still gets through,
+// whatever order the players are stepped in, so a line of players can all shift one tile
+// together. Each player moves at most once, since a step starts their movement cooldown.
+// It returns the players who could not move; players may be reused for that result.
+func settleSteps(players []*models.Player, step func(*models.Player) bool) []*models.Player {
+	for {
+		stuck := players[:0]
+		for _, player := range players {
+			if !step(player) {
+				stuck = append(stuck, player)
+			}
+		}
+		if len(stuck) == len(players) {
+			return stuck
+		}
+		players = stuck
+	}
+}
+
+// Tile movement pacing: a player at Speed 0 moves a tile every BaseMoveInterval ticks
+// (5 tiles per second at 20 ticks/sec), and each Speed level takes a tick off, down to
+// MinMoveInterval.
+const (
+	BaseMoveInterval = 4
+	MinMoveInterval  = 1
+)
+
+// MoveInterval is how many ticks a player waits between two tiles.
+func MoveInterval(player *models.Player) int {
+	return max(BaseMoveInterval-player.Speed, MinMoveInterval)
+}
+
+// TickMoveCooldowns counts down every player's movement cooldown by one tick.
+func TickMoveCooldowns(gs *models.GameState) {
+	for _, player := range gs.Players {
+		if player.MoveCooldown > 0 {
+			player.MoveCooldown--
+		}
+	}
+}
 
-	// Determine move amount - if precise movement is requested, move only 1 step
-	moveAmount := 1 + player.Speed
-	if len(preciseMove) > 0 && preciseMove[0] {
-		moveAmount = 1 // Precise movement: always move 1 step regardless of speed
+// stepPlayer does the actual movement for MovePlayer and reports whether the player
+// advanced a tile. It fails while the player's movement cooldown is running.
+func stepPlayer(player *models.Player, direction models.Direction, gs *models.GameState) bool {
+	if HasStatus(player, models.StatusReverseControls) {
+		direction = direction.Reverse()
 	}
+	delta := direction.Delta()
+	if delta == (models.Position{}) {
+		return false // Not a direction
+	}
+
+	targetPos := models.Position{X: player.Position.X + delta.X, Y: player.Position.Y + delta.Y}
+	return ApplyClientPosition(gs, player, targetPos) == nil
+}
+
+// ApplyClientPosition moves a player to a position if it is a legal single step: the current
+// tile or an orthogonally adjacent, walkable one, once the movement cooldown is over. Every
+// move goes through it. Moves are normally resolved on the server from directions; this also
+// guards any future client position sync, where a position further away can only come from
+// a modified client.
+func ApplyClientPosition(gs *models.GameState, player *models.Player, pos models.Position) error {
+	if !player.Alive {
+		return fmt.Errorf("player %s is not alive", player.ID)
+	}
+	if pos == player.Position {
+		return nil
+	}
+	if player.MoveCooldown > 0 {
+		return fmt.Errorf("player %s can't move for %d more ticks", player.ID, player.MoveCooldown)
+	}
+	if manhattan(pos, player.Position) != 1 {
+		gameLogger.Warnf("Suspected teleport: player %s reported %v from %v", player.ID, pos, player.Position)
+		return fmt.Errorf("position %v is not adjacent to %v", pos, player.Position)
+	}
+	if !isPositionValid(pos, player, gs) {
+		return fmt.Errorf("position %v is blocked", pos)
+	}
+
+	player.Position = pos
+	player.MoveCooldown = MoveInterval(player)
+	if gs.Config.PixelMovement {
+		player.SubPosition = tileCenter(player.Position)
+	}
+	checkPlayerPowerUpPickup(player, gs)
+	return nil
+}
+
+// Player input actions carried by a MoveIntent
+const (
+	ActionMove      = "move"       // Move one tile
+	ActionBomb      = "bomb"       // Place a bomb
+	ActionMoveStart = "move_start" // Start moving continuously in Direction
+	ActionMoveStop  = "move_stop"  // Stop continuous movement
+	ActionPunch     = "punch"      // Punch the adjacent bomb in Direction
+	ActionLeave     = "leave"      // The player disconnected and is eliminated
+)
+
+// MoveIntent is a player input collected between ticks and applied by ResolveMoves.
+type MoveIntent struct {
+	Player    *models.Player
+	Action    string // One of the Action constants; empty means ActionMove
+	Direction models.Direction
+}
 
-	// We check each step individually to prevent jumping over walls.
-	for i := 0; i < moveAmount; i++ {
-		targetPos := player.Position
-		switch direction {
-		case "up":
-			targetPos.Y--
-		case "down":
-			targetPos.Y++
-		case "left":
-			targetPos.X--
-		case "right":
-			targetPos.X++
+// ResolveMoves applies the inputs collected since the last tick in arrival order.
+// Each move sees the positions produced by the moves before it, so when two players
+// head for the same free tile only the earlier input gets there; the later one is blocked.
+// A move blocked by a player who moves away later in the tick is retried as soon as the tile
+// frees up, ahead of any later input for that tile.
+// Inputs from eliminated players, who keep watching as spectators, are dropped.
+func ResolveMoves(gs *models.GameState, intents []MoveIntent) {
+	var blocked []*models.Player
+	for _, intent := range intents {
+		if !intent.Player.Alive {
+			continue
 		}
+		intent.Player.LastInputTick = gs.Tick
+		switch intent.Action {
+		case ActionBomb:
+			PlaceBomb(gs, intent.Player)
+		case ActionMoveStart:
+			intent.Player.MoveDirection = intent.Direction // First tile goes once the cooldown is over
+		case ActionMoveStop:
+			intent.Player.MoveDirection = ""
+		case ActionPunch:
+			PunchBomb(gs, intent.Player, intent.Direction)
+		case ActionLeave:
+			LeaveGame(gs, intent.Player)
+		default:
+			MovePlayer(intent.Player, intent.Direction, gs)
+			if intent.Player.PendingDirection != "" && !slices.Contains(blocked, intent.Player) {
+				blocked = append(blocked, intent.Player)
+			}
+		}
+		if len(blocked) > 0 {
+			blocked = settleSteps(blocked, func(p *models.Player) bool { return stepPending(p, gs) })
+		}
+	}
+}
 
-		if isPositionValid(targetPos, player, gs) {
-			// If the next step is valid, update the player's position.
-			player.Position = targetPos
-			
-			// Check for power-up collection at each step to prevent skipping
-			checkPlayerPowerUpPickup(player, gs)
-		} else {
-			// If the path is blocked, stop moving immediately.
-			break
+// AdvanceContinuousMoves moves every player holding a direction by one tile whenever their
+// movement cooldown allows, or by a few SubTile units every tick with pixel movement.
+// Running into something ends the movement.
+func AdvanceContinuousMoves(gs *models.GameState) {
+	if gs.Map == nil {
+		return
+	}
+	if gs.Config.PixelMovement {
+		for _, player := range gs.Players {
+			syncSubPosition(player) // Spawns and respawns only set the tile
+		}
+	}
+	var moving []*models.Player
+	for _, player := range gs.Players {
+		if player.MoveDirection == "" {
+			continue
+		}
+		if !player.Alive {
+			player.MoveDirection = ""
+			continue
+		}
+		if gs.Config.PixelMovement {
+			if !stepPixels(player, player.MoveDirection, gs) {
+				player.MoveDirection = ""
+			}
+			continue
 		}
+		if player.MoveCooldown > 0 {
+			continue
+		}
+		moving = append(moving, player)
+	}
+
+	stuck := settleSteps(moving, func(p *models.Player) bool { return stepPlayer(p, p.MoveDirection, gs) })
+	for _, player := range stuck {
+		player.MoveDirection = ""
 	}
 }
 
 // isPositionValid checks if a given position is within map bounds and not occupied by a solid object.
 func isPositionValid(pos models.Position, movingPlayer *models.Player, gs *models.GameState) bool {
+	if gs.Map == nil {
+		return false
+	}
+
 	// 1. Check map boundaries (assuming a simple grid size)
 	if pos.X < 0 || pos.X >= gs.Map.Width || pos.Y < 0 || pos.Y >= gs.Map.Height {
 		return false
 	}
 
 	// 2. Check for collisions with Walls
-	for _, wall := range gs.Map.Walls {
-		if wall.Position == pos {
-			return false
-		}
+	if gs.Map.WallAt(pos) {
+		return false
 	}
 
 	// 3. Check for collisions with Blocks (only non-destroyed blocks block movement)
-	for _, block := range gs.Map.Blocks {
-		if block.Position == pos && !block.Destroyed {
-			return false // Only non-destroyed blocks block movement
-		}
+	if gs.Map.BlockAt(pos) != nil {
+		return false
 	}
 
-	// 4. Check for collisions with other Players
-	for _, otherPlayer := range gs.Players {
-		// A player cannot move onto a tile occupied by another player.
-		if otherPlayer.ID != movingPlayer.ID && otherPlayer.Position == pos {
-			return false
+	// 4. Check for collisions with other Players, unless the game lets players pass through each other
+	if !gs.Config.PassThrough {
+		for _, otherPlayer := range gs.Players {
+			// A player cannot move onto a tile occupied by another player.
+			if otherPlayer.ID != movingPlayer.ID && otherPlayer.Position == pos {
+				return false
+			}
 		}
 	}
 
-	// 5. Check for collisions with Bombs
+	// 5. Lava blocks everyone until it cools
+	if hazardAt(gs, pos) {
+		return false
+	}
+
+	// 6. Check for collisions with Bombs
 	for _, bomb := range gs.Bombs {
-		if bomb.Position == pos {
+		if bomb.Position == pos && bomb.Airborne == 0 {
 			// A bomb is solid UNLESS the player is currently standing on it.
 			// This allows the "walk-off" mechanic but prevents walking back onto it.
 			return movingPlayer.Position == bomb.Position
@@ -87,7 +300,12 @@ func isPositionValid(pos models.Position, movingPlayer *models.Player, gs *model
 
 // IsGameOver checks if the game has concluded by counting the number of living players.
 // It returns true if one or zero players are left alive, false otherwise.
+// In team mode the game ends once one or zero teams still have a living player.
 func IsGameOver(gs *models.GameState) bool {
+	if gs.Config.TeamMode {
+		return len(aliveTeams(gs)) <= 1
+	}
+
 	aliveCount := 0
 	for _, player := range gs.Players {
 		if player.Alive {
@@ -114,11 +332,176 @@ func GetWinner(gs *models.GameState) *models.Player {
 	return lastAlivePlayer // This will be the single winner, or nil if 0 are alive.
 }
 
+// GetWinningTeam returns the TeamID of the only team with living players,
+// or -1 if no team survived (a draw) or several teams are still alive.
+func GetWinningTeam(gs *models.GameState) int {
+	teams := aliveTeams(gs)
+	if len(teams) != 1 {
+		return -1
+	}
+	for team := range teams {
+		return team
+	}
+	return -1
+}
+
+// aliveTeams returns the set of TeamIDs that still have at least one living player.
+func aliveTeams(gs *models.GameState) map[int]bool {
+	teams := make(map[int]bool)
+	for _, p := range gs.Players {
+		if p.Alive {
+			teams[p.TeamID] = true
+		}
+	}
+	return teams
+}
+
+// KillPlayer marks a player who lost their last life to fire as dead and records their place in
+// EliminationOrder, crediting killerID, the owner of the bomb. Players eliminated during the
+// same tick share a rank.
+func KillPlayer(gs *models.GameState, player *models.Player, killerID string) {
+	eliminate(gs, player, "", killerID)
+}
+
+// LeaveGame eliminates a player who disconnected from a running game.
+func LeaveGame(gs *models.GameState, player *models.Player) {
+	eliminate(gs, player, models.EliminationLeft, "")
+}
+
+// SurrenderIdlePlayers eliminates every human player who hasn't sent input for gs.Config.AFKTimeout
+// ticks, so a match can't stall on someone who walked away. Holding a direction counts as input.
+func SurrenderIdlePlayers(gs *models.GameState) {
+	if gs.Config.AFKTimeout <= 0 {
+		return
+	}
+	for _, player := range gs.Players {
+		if !player.Alive || player.IsBot {
+			continue
+		}
+		if player.MoveDirection != "" {
+			player.LastInputTick = gs.Tick
+			continue
+		}
+		if gs.Tick-player.LastInputTick >= gs.Config.AFKTimeout {
+			eliminate(gs, player, models.EliminationAFK, "")
+		}
+	}
+}
+
+// eliminate marks the player as out and records why, and by whom, in gs.EliminationOrder.
+func eliminate(gs *models.GameState, player *models.Player, reason, killerID string) {
+	if !player.Alive {
+		return
+	}
+	player.Alive = false
+
+	rank := len(gs.EliminationOrder) + 1
+	if n := len(gs.EliminationOrder); n > 0 && gs.EliminationOrder[n-1].Tick == gs.Tick {
+		rank = gs.EliminationOrder[n-1].Rank
+	}
+	gs.EliminationOrder = append(gs.EliminationOrder, models.Elimination{
+		PlayerID: player.ID,
+		Tick:     gs.Tick,
+		Rank:     rank,
+		Reason:   reason,
+		KillerID: killerID,
+		Position: player.Position,
+	})
+}
+
+// Standings ranks every player for the end of the game: survivors first, then by how late they
+// were eliminated, then by Score, BlocksDestroyed and PowerUpsCollected. Players equal on all of
+// these share a place, and places after a tie skip ahead (1, 1, 3).
+func Standings(gs *models.GameState) []models.Standing {
+	eliminatedAt := make(map[string]int, len(gs.EliminationOrder))
+	for _, e := range gs.EliminationOrder {
+		eliminatedAt[e.PlayerID] = e.Tick
+	}
+	// Survivors sort as if eliminated after every recorded tick
+	lastedUntil := func(p *models.Player) int {
+		if tick, ok := eliminatedAt[p.ID]; ok && !p.Alive {
+			return tick
+		}
+		return gs.Tick + 1
+	}
+	better := func(a, b *models.Player) bool {
+		if x, y := lastedUntil(a), lastedUntil(b); x != y {
+			return x > y
+		}
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.BlocksDestroyed != b.BlocksDestroyed {
+			return a.BlocksDestroyed > b.BlocksDestroyed
+		}
+		return a.PowerUpsCollected > b.PowerUpsCollected
+	}
+
+	players := make([]*models.Player, len(gs.Players))
+	copy(players, gs.Players)
+	sort.SliceStable(players, func(i, j int) bool {
+		return better(players[i], players[j])
+	})
+
+	standings := make([]models.Standing, len(players))
+	for i, p := range players {
+		place := i + 1
+		if i > 0 && !better(players[i-1], p) {
+			place = standings[i-1].Place
+		}
+		standings[i] = models.Standing{
+			PlayerID:          p.ID,
+			Place:             place,
+			Score:             p.Score,
+			BlocksDestroyed:   p.BlocksDestroyed,
+			PowerUpsCollected: p.PowerUpsCollected,
+		}
+	}
+	return standings
+}
+
+// GetPlayerState builds the private state of a player for MSG_PLAYER_STATE.
+func GetPlayerState(player *models.Player) *models.PlayerState {
+	remaining := player.BombCount - player.BombsPlaced
+	if remaining < 0 {
+		remaining = 0
+	}
+	abilities := []string{}
+	if player.CanPunch {
+		abilities = append(abilities, models.PunchBomb.String())
+	}
+	if player.HasLava {
+		abilities = append(abilities, models.LavaBomb.String())
+	}
+	return &models.PlayerState{
+		PlayerID:       player.ID,
+		BombCount:      player.BombCount,
+		BombsPlaced:    player.BombsPlaced,
+		BombsRemaining: remaining,
+		BombCooldown:   player.BombCooldown,
+		FlameRange:     player.FlameRange,
+		Speed:          player.Speed,
+		Invincible:     player.Invincible,
+		Shielded:       player.Shielded,
+		Abilities:      abilities,
+	}
+}
+
 // UpdatePlayers handles per-tick updates for all players, like invincibility timers.
 func UpdatePlayers(gs *models.GameState) {
 	for _, player := range gs.Players {
 		if player.Invincible > 0 {
 			player.Invincible--
 		}
+		if player.Shielded > 0 {
+			player.Shielded--
+		}
+		if player.BombCooldown > 0 {
+			player.BombCooldown--
+		}
+		if player.Alive && HasStatus(player, models.StatusAutoBomb) {
+			PlaceBomb(gs, player) // Cooldown and BombCount still apply
+		}
+		tickStatuses(player)
 	}
 }
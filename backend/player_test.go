@@ -0,0 +1,277 @@
+package main
+
+import (
+	"bomberman-dom/logging"
+	"bomberman-dom/models"
+	"strings"
+	"testing"
+)
+
+func TestResolveMovesSameTarget(t *testing.T) {
+	for _, p2First := range []bool{false, true} {
+		gs := newTestGame(at(1, 1), at(3, 1))
+		p1, p2 := gs.Players[0], gs.Players[1]
+		intents := []MoveIntent{
+			{Player: p1, Action: ActionMove, Direction: models.DirRight},
+			{Player: p2, Action: ActionMove, Direction: models.DirLeft},
+		}
+		winner, loser, loserStart := p1, p2, at(3, 1)
+		if p2First {
+			intents[0], intents[1] = intents[1], intents[0]
+			winner, loser, loserStart = p2, p1, at(1, 1)
+		}
+
+		ResolveMoves(gs, intents)
+		if winner.Position != at(2, 1) {
+			t.Errorf("earlier input: %s at %v, want (2,1)", winner.ID, winner.Position)
+		}
+		if loser.Position != loserStart {
+			t.Errorf("later input: %s moved to %v, want it blocked at %v", loser.ID, loser.Position, loserStart)
+		}
+	}
+}
+
+func TestLineOfPlayersShiftsTogether(t *testing.T) {
+	for _, action := range []string{ActionMove, ActionMoveStart} {
+		gs := newTestGame(at(1, 1), at(2, 1), at(3, 1), at(4, 1))
+		// Back of the line first, so every player's target is still taken when their input is applied
+		var intents []MoveIntent
+		for _, p := range gs.Players {
+			intents = append(intents, MoveIntent{Player: p, Action: action, Direction: models.DirRight})
+		}
+		ResolveMoves(gs, intents)
+		if action == ActionMoveStart {
+			GameTick(gs)
+		}
+		for i, p := range gs.Players {
+			if want := at(i+2, 1); p.Position != want {
+				t.Errorf("%s: %s at %v, want %v", action, p.ID, p.Position, want)
+			}
+		}
+	}
+
+	// Two players walking into each other can't swap places
+	gs := newTestGame(at(1, 1), at(2, 1))
+	ResolveMoves(gs, []MoveIntent{
+		{Player: gs.Players[0], Action: ActionMove, Direction: models.DirRight},
+		{Player: gs.Players[1], Action: ActionMove, Direction: models.DirLeft},
+	})
+	if gs.Players[0].Position != at(1, 1) || gs.Players[1].Position != at(2, 1) {
+		t.Errorf("players swapped to %v and %v", gs.Players[0].Position, gs.Players[1].Position)
+	}
+}
+
+func TestTiebreakCountersIncrement(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	gs.Map.Blocks = []*models.Block{
+		{Position: at(3, 1), HiddenPowerUp: &models.PowerUp{Type: models.FlameUp}},
+		{Position: at(1, 3)},
+	}
+	gs.Map.Reindex()
+	gs.Bombs = []*models.Bomb{{Position: at(1, 1), OwnerID: "p1", Timer: 1, FlameRange: 2}}
+
+	UpdateBombs(gs)
+	p1, p2 := gs.Players[0], gs.Players[1]
+	if p1.BlocksDestroyed != 2 || p2.BlocksDestroyed != 0 {
+		t.Errorf("blocks destroyed p1=%d p2=%d, want 2 and 0", p1.BlocksDestroyed, p2.BlocksDestroyed)
+	}
+
+	gs.Tick += PowerUpRevealDelay
+	p2.Position = at(3, 1)
+	PowerUpPickups(gs)
+	if p1.PowerUpsCollected != 0 || p2.PowerUpsCollected != 1 {
+		t.Errorf("power-ups collected p1=%d p2=%d, want 0 and 1", p1.PowerUpsCollected, p2.PowerUpsCollected)
+	}
+}
+
+func TestStandingsTiebreakOrder(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(3, 1), at(5, 1), at(7, 1), at(9, 1))
+	// All survive a timed-out game with the same score, so only the counters separate them
+	set := func(i, blocks, powerUps int) {
+		gs.Players[i].BlocksDestroyed, gs.Players[i].PowerUpsCollected = blocks, powerUps
+	}
+	set(0, 1, 5)
+	set(1, 3, 0)
+	set(2, 3, 2)
+	set(3, 1, 5)
+	set(4, 0, 9)
+	gs.Players[4].Score = 1
+
+	got := Standings(gs)
+	want := []struct {
+		id    string
+		place int
+	}{{"p5", 1}, {"p3", 2}, {"p2", 3}, {"p1", 4}, {"p4", 4}}
+	for i, w := range want {
+		if got[i].PlayerID != w.id || got[i].Place != w.place {
+			t.Errorf("standing %d = %s in place %d, want %s in place %d", i, got[i].PlayerID, got[i].Place, w.id, w.place)
+		}
+	}
+}
+
+func TestBufferedMoveAfterWallRemoved(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	gs.Map.Walls = append(gs.Map.Walls, &models.Wall{Position: at(2, 1)})
+	gs.Map.Reindex()
+	player := gs.Players[0]
+
+	ResolveMoves(gs, []MoveIntent{{Player: player, Direction: models.DirRight}})
+	for i := 0; i < 3; i++ {
+		GameTick(gs)
+	}
+	if player.Position != at(1, 1) || player.PendingDirection != models.DirRight {
+		t.Fatalf("blocked player at %v with pending %q, want (1,1) with right buffered", player.Position, player.PendingDirection)
+	}
+
+	// The wall goes away and the buffered move goes through on its own
+	gs.Map.Walls = gs.Map.Walls[:len(gs.Map.Walls)-1]
+	gs.Map.Reindex()
+	GameTick(gs)
+	if player.Position != at(2, 1) || player.PendingDirection != "" {
+		t.Errorf("after the wall was removed player at %v with pending %q, want (2,1) and nothing buffered", player.Position, player.PendingDirection)
+	}
+}
+
+func TestHoldToMove(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	player := gs.Players[0]
+	interval := MoveInterval(player)
+
+	ResolveMoves(gs, []MoveIntent{{Player: player, Action: ActionMoveStart, Direction: models.DirDown}})
+	for i := 0; i < 3*interval; i++ {
+		GameTick(gs)
+	}
+	if player.Position != at(1, 4) || player.MoveDirection != models.DirDown {
+		t.Fatalf("after %d ticks of holding down player at %v moving %q, want (1,4) still moving down",
+			3*interval, player.Position, player.MoveDirection)
+	}
+
+	ResolveMoves(gs, []MoveIntent{{Player: player, Action: ActionMoveStop}})
+	for i := 0; i < 3*interval; i++ {
+		GameTick(gs)
+	}
+	if player.Position != at(1, 4) || player.MoveDirection != "" {
+		t.Errorf("after MOVE_STOP player at %v moving %q, want stopped at (1,4)", player.Position, player.MoveDirection)
+	}
+}
+
+func TestSpeedPacesMovement(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(1, 11))
+	slow, fast := gs.Players[0], gs.Players[1]
+	fast.Speed = 2
+	ResolveMoves(gs, []MoveIntent{
+		{Player: slow, Action: ActionMoveStart, Direction: models.DirRight},
+		{Player: fast, Action: ActionMoveStart, Direction: models.DirRight},
+	})
+
+	const window = 4 * 4 // Four moves at Speed 0
+	for i := 0; i < window; i++ {
+		before := map[*models.Player]int{slow: slow.Position.X, fast: fast.Position.X}
+		GameTick(gs)
+		for p, x := range before {
+			if step := p.Position.X - x; step > 1 {
+				t.Fatalf("tick %d: speed %d player jumped %d tiles", gs.Tick, p.Speed, step)
+			}
+		}
+	}
+
+	slowTiles, fastTiles := slow.Position.X-1, fast.Position.X-1
+	if slowTiles != 4 || fastTiles != 2*slowTiles {
+		t.Errorf("over %d ticks speed 0 moved %d tiles and speed 2 moved %d, want 4 and 8", window, slowTiles, fastTiles)
+	}
+}
+
+func TestPassThroughPlayersSetting(t *testing.T) {
+	for _, passThrough := range []bool{false, true} {
+		lh := NewLobbyHandler(testLogger())
+		lh.lobby.PassThrough = passThrough
+		gs := newTestGame(at(1, 1), at(3, 1))
+		gs.Config.PassThrough = lh.gameConfig().PassThrough
+		walker := gs.Players[0]
+		// A bomb further down the corridor blocks either way
+		gs.Bombs = []*models.Bomb{{Position: at(5, 1), OwnerID: "p2", Timer: 1000, FlameRange: 1}}
+
+		ResolveMoves(gs, []MoveIntent{{Player: walker, Action: ActionMoveStart, Direction: models.DirRight}})
+		for i := 0; i < 6*MoveInterval(walker); i++ {
+			GameTick(gs)
+		}
+
+		want := at(2, 1) // Stopped by the other player
+		if passThrough {
+			want = at(4, 1) // Walked through them, stopped by the bomb
+		}
+		if walker.Position != want {
+			t.Errorf("pass-through %v: walker at %v, want %v", passThrough, walker.Position, want)
+		}
+	}
+}
+
+func TestAFKPlayerSurrenders(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	gs.Config.AFKTimeout = 50
+	active, idle := gs.Players[0], gs.Players[1]
+
+	directions := []models.Direction{models.DirRight, models.DirLeft}
+	for gs.Tick < gs.Config.AFKTimeout-1 {
+		if gs.Tick%10 == 0 {
+			ResolveMoves(gs, []MoveIntent{{Player: active, Direction: directions[gs.Tick/10%2]}})
+		}
+		GameTick(gs)
+	}
+	if !idle.Alive {
+		t.Fatalf("idle player surrendered at tick %d, before the %d tick timeout", gs.Tick, gs.Config.AFKTimeout)
+	}
+
+	GameTick(gs)
+	if idle.Alive {
+		t.Fatalf("idle player still in the game after %d ticks without input", gs.Tick)
+	}
+	if len(gs.EliminationOrder) != 1 || gs.EliminationOrder[0].PlayerID != idle.ID || gs.EliminationOrder[0].Reason != models.EliminationAFK {
+		t.Errorf("eliminations %+v, want only %s for being AFK", gs.EliminationOrder, idle.ID)
+	}
+	if !active.Alive {
+		t.Error("a player sending input was eliminated as AFK")
+	}
+}
+
+func TestApplyClientPosition(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	gs.PowerUps = []*models.ActivePowerUp{{Position: at(2, 1), Type: models.BombUp}}
+	player := gs.Players[0]
+
+	var logged strings.Builder
+	defer func(previous *logging.Logger) { gameLogger = previous }(gameLogger)
+	gameLogger = logging.New(&logged, logging.Warn)
+
+	// One tile over: accepted, and treated exactly like a direction move
+	if err := ApplyClientPosition(gs, player, at(2, 1)); err != nil {
+		t.Fatalf("adjacent move rejected: %v", err)
+	}
+	if player.Position != at(2, 1) || player.MoveCooldown != MoveInterval(player) || player.BombCount != 2 {
+		t.Errorf("after the move: at %v, cooldown %d, %d bombs; want (2,1), cooldown %d and the power-up",
+			player.Position, player.MoveCooldown, player.BombCount, MoveInterval(player))
+	}
+	if err := ApplyClientPosition(gs, player, at(3, 1)); err == nil || player.Position != at(2, 1) {
+		t.Errorf("second move during the cooldown accepted, now at %v", player.Position)
+	}
+	if logged.Len() != 0 {
+		t.Errorf("legal moves logged a warning: %s", logged.String())
+	}
+
+	// Across the map: rejected and reported, even with the cooldown over
+	player.MoveCooldown = 0
+	if err := ApplyClientPosition(gs, player, at(11, 11)); err == nil {
+		t.Error("teleport across the map accepted")
+	}
+	if player.Position != at(2, 1) {
+		t.Errorf("teleport moved the player to %v", player.Position)
+	}
+	if !strings.Contains(logged.String(), "Suspected teleport") {
+		t.Errorf("teleport not logged as suspected cheating: %q", logged.String())
+	}
+
+	// Into a wall: rejected
+	if err := ApplyClientPosition(gs, player, at(2, 2)); err == nil || player.Position != at(2, 1) {
+		t.Errorf("move into the wall at (2,2) accepted, now at %v", player.Position)
+	}
+}
@@ -4,6 +4,21 @@ import (
 	"bomberman-dom/models"
 )
 
+// ShieldDuration is how long a Shield keeps the player safe from flames (8 seconds at 20 ticks/sec).
+const ShieldDuration = 160
+
+// CurseDuration is how long a curse lasts once picked up (10 seconds at 20 ticks/sec).
+const CurseDuration = 200
+
+// PowerUpRevealDelay is how many ticks a power-up stays uncollectible after its block is destroyed,
+// so the bomber can't grab it through their own flame.
+const PowerUpRevealDelay = 10
+
+// isCollectible reports whether a power-up's reveal delay has elapsed.
+func isCollectible(gs *models.GameState, powerUp *models.ActivePowerUp) bool {
+	return gs.Tick >= powerUp.RevealTick
+}
+
 // CheckPowerUpPickups iterates through players and active power-ups to see if any have been collected.
 func PowerUpPickups(gs *models.GameState) {
 	var remainingPowerUps []*models.ActivePowerUp
@@ -12,7 +27,7 @@ func PowerUpPickups(gs *models.GameState) {
 		pickedUp := false
 		for _, player := range gs.Players {
 			// Check if a living player is on the same tile as the power-up
-			if player.Alive && player.Position == powerUp.Position {
+			if player.Alive && player.Position == powerUp.Position && isCollectible(gs, powerUp) {
 				applyPowerUp(player, powerUp.Type)
 				pickedUp = true
 				break // Only one player can pick it up
@@ -28,6 +43,7 @@ func PowerUpPickups(gs *models.GameState) {
 
 // applyPowerUp modifies a player's stats based on the power-up type.
 func applyPowerUp(player *models.Player, powerUpType models.PowerUpType) {
+	player.PowerUpsCollected++
 	switch powerUpType {
 	case models.BombUp:
 		player.BombCount++
@@ -35,7 +51,51 @@ func applyPowerUp(player *models.Player, powerUpType models.PowerUpType) {
 		player.FlameRange++
 	case models.SpeedUp:
 		player.Speed++
+	case models.Shield:
+		// Only fire checks Shielded; walls, blocks and bombs still block the player
+		player.Shielded = max(player.Shielded, ShieldDuration)
+	case models.CurseReverse:
+		AddStatus(player, models.StatusReverseControls, CurseDuration)
+	case models.CurseAutoBomb:
+		AddStatus(player, models.StatusAutoBomb, CurseDuration)
+	case models.PunchBomb:
+		player.CanPunch = true
+	case models.LavaBomb:
+		player.HasLava = true
+	}
+}
+
+// AddStatus puts a timed effect on the player. Picking up the same effect again restarts its timer.
+func AddStatus(player *models.Player, status string, ticks int) {
+	for i := range player.Statuses {
+		if player.Statuses[i].Type == status {
+			player.Statuses[i].TicksLeft = ticks
+			return
+		}
+	}
+	player.Statuses = append(player.Statuses, models.StatusEffect{Type: status, TicksLeft: ticks})
+}
+
+// HasStatus reports whether the effect is currently active on the player.
+func HasStatus(player *models.Player, status string) bool {
+	for _, effect := range player.Statuses {
+		if effect.Type == status {
+			return true
+		}
+	}
+	return false
+}
+
+// tickStatuses counts down the player's effects and drops the ones that ran out.
+func tickStatuses(player *models.Player) {
+	remaining := player.Statuses[:0]
+	for _, effect := range player.Statuses {
+		effect.TicksLeft--
+		if effect.TicksLeft > 0 {
+			remaining = append(remaining, effect)
+		}
 	}
+	player.Statuses = remaining
 }
 
 // checkPlayerPowerUpPickup checks if a specific player can pick up any power-up at their current position.
@@ -47,7 +107,7 @@ func checkPlayerPowerUpPickup(player *models.Player, gs *models.GameState) {
 
 	var remainingPowerUps []*models.ActivePowerUp
 	for _, powerUp := range gs.PowerUps {
-		if player.Position == powerUp.Position {
+		if player.Position == powerUp.Position && isCollectible(gs, powerUp) {
 			// Player picked up this power-up
 			applyPowerUp(player, powerUp.Type)
 		} else {
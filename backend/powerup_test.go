@@ -0,0 +1,101 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"testing"
+)
+
+func TestPowerUpRevealDelay(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	gs.Map.Blocks = []*models.Block{{Position: at(3, 1), HiddenPowerUp: &models.PowerUp{Type: models.BombUp}}}
+	gs.Map.Reindex()
+	gs.Bombs = []*models.Bomb{{Position: at(2, 1), OwnerID: "p1", Timer: 1, FlameRange: 1}}
+
+	UpdateBombs(gs)
+	if len(gs.PowerUps) != 1 {
+		t.Fatalf("%d power-ups after the block burned, want 1", len(gs.PowerUps))
+	}
+	revealed := gs.Tick
+
+	// The bomber steps onto the tile right away but can't take it until the delay is up
+	player := gs.Players[0]
+	player.Position = at(3, 1)
+	for ; gs.Tick < revealed+PowerUpRevealDelay; gs.Tick++ {
+		PowerUpPickups(gs)
+		checkPlayerPowerUpPickup(player, gs)
+		if len(gs.PowerUps) != 1 || player.BombCount != 1 {
+			t.Fatalf("tick %d: power-up collected %d ticks after the reveal, before the delay of %d",
+				gs.Tick, gs.Tick-revealed, PowerUpRevealDelay)
+		}
+	}
+
+	PowerUpPickups(gs)
+	if len(gs.PowerUps) != 0 || player.BombCount != 2 {
+		t.Errorf("power-up not collected once the delay elapsed: %d left, bomb count %d", len(gs.PowerUps), player.BombCount)
+	}
+}
+
+func TestReverseCurseExpires(t *testing.T) {
+	gs := newTestGame(at(5, 1), at(13, 11))
+	player := gs.Players[0]
+	applyPowerUp(player, models.CurseReverse)
+
+	moveRight := func() {
+		player.MoveCooldown = 0
+		ResolveMoves(gs, []MoveIntent{{Player: player, Direction: models.DirRight}})
+	}
+	moveRight()
+	if player.Position != at(4, 1) {
+		t.Fatalf("cursed player pressing right ended at %v, want (4,1)", player.Position)
+	}
+
+	// The curse lasts exactly CurseDuration ticks
+	for i := 0; i < CurseDuration-1; i++ {
+		UpdatePlayers(gs)
+	}
+	if !HasStatus(player, models.StatusReverseControls) {
+		t.Fatalf("curse wore off before %d ticks", CurseDuration)
+	}
+	UpdatePlayers(gs)
+	if HasStatus(player, models.StatusReverseControls) || len(player.Statuses) != 0 {
+		t.Fatalf("curse still active after %d ticks: %+v", CurseDuration, player.Statuses)
+	}
+
+	moveRight()
+	if player.Position != at(5, 1) {
+		t.Errorf("player pressing right after the curse ended at %v, want (5,1)", player.Position)
+	}
+}
+
+func TestShieldWalksThroughFlames(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	player := gs.Players[0]
+	lives := player.Lives
+	applyPowerUp(player, models.Shield)
+	if player.Shielded != ShieldDuration || player.Invincible != 0 {
+		t.Fatalf("shield gave shielded=%d invincible=%d, want %d and 0", player.Shielded, player.Invincible, ShieldDuration)
+	}
+
+	// A long-burning flame from p2's bomb, lit before the player walks in
+	flame := newFlame(at(2, 1), 10*ShieldDuration, "p2")
+	gs.Flames = append(gs.Flames, flame)
+	gs.Tick = 1
+	ResolveMoves(gs, []MoveIntent{{Player: player, Direction: models.DirRight}})
+	if player.Position != at(2, 1) {
+		t.Fatalf("shielded player at %v, want inside the flame at (2,1)", player.Position)
+	}
+
+	for player.Shielded > 0 {
+		GameTick(gs)
+		if player.Lives != lives || player.Position != at(2, 1) {
+			t.Fatalf("tick %d: shielded player burned with %d shield ticks left", gs.Tick, player.Shielded)
+		}
+	}
+	GameTick(gs)
+	if player.Lives != lives-1 || player.Position != player.SpawnPoint {
+		t.Errorf("player still in the flame once the shield ran out: %d lives at %v", player.Lives, player.Position)
+	}
+	if len(gs.EliminationOrder) != 0 {
+		t.Errorf("player eliminated with lives to spare")
+	}
+}
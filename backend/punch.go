@@ -0,0 +1,89 @@
+package main
+
+import "bomberman-dom/models"
+
+// PunchBomb lets a player holding the PunchBomb power-up knock the bomb on the tile next to them,
+// in the given direction. The bomb arcs over whatever is in the way and lands on the first empty
+// tile beyond, flying one tile per tick. It keeps its timer while airborne; a bomb whose timer
+// runs out in flight explodes where it lands. It reports whether a bomb was punched: there may
+// be no bomb there, the bomb may already be airborne, or no landing tile may exist before the
+// map edge.
+func PunchBomb(gs *models.GameState, player *models.Player, direction models.Direction) bool {
+	if !player.Alive || !player.CanPunch {
+		return false
+	}
+	if HasStatus(player, models.StatusReverseControls) {
+		direction = direction.Reverse()
+	}
+	delta := direction.Delta()
+	if delta == (models.Position{}) {
+		return false
+	}
+
+	target := models.Position{X: player.Position.X + delta.X, Y: player.Position.Y + delta.Y}
+	var bomb *models.Bomb
+	for _, b := range gs.Bombs {
+		if b.Position == target && b.Airborne == 0 {
+			bomb = b
+			break
+		}
+	}
+	if bomb == nil {
+		return false
+	}
+
+	distance, ok := punchLanding(gs, bomb, bomb.Position, delta)
+	if !ok {
+		return false
+	}
+	bomb.Airborne = distance
+	bomb.Direction = direction
+	return true
+}
+
+// punchLanding returns how many tiles past from, in the direction delta, the first tile a bomb
+// can land on is. Walls, intact blocks, other bombs and living players are flown over.
+func punchLanding(gs *models.GameState, bomb *models.Bomb, from, delta models.Position) (int, bool) {
+	pos := from
+	for distance := 1; ; distance++ {
+		pos = models.Position{X: pos.X + delta.X, Y: pos.Y + delta.Y}
+		if pos.X < 0 || pos.X >= gs.Map.Width || pos.Y < 0 || pos.Y >= gs.Map.Height {
+			return 0, false
+		}
+		if canLandBomb(gs, bomb, pos) {
+			return distance, true
+		}
+	}
+}
+
+// canLandBomb reports whether the tile is empty enough for a punched bomb to land on.
+func canLandBomb(gs *models.GameState, bomb *models.Bomb, pos models.Position) bool {
+	if isWall(gs, pos) || hasBlock(gs, pos) || hazardAt(gs, pos) {
+		return false
+	}
+	for _, other := range gs.Bombs {
+		if other != bomb && other.Position == pos {
+			return false
+		}
+	}
+	for _, p := range gs.Players {
+		if p.Alive && p.Position == pos {
+			return false
+		}
+	}
+	return true
+}
+
+// advanceAirborneBomb moves a punched bomb one tile along its flight. If its landing tile was
+// taken while it flew, it carries on to the next free one, or lands where it is if none is left.
+func advanceAirborneBomb(gs *models.GameState, bomb *models.Bomb) {
+	delta := bomb.Direction.Delta()
+	bomb.Position = models.Position{X: bomb.Position.X + delta.X, Y: bomb.Position.Y + delta.Y}
+	bomb.Airborne--
+	if bomb.Airborne > 0 || canLandBomb(gs, bomb, bomb.Position) {
+		return
+	}
+	if distance, ok := punchLanding(gs, bomb, bomb.Position, delta); ok {
+		bomb.Airborne = distance
+	}
+}
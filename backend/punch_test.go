@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"testing"
+)
+
+func TestPunchedBombClearsWall(t *testing.T) {
+	gs := newTestGame(at(1, 1), at(13, 11))
+	puncher := gs.Players[0]
+	puncher.CanPunch = true
+	gs.Map.Walls = append(gs.Map.Walls, &models.Wall{Position: at(3, 1)})
+	gs.Map.Reindex()
+	bomb := &models.Bomb{Position: at(2, 1), OwnerID: "p1", Timer: 30, FlameRange: 1}
+	gs.Bombs = []*models.Bomb{bomb}
+
+	if !PunchBomb(gs, puncher, models.DirRight) {
+		t.Fatal("punch refused with an open tile past the wall")
+	}
+	if bomb.Airborne != 2 {
+		t.Fatalf("bomb airborne for %d tiles, want 2: over the wall to (4,1)", bomb.Airborne)
+	}
+
+	UpdateBombs(gs)
+	if bomb.Position != at(3, 1) || bomb.Airborne != 1 {
+		t.Errorf("after one tick bomb at %v with %d tiles to go, want over the wall at (3,1) with 1", bomb.Position, bomb.Airborne)
+	}
+	UpdateBombs(gs)
+	if bomb.Position != at(4, 1) || bomb.Airborne != 0 {
+		t.Errorf("bomb landed at %v with %d tiles to go, want on (4,1)", bomb.Position, bomb.Airborne)
+	}
+	if bomb.Timer != 28 {
+		t.Errorf("timer %d after two ticks of flight, want 28: it keeps counting in the air", bomb.Timer)
+	}
+}
+
+func TestPunchWithoutLandingTile(t *testing.T) {
+	// Past the bomb there is only a player and then the border
+	gs := newTestGame(at(11, 1), at(13, 1))
+	puncher := gs.Players[0]
+	puncher.CanPunch = true
+	bomb := &models.Bomb{Position: at(12, 1), OwnerID: "p1", Timer: 30, FlameRange: 1}
+	gs.Bombs = []*models.Bomb{bomb}
+
+	if PunchBomb(gs, puncher, models.DirRight) {
+		t.Error("punch accepted with no tile to land on")
+	}
+	if bomb.Position != at(12, 1) || bomb.Airborne != 0 || bomb.Direction != "" {
+		t.Errorf("refused punch moved the bomb: at %v, airborne %d, direction %q", bomb.Position, bomb.Airborne, bomb.Direction)
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	ChatBurst  = 3               // Chat messages a player may send back to back
+	ChatRefill = 2 * time.Second // Time for a full chat burst to refill
+
+	EmoteBurst  = 2
+	EmoteRefill = 3 * time.Second
+
+	ResyncBurst  = 2
+	ResyncRefill = 5 * time.Second
+
+	StatsBurst  = 3
+	StatsRefill = 3 * time.Second
+)
+
+// RateLimiter is a token bucket per key (WebSocketID). Each bucket holds up to
+// capacity tokens and refills continuously at capacity tokens per period.
+type RateLimiter struct {
+	capacity float64
+	period   time.Duration
+	buckets  map[string]*tokenBucket
+	mu       sync.Mutex
+	now      func() time.Time
+}
+
+type tokenBucket struct {
+	tokens   float64
+	lastSeen time.Time
+}
+
+func NewRateLimiter(capacity int, period time.Duration) *RateLimiter {
+	return &RateLimiter{
+		capacity: float64(capacity),
+		period:   period,
+		buckets:  make(map[string]*tokenBucket),
+		now:      time.Now,
+	}
+}
+
+// Allow takes a token from key's bucket, reporting false if it is empty.
+func (rl *RateLimiter) Allow(key string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	bucket, ok := rl.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: rl.capacity, lastSeen: now}
+		rl.buckets[key] = bucket
+	}
+
+	refill := now.Sub(bucket.lastSeen).Seconds() / rl.period.Seconds() * rl.capacity
+	bucket.tokens = min(rl.capacity, bucket.tokens+refill)
+	bucket.lastSeen = now
+
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
+
+// Forget drops key's bucket, e.g. when the player disconnects.
+func (rl *RateLimiter) Forget(key string) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	delete(rl.buckets, key)
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ReplayInput is one player input, tagged with the tick it was applied before.
+// Action is one of the MoveIntent Action constants.
+type ReplayInput struct {
+	Tick      int              `json:"tick"`
+	PlayerID  string           `json:"playerId"`
+	Action    string           `json:"action"`
+	Direction models.Direction `json:"direction,omitempty"`
+}
+
+// Replay is a compact log of a game: the state it started from plus every input.
+// The generated map is stored as part of the starting state, so no RNG seed is needed
+// to rebuild it; everything after that is deterministic given the inputs.
+type Replay struct {
+	Initial   *models.GameState
+	Inputs    []ReplayInput
+	FinalTick int
+
+	mu sync.Mutex // Saved through replayFile
+}
+
+// NewReplay starts a replay from a copy of the game state as it is now.
+func NewReplay(gs *models.GameState) (*Replay, error) {
+	initial, err := copyGameState(gs)
+	if err != nil {
+		return nil, err
+	}
+	return &Replay{Initial: initial, Inputs: []ReplayInput{}}, nil
+}
+
+// Record appends the inputs applied before the given tick.
+func (r *Replay) Record(tick int, intents ...MoveIntent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, intent := range intents {
+		action := intent.Action
+		if action == "" {
+			action = ActionMove
+		}
+		r.Inputs = append(r.Inputs, ReplayInput{
+			Tick:      tick,
+			PlayerID:  intent.Player.ID,
+			Action:    action,
+			Direction: intent.Direction,
+		})
+	}
+}
+
+// Finish marks the tick the game ended on.
+func (r *Replay) Finish(tick int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.FinalTick = tick
+}
+
+// Run rebuilds the game from the starting state, feeding the recorded inputs through
+// ResolveMoves and GameTick, and returns the state at FinalTick.
+func (r *Replay) Run() (*models.GameState, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	gs, err := copyGameState(r.Initial)
+	if err != nil {
+		return nil, err
+	}
+
+	players := make(map[string]*models.Player, len(gs.Players))
+	for _, p := range gs.Players {
+		players[p.ID] = p
+	}
+
+	next := 0
+	for gs.Tick < r.FinalTick {
+		var intents []MoveIntent
+		for ; next < len(r.Inputs) && r.Inputs[next].Tick == gs.Tick; next++ {
+			input := r.Inputs[next]
+			player, ok := players[input.PlayerID]
+			if !ok {
+				return nil, fmt.Errorf("replay input at tick %d references unknown player %q", input.Tick, input.PlayerID)
+			}
+			switch input.Action {
+			case ActionMove, ActionBomb, ActionMoveStart, ActionMoveStop, ActionPunch, ActionLeave:
+				intents = append(intents, MoveIntent{Player: player, Action: input.Action, Direction: input.Direction})
+			default:
+				return nil, fmt.Errorf("replay input at tick %d has unknown action %q", input.Tick, input.Action)
+			}
+		}
+		ResolveMoves(gs, intents)
+		GameTick(gs)
+	}
+	return gs, nil
+}
+
+// replayFile is the saved form of a Replay.
+type replayFile struct {
+	Initial   *fullGameState `json:"initial"`
+	Inputs    []ReplayInput  `json:"inputs"`
+	FinalTick int            `json:"finalTick"`
+}
+
+// fullGameState encodes every field of a GameState. GameState.MarshalJSON only sends the
+// client schema, which can't rebuild a game.
+type fullGameState models.GameState
+
+// Save writes the replay as <dir>/<name>.json.
+func (r *Replay) Save(dir, name string) (string, error) {
+	r.mu.Lock()
+	data, err := json.Marshal(&replayFile{
+		Initial:   (*fullGameState)(r.Initial),
+		Inputs:    r.Inputs,
+		FinalTick: r.FinalTick,
+	})
+	r.mu.Unlock()
+	if err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, name+".json")
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+// replaysDir is where finished games are saved; REPLAYS_DIR unset disables saving.
+func replaysDir() string {
+	return os.Getenv("REPLAYS_DIR")
+}
+
+// replayName names a replay file after its lobby and the time the game ended.
+func replayName(lobbyID string, endedAt time.Time) string {
+	return fmt.Sprintf("%s-%d", lobbyID, endedAt.Unix())
+}
+
+// copyGameState deep-copies a game state through its full JSON form.
+func copyGameState(gs *models.GameState) (*models.GameState, error) {
+	data, err := json.Marshal((*fullGameState)(gs))
+	if err != nil {
+		return nil, err
+	}
+	copied := &models.GameState{}
+	if err := json.Unmarshal(data, (*fullGameState)(copied)); err != nil {
+		return nil, err
+	}
+	return copied, nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"bytes"
+	"os"
+)
+
+// Policies applied when a player's Send buffer is full.
+const (
+	OverflowDisconnect    = "disconnect"     // Evict the client (original behaviour)
+	OverflowDropOldest    = "drop_oldest"    // Discard the oldest queued message to make room
+	OverflowCoalesceState = "coalesce_state" // Drop queued state updates, keeping only the newest
+)
+
+// stateUpdatePrefix is how every marshaled MSG_GAME_STATE_UPDATE envelope begins.
+var stateUpdatePrefix = []byte(`{"type":"` + models.MSG_GAME_STATE_UPDATE + `"`)
+
+// overflowPolicyFromEnv reads SEND_OVERFLOW_POLICY, defaulting to coalescing state updates.
+func overflowPolicyFromEnv() string {
+	switch policy := os.Getenv("SEND_OVERFLOW_POLICY"); policy {
+	case OverflowDisconnect, OverflowDropOldest, OverflowCoalesceState:
+		return policy
+	}
+	return OverflowCoalesceState
+}
+
+// enqueueOnOverflow tries to make room in a full Send buffer according to policy and queue data.
+// It returns false when the message could not be queued and the client should be evicted.
+func enqueueOnOverflow(player *models.WebSocketPlayer, data []byte, policy string) bool {
+	switch policy {
+	case OverflowDropOldest:
+		select {
+		case <-player.Send:
+		default:
+		}
+
+	case OverflowCoalesceState:
+		// Pull everything that is queued right now and put back all but the stale state updates.
+		// The incoming message is the newest one, so a queued state update is always outdated.
+		queued := len(player.Send)
+		kept := make([][]byte, 0, queued)
+		for i := 0; i < queued; i++ {
+			select {
+			case msg := <-player.Send:
+				if !bytes.HasPrefix(msg, stateUpdatePrefix) && !isBinaryFrame(msg) {
+					kept = append(kept, msg)
+				}
+			default:
+			}
+		}
+		for _, msg := range kept {
+			select {
+			case player.Send <- msg:
+			default:
+				return false
+			}
+		}
+
+	default:
+		return false
+	}
+
+	select {
+	case player.Send <- data:
+		return true
+	default:
+		return false
+	}
+}
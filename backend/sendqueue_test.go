@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"encoding/json"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentBroadcastEvictsFullBuffer(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	lh.overflowPolicy = OverflowDisconnect
+	fast := addTestPlayer(lh, "fast", true)
+	slow := addTestPlayer(lh, "slow", true)
+	slow.Send = make(chan []byte, 2) // Never read: fills after two messages
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				lh.broadcastToLobby("", &models.WebSocketMessage{Type: models.MSG_CHAT_MESSAGE, Data: i})
+			}
+		}()
+	}
+	wg.Wait()
+
+	inLobby := func(p *models.WebSocketPlayer) bool {
+		lh.lobby.Mutex.RLock()
+		defer lh.lobby.Mutex.RUnlock()
+		_, ok := lh.lobby.Players[p.WebSocketID]
+		return ok
+	}
+	waitFor(t, "slow player eviction", func() bool { return !inLobby(slow) })
+	if !inLobby(fast) {
+		t.Error("player keeping up was evicted")
+	}
+	if n := len(messagesOfType(drainMessages(t, fast), models.MSG_CHAT_MESSAGE)); n != 8*20 {
+		t.Errorf("fast player got %d messages, want %d", n, 8*20)
+	}
+}
+
+func TestDoubleEvictionIsSafe(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	player := addTestPlayer(lh, "a", true)
+	addTestPlayer(lh, "b", true)
+
+	if !player.MarkEvicting() {
+		t.Fatal("first eviction request refused")
+	}
+	if player.MarkEvicting() {
+		t.Error("second eviction request accepted")
+	}
+
+	// Both teardown paths reaching the player must not close Send twice
+	lh.unregisterPlayer(player)
+	lh.unregisterPlayer(player)
+	player.CloseSend()
+
+	if got := len(connectedPlayers(lh)); got != 1 {
+		t.Errorf("%d players connected, want 1", got)
+	}
+	if n := lh.metrics.activeConnections.Load(); n != 1 {
+		t.Errorf("activeConnections = %d, want 1", n)
+	}
+}
+
+func TestEnqueueOnOverflow(t *testing.T) {
+	state := func(n int) []byte {
+		return []byte(`{"type":"` + models.MSG_GAME_STATE_UPDATE + `","data":{"tick":` + string(rune('0'+n)) + `}}`)
+	}
+	chat := []byte(`{"type":"` + models.MSG_CHAT_MESSAGE + `","data":"hi"}`)
+	full := func() *models.WebSocketPlayer {
+		p := &models.WebSocketPlayer{Send: make(chan []byte, 3)}
+		p.Send <- state(1)
+		p.Send <- chat
+		p.Send <- state(2)
+		return p
+	}
+	queued := func(p *models.WebSocketPlayer) []string {
+		var out []string
+		for len(p.Send) > 0 {
+			out = append(out, string(<-p.Send))
+		}
+		return out
+	}
+
+	p := full()
+	if !enqueueOnOverflow(p, state(3), OverflowCoalesceState) {
+		t.Fatal("coalesce refused the newest state")
+	}
+	got := queued(p)
+	if len(got) != 2 || got[0] != string(chat) || got[1] != string(state(3)) {
+		t.Errorf("coalesce left %q, want the chat then only the newest state", got)
+	}
+
+	p = full()
+	if !enqueueOnOverflow(p, state(3), OverflowDropOldest) {
+		t.Fatal("drop_oldest refused the message")
+	}
+	got = queued(p)
+	if len(got) != 3 || got[0] != string(chat) || got[2] != string(state(3)) {
+		t.Errorf("drop_oldest left %q", got)
+	}
+
+	p = full()
+	if enqueueOnOverflow(p, state(3), OverflowDisconnect) {
+		t.Error("disconnect policy queued the message")
+	}
+
+	// A buffer full of messages that must all be kept can't make room
+	p = &models.WebSocketPlayer{Send: make(chan []byte, 1)}
+	p.Send <- chat
+	if enqueueOnOverflow(p, state(3), OverflowCoalesceState) {
+		t.Error("coalesce dropped a chat message to make room")
+	}
+}
+
+func TestSlowReaderGetsLatestStateOnly(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	slow := addTestPlayer(lh, "slow", true)
+	drainMessages(t, slow)
+	gs := newTestGame(at(1, 1), at(13, 11))
+
+	// A hundred ticks go by without the client reading; a few events happen along the way
+	for tick := 1; tick <= 100; tick++ {
+		gs.Tick = tick
+		lh.broadcastToLobby("", &models.WebSocketMessage{Type: models.MSG_GAME_STATE_UPDATE, Data: gs})
+		if tick%25 == 0 {
+			lh.broadcastToLobby("", &models.WebSocketMessage{Type: models.MSG_CHAT_MESSAGE, Data: tick})
+		}
+	}
+
+	messages := drainMessages(t, slow)
+	states := messagesOfType(messages, models.MSG_GAME_STATE_UPDATE)
+	if len(states) != 1 {
+		t.Fatalf("slow reader has %d states waiting, want only the latest", len(states))
+	}
+	var state struct {
+		Tick int `json:"tick"`
+	}
+	if err := json.Unmarshal(states[0].Data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Tick != 100 {
+		t.Errorf("waiting state is from tick %d, want the newest, 100", state.Tick)
+	}
+	if n := len(messagesOfType(messages, models.MSG_CHAT_MESSAGE)); n != 4 {
+		t.Errorf("slow reader has %d chat messages waiting, want all 4", n)
+	}
+}
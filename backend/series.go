@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"sort"
+)
+
+// RoundWinners returns the IDs of the players credited with winning a finished game:
+// the sole survivor, or every member of the winning team. A draw credits nobody.
+func RoundWinners(gs *models.GameState) []string {
+	if gs.Draw {
+		return nil
+	}
+	if gs.Config.TeamMode {
+		var ids []string
+		for _, p := range gs.Players {
+			if p.TeamID == gs.WinningTeam {
+				ids = append(ids, p.ID)
+			}
+		}
+		return ids
+	}
+	if gs.Winner != nil {
+		return []string{gs.Winner.ID}
+	}
+	return nil
+}
+
+// AddRoundWins credits the winners of a finished game in the series score.
+func AddRoundWins(score map[string]int, gs *models.GameState) {
+	for _, id := range RoundWinners(gs) {
+		score[id]++
+	}
+}
+
+// SeriesStandings lists the players of the last round by round wins, most first, then by name.
+// Winner is set on everyone who reached winsNeeded.
+func SeriesStandings(score map[string]int, players []*models.Player, winsNeeded int) []models.SeriesStanding {
+	standings := make([]models.SeriesStanding, 0, len(players))
+	for _, p := range players {
+		standings = append(standings, models.SeriesStanding{
+			PlayerID: p.ID,
+			Name:     p.Name,
+			Wins:     score[p.ID],
+			Winner:   score[p.ID] >= winsNeeded,
+		})
+	}
+	sort.SliceStable(standings, func(i, j int) bool {
+		if standings[i].Wins != standings[j].Wins {
+			return standings[i].Wins > standings[j].Wins
+		}
+		return standings[i].Name < standings[j].Name
+	})
+	return standings
+}
+
+// SeriesOver reports whether any player has reached winsNeeded round wins.
+func SeriesOver(score map[string]int, winsNeeded int) bool {
+	for _, wins := range score {
+		if wins >= winsNeeded {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"encoding/json"
+	"testing"
+)
+
+func TestThreeRoundSeries(t *testing.T) {
+	lh := NewLobbyHandler(testLogger())
+	useFakeClock(t, lh, 0) // Hold the countdowns between rounds
+	alice := addTestPlayer(lh, "alice", true)
+	addTestPlayer(lh, "bob", true)
+	lh.lobby.Mutex.Lock()
+	lh.lobby.SeriesWins = 2
+	lh.lobby.Mutex.Unlock()
+
+	for round, winner := range []string{"alice", "bob", "alice"} {
+		// Each round is a fresh game won by one player
+		gs := newTestGame(at(1, 1), at(13, 11))
+		gs.Players[0].ID, gs.Players[1].ID = "alice", "bob"
+		gs.Status = models.Finished
+		for _, p := range gs.Players {
+			if p.ID == winner {
+				gs.Winner = p
+			}
+		}
+		lh.lobby.Mutex.Lock()
+		lh.GameState = gs
+		lh.lobby.GameStarted = true
+		lh.lobby.Round = round + 1
+		lh.lobby.Mutex.Unlock()
+		drainMessages(t, alice)
+
+		lh.finishRound()
+		updates := messagesOfType(drainMessages(t, alice), models.MSG_SERIES_UPDATE)
+		if len(updates) != 1 {
+			t.Fatalf("round %d: got %d series updates, want 1", round+1, len(updates))
+		}
+		var update models.SeriesUpdate
+		if err := json.Unmarshal(updates[0].Data, &update); err != nil {
+			t.Fatal(err)
+		}
+
+		lh.lobby.Mutex.RLock()
+		status := lh.lobby.Status
+		lh.lobby.Mutex.RUnlock()
+		if round < 2 {
+			if update.Finished || status != "starting" {
+				t.Fatalf("round %d: finished=%v status %q, want the next round counting down", round+1, update.Finished, status)
+			}
+			continue
+		}
+
+		if !update.Finished || status != "waiting" {
+			t.Fatalf("round 3: finished=%v status %q, want the series over and the lobby reopened", update.Finished, status)
+		}
+		top := update.Standings[0]
+		if top.PlayerID != "alice" || top.Wins != 2 || !top.Winner {
+			t.Errorf("series leader %+v, want alice winning with 2 round wins", top)
+		}
+		if second := update.Standings[1]; second.PlayerID != "bob" || second.Wins != 1 || second.Winner {
+			t.Errorf("runner-up %+v, want bob with 1 round win", second)
+		}
+	}
+
+	lh.lobby.Mutex.RLock()
+	defer lh.lobby.Mutex.RUnlock()
+	if lh.lobby.Round != 0 || len(lh.lobby.SeriesScore) != 0 {
+		t.Errorf("series not reset: round %d, score %v", lh.lobby.Round, lh.lobby.SeriesScore)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"fmt"
+	"sort"
+)
+
+const (
+	SpawnCorners      = "corners"       // Every player gets their own corner
+	SpawnTeamAdjacent = "team_adjacent" // Teammates share one side of the map
+)
+
+// SpawnPoints returns the four corner spawn points for a map of the given size,
+// ordered top-left, top-right, bottom-left, bottom-right.
+func SpawnPoints(width, height int) []models.Position {
+	return []models.Position{
+		{X: 1, Y: 1},
+		{X: width - 2, Y: 1},
+		{X: 1, Y: height - 2},
+		{X: width - 2, Y: height - 2},
+	}
+}
+
+// MapSpawnPoints returns the spawn points of a map: its own from a map file, or the four
+// corners computed from its actual dimensions.
+func MapSpawnPoints(m *models.Map) []models.Position {
+	if len(m.SpawnPoints) > 0 {
+		return m.SpawnPoints
+	}
+	return SpawnPoints(m.Width, m.Height)
+}
+
+// AssignSpawnPoints sets the Position and SpawnPoint of every player according to the arrangement.
+// With "team_adjacent", each team gets one side of the map (top, then bottom) so teammates start together.
+func AssignSpawnPoints(players []*models.Player, m *models.Map, arrangement string) error {
+	if m == nil {
+		return fmt.Errorf("no map to place spawn points on")
+	}
+	corners := MapSpawnPoints(m)
+	var spawns []models.Position
+
+	switch arrangement {
+	case SpawnTeamAdjacent:
+		if len(corners) < 4 {
+			return fmt.Errorf("team_adjacent spawns need 4 spawn points, map has %d", len(corners))
+		}
+		sides := [][]models.Position{
+			{corners[0], corners[1]}, // Top side
+			{corners[2], corners[3]}, // Bottom side
+		}
+
+		teams := map[int][]int{}
+		teamIDs := []int{}
+		for i, p := range players {
+			if _, ok := teams[p.TeamID]; !ok {
+				teamIDs = append(teamIDs, p.TeamID)
+			}
+			teams[p.TeamID] = append(teams[p.TeamID], i)
+		}
+		sort.Ints(teamIDs)
+
+		if len(teamIDs) > len(sides) {
+			return fmt.Errorf("team_adjacent spawns support at most %d teams, got %d", len(sides), len(teamIDs))
+		}
+
+		spawns = make([]models.Position, len(players))
+		for side, team := range teamIDs {
+			members := teams[team]
+			if len(members) > len(sides[side]) {
+				return fmt.Errorf("team %d has %d players, only %d fit on one side", team, len(members), len(sides[side]))
+			}
+			for j, idx := range members {
+				spawns[idx] = sides[side][j]
+			}
+		}
+
+	case SpawnCorners, "":
+		if len(players) > len(corners) {
+			return fmt.Errorf("%d players but only %d spawn points", len(players), len(corners))
+		}
+		spawns = corners[:len(players)]
+
+	default:
+		return fmt.Errorf("unknown spawn arrangement %q", arrangement)
+	}
+
+	if err := validateSpawnPoints(spawns, m); err != nil {
+		return err
+	}
+
+	for i, p := range players {
+		p.Position = spawns[i]
+		p.SpawnPoint = spawns[i]
+	}
+	return nil
+}
+
+// validateSpawnPoints checks that no two spawns overlap and that every spawn is walkable.
+func validateSpawnPoints(spawns []models.Position, m *models.Map) error {
+	seen := make(map[models.Position]bool)
+	for _, pos := range spawns {
+		if seen[pos] {
+			return fmt.Errorf("spawn point %v is assigned twice", pos)
+		}
+		seen[pos] = true
+
+		if pos.X < 0 || pos.X >= m.Width || pos.Y < 0 || pos.Y >= m.Height {
+			return fmt.Errorf("spawn point %v is outside the map", pos)
+		}
+		if m.WallAt(pos) {
+			return fmt.Errorf("spawn point %v is inside a wall", pos)
+		}
+		if m.BlockAt(pos) != nil {
+			return fmt.Errorf("spawn point %v is covered by a block", pos)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bomberman-dom/models"
+	"testing"
+)
+
+func TestAssignSpawnPointsTeamAdjacent(t *testing.T) {
+	cfg := DefaultGameConfig()
+	m := GenerateMap(cfg, MapRNG(1))
+	m.Reindex()
+
+	// Teams are interleaved in join order; each team must still end up on one side.
+	players := []*models.Player{
+		{ID: "a1", TeamID: 0},
+		{ID: "b1", TeamID: 1},
+		{ID: "a2", TeamID: 0},
+		{ID: "b2", TeamID: 1},
+	}
+	if err := AssignSpawnPoints(players, m, SpawnTeamAdjacent); err != nil {
+		t.Fatalf("AssignSpawnPoints: %v", err)
+	}
+
+	for _, pair := range [][2]*models.Player{{players[0], players[2]}, {players[1], players[3]}} {
+		if pair[0].Position.Y != pair[1].Position.Y {
+			t.Errorf("teammates %s at %v and %s at %v are on different sides",
+				pair[0].ID, pair[0].Position, pair[1].ID, pair[1].Position)
+		}
+	}
+	if players[0].Position.Y == players[1].Position.Y {
+		t.Errorf("opposing teams share a side at y=%d", players[0].Position.Y)
+	}
+	for _, p := range players {
+		if p.SpawnPoint != p.Position {
+			t.Errorf("%s: SpawnPoint %v != Position %v", p.ID, p.SpawnPoint, p.Position)
+		}
+	}
+}
+
+func TestAssignSpawnPointsTeamAdjacentRejectsThreeTeams(t *testing.T) {
+	m := GenerateMap(DefaultGameConfig(), MapRNG(1))
+	players := []*models.Player{{ID: "a", TeamID: 0}, {ID: "b", TeamID: 1}, {ID: "c", TeamID: 2}}
+	if err := AssignSpawnPoints(players, m, SpawnTeamAdjacent); err == nil {
+		t.Fatal("three teams should not fit on two sides")
+	}
+}
+
+func TestAssignSpawnPointsCornersStable(t *testing.T) {
+	cfg := DefaultGameConfig()
+	m := GenerateMap(cfg, MapRNG(7))
+	m.Reindex()
+	corners := SpawnPoints(cfg.MapWidth, cfg.MapHeight)
+
+	for n := 2; n <= 4; n++ {
+		var first []models.Position
+		for run := 0; run < 3; run++ {
+			players := make([]*models.Player, n)
+			for i := range players {
+				players[i] = &models.Player{ID: string(rune('a' + i))}
+			}
+			if err := AssignSpawnPoints(players, m, SpawnCorners); err != nil {
+				t.Fatalf("%d players: %v", n, err)
+			}
+
+			seen := map[models.Position]bool{}
+			for i, p := range players {
+				if seen[p.Position] {
+					t.Errorf("%d players: two players spawn on %v", n, p.Position)
+				}
+				seen[p.Position] = true
+				if p.Position != corners[i] {
+					t.Errorf("%d players: player %d at %v, want corner %v", n, i, p.Position, corners[i])
+				}
+				if run == 0 {
+					first = append(first, p.Position)
+				} else if p.Position != first[i] {
+					t.Errorf("%d players: run %d moved player %d from %v to %v", n, run, i, first[i], p.Position)
+				}
+			}
+		}
+	}
+
+	tooMany := make([]*models.Player, len(corners)+1)
+	for i := range tooMany {
+		tooMany[i] = &models.Player{}
+	}
+	if err := AssignSpawnPoints(tooMany, m, SpawnCorners); err == nil {
+		t.Error("more players than corners was accepted")
+	}
+}
+
+func TestSpawnPointsOnSmallMap(t *testing.T) {
+	cfg := DefaultGameConfig()
+	cfg.MapWidth, cfg.MapHeight = 11, 11
+	for seed := int64(1); seed <= 5; seed++ {
+		m := GenerateMap(cfg, MapRNG(seed))
+		players := make([]*models.Player, 4)
+		for i := range players {
+			players[i] = NewGamePlayer(string(rune('a'+i)), "Player", cfg)
+		}
+		if err := AssignSpawnPoints(players, m, SpawnCorners); err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+
+		want := []models.Position{at(1, 1), at(9, 1), at(1, 9), at(9, 9)}
+		for i, p := range players {
+			if p.Position != want[i] || p.SpawnPoint != want[i] {
+				t.Errorf("seed %d: player %d spawns at %v, want %v", seed, i, p.Position, want[i])
+			}
+			if m.WallAt(p.Position) || m.BlockAt(p.Position) != nil {
+				t.Errorf("seed %d: spawn %v is not clear", seed, p.Position)
+			}
+		}
+	}
+
+	// A spawn that lands on a wall is refused rather than used
+	m := GenerateMap(cfg, MapRNG(1))
+	m.Walls = append(m.Walls, &models.Wall{Position: at(9, 9)})
+	m.Reindex()
+	players := []*models.Player{NewGamePlayer("a", "a", cfg), NewGamePlayer("b", "b", cfg), NewGamePlayer("c", "c", cfg), NewGamePlayer("d", "d", cfg)}
+	if err := AssignSpawnPoints(players, m, SpawnCorners); err == nil {
+		t.Error("spawn inside a wall accepted")
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bomberman-dom/logging"
+	"bomberman-dom/models"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestMain(m *testing.M) {
+	gameLogger = testLogger()
+	os.Exit(m.Run())
+}
+
+// testLogger drops everything below Error so test output only shows real failures.
+func testLogger() *logging.Logger {
+	return logging.New(io.Discard, logging.Error)
+}
+
+// newTestServer serves the whole HTTP API of a fresh lobby handler.
+func newTestServer(t *testing.T) (*LobbyHandler, *httptest.Server) {
+	t.Helper()
+	lh := NewLobbyHandler(testLogger())
+	srv := httptest.NewServer(NewServeMux(lh))
+	t.Cleanup(srv.Close)
+	return lh, srv
+}
+
+// dialWS opens a WebSocket connection to the test server's /ws endpoint.
+func dialWS(srv *httptest.Server, header http.Header) (*websocket.Conn, *http.Response, error) {
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+	return websocket.DefaultDialer.Dial(url, header)
+}
+
+// waitFor polls cond until it holds, failing the test after a second.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// connectedPlayers returns the server side of every open connection.
+func connectedPlayers(lh *LobbyHandler) []*models.WebSocketPlayer {
+	lh.hub.Mutex.RLock()
+	defer lh.hub.Mutex.RUnlock()
+	players := make([]*models.WebSocketPlayer, 0, len(lh.hub.Players))
+	for _, p := range lh.hub.Players {
+		players = append(players, p)
+	}
+	return players
+}
+
+// newTestGame starts a game on the default map with its blocks cleared, so only the fixed
+// walls are in the way, and puts player N at positions[N-1]. Player IDs are "p1", "p2", ...
+func newTestGame(positions ...models.Position) *models.GameState {
+	cfg := DefaultGameConfig()
+	cfg.MapSeed = 1
+	players := make([]*models.Player, len(positions))
+	for i, pos := range positions {
+		p := NewGamePlayer(fmt.Sprintf("p%d", i+1), fmt.Sprintf("Player %d", i+1), cfg)
+		p.Position, p.SpawnPoint = pos, pos
+		players[i] = p
+	}
+	gs := NewGame(players, cfg)
+	gs.Map.Blocks = nil
+	gs.Map.Reindex()
+	return gs
+}
+
+// at is shorthand for a tile position.
+func at(x, y int) models.Position {
+	return models.Position{X: x, Y: y}
+}
+
+// addTestPlayer registers a connection-less player and, if inLobby, puts it in the lobby.
+// Messages sent to it pile up in its Send and State channels for drainMessages to read.
+func addTestPlayer(lh *LobbyHandler, id string, inLobby bool) *models.WebSocketPlayer {
+	player := &models.WebSocketPlayer{
+		WebSocketID: id,
+		Send:        make(chan []byte, 256),
+		State:       make(chan []byte, 1),
+		IsConnected: true,
+		JoinedAt:    lh.now(),
+	}
+	player.Name = id
+	player.SetHandshake(models.ProtocolVersion, EncodingJSON) // As if it had sent its hello
+	player.Touch(lh.now())
+
+	lh.registerPlayer(player)
+	if inLobby {
+		lh.lobby.Mutex.Lock()
+		player.LobbyID = lh.lobby.ID
+		lh.lobby.Players[id] = player
+		lh.lobby.Mutex.Unlock()
+	}
+	return player
+}
+
+// testMessage is a sent message with its payload left undecoded.
+type testMessage struct {
+	Type string          `json:"type"`
+	Data json.RawMessage `json:"data"`
+}
+
+// drainMessages returns every JSON message queued for a test player, oldest first, with the
+// pending state update (if any) last.
+func drainMessages(t *testing.T, player *models.WebSocketPlayer) []testMessage {
+	t.Helper()
+	var messages []testMessage
+	for {
+		select {
+		case data := <-player.Send:
+			messages = append(messages, decodeTestMessage(t, data))
+			continue
+		default:
+		}
+		break
+	}
+	select {
+	case data := <-player.State:
+		messages = append(messages, decodeTestMessage(t, data))
+	default:
+	}
+	return messages
+}
+
+func decodeTestMessage(t *testing.T, data []byte) testMessage {
+	t.Helper()
+	var msg testMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		t.Fatalf("undecodable message %q: %v", data, err)
+	}
+	return msg
+}
+
+// messagesOfType filters messages down to one type.
+func messagesOfType(messages []testMessage, msgType string) []testMessage {
+	var out []testMessage
+	for _, m := range messages {
+		if m.Type == msgType {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// fakeClock drives lh.now and lh.sleep in tests. Sleeps are served in the order they start:
+// allowed ones return at once and advance the clock by the time slept, the rest block until
+// Allow lets them through or the test ends. A test can so run countdowns up to a chosen point
+// and hold them there.
+type fakeClock struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	now      time.Time
+	tickets  int // Sleeps started so far
+	allowed  int // Sleeps with a lower ticket than this may return
+	passed   int // Sleeps that have returned
+	released bool
+}
+
+// useFakeClock installs a fakeClock on lh that lets the first free sleeps through. At cleanup,
+// countdowns are cancelled and every blocked sleep is released.
+func useFakeClock(t *testing.T, lh *LobbyHandler, free int) *fakeClock {
+	c := &fakeClock{now: time.Unix(1700000000, 0), allowed: free}
+	c.cond = sync.NewCond(&c.mu)
+	lh.now = c.Now
+	lh.sleep = c.Sleep
+	t.Cleanup(func() {
+		lh.lobby.Mutex.Lock()
+		lh.beginCountdown()
+		lh.lobby.Mutex.Unlock()
+
+		c.mu.Lock()
+		c.released = true
+		c.cond.Broadcast()
+		c.mu.Unlock()
+	})
+	return c
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ticket := c.tickets
+	c.tickets++
+	for ticket >= c.allowed && !c.released {
+		c.cond.Wait()
+	}
+	if c.released {
+		return
+	}
+	c.passed++
+	c.now = c.now.Add(d)
+}
+
+// Allow lets the n longest-blocked sleeps, or the next ones to start, return.
+func (c *fakeClock) Allow(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.allowed += n
+	c.cond.Broadcast()
+}
+
+// Settled reports whether every allowed sleep has returned and exactly sleepers are blocked.
+func (c *fakeClock) Settled(sleepers int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.passed == c.allowed && c.tickets-c.passed == sleepers
+}
+
+// sendJSON writes a message from a test client.
+func sendJSON(t *testing.T, conn *websocket.Conn, msgType string, data interface{}) {
+	t.Helper()
+	if err := conn.WriteJSON(&models.WebSocketMessage{Type: msgType, Data: data}); err != nil {
+		t.Fatalf("send %s: %v", msgType, err)
+	}
+}
+
+// readUntil reads a test client's messages until one of msgType arrives, and returns it.
+// Binary frames are skipped.
+func readUntil(t *testing.T, conn *websocket.Conn, msgType string) testMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	defer conn.SetReadDeadline(time.Time{})
+	for {
+		frameType, data, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %s: %v", msgType, err)
+		}
+		if frameType == websocket.BinaryMessage {
+			continue // A gob state update
+		}
+		var msg testMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatalf("waiting for %s: %v", msgType, err)
+		}
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
@@ -79,6 +79,7 @@ function ensureGameState(state) {
         players: validPlayers.length > 0 ? validPlayers : [defaultPlayer],
         bombs: state.bombs || [],
         flames: state.flames || [],
+        hazards: state.hazards || [],
         powerUps: state.powerUps || [],
         currentPlayer: state.currentPlayer || defaultPlayer,
         winner: state.winner || null,
@@ -209,7 +210,7 @@ function renderGameHeader(state) {
  * Render the main game board
  */
 function renderGameBoard(state, onMove, onPlaceBomb) {
-    const { gameMap, players = [], bombs = [], flames = [], powerUps = [] } = state;
+    const { gameMap, players = [], bombs = [], flames = [], hazards = [], powerUps = [] } = state;
     
     if (!gameMap) {
         return createElement('div', { className: 'game-board loading' },
@@ -248,7 +249,7 @@ function renderGameBoard(state, onMove, onPlaceBomb) {
             }
         },
             // Render all board cells without stateVersion
-            ...renderBoardCells(gameMap, players, bombs, flames, powerUps)
+            ...renderBoardCells(gameMap, players, bombs, flames, hazards, powerUps)
         )
     );
 }
@@ -256,12 +257,12 @@ function renderGameBoard(state, onMove, onPlaceBomb) {
 /**
  * Render all cells in the game board
  */
-function renderBoardCells(gameMap, players, bombs, flames, powerUps) {
+function renderBoardCells(gameMap, players, bombs, flames, hazards, powerUps) {
     const cells = [];
     
     for (let y = 0; y < gameMap.height; y++) {
         for (let x = 0; x < gameMap.width; x++) {
-            cells.push(renderCell(x, y, gameMap, players, bombs, flames, powerUps));
+            cells.push(renderCell(x, y, gameMap, players, bombs, flames, hazards, powerUps));
         }
     }
     
@@ -271,7 +272,7 @@ function renderBoardCells(gameMap, players, bombs, flames, powerUps) {
 /**
  * Render a single cell on the game board - OPTIMIZED for no ghosting
  */
-function renderCell(x, y, gameMap, players, bombs, flames, powerUps) {
+function renderCell(x, y, gameMap, players, bombs, flames, hazards, powerUps) {
     // Find what's actually at this position first
     const playersHere = (players || []).filter(p => 
         p && p.position && 
@@ -290,6 +291,9 @@ function renderCell(x, y, gameMap, players, bombs, flames, powerUps) {
         typeof f.position.x === 'number' && typeof f.position.y === 'number' &&
         f.position.x === x && f.position.y === y
     );
+    const hazard = (hazards || []).find(h =>
+        h && h.position && h.position.x === x && h.position.y === y
+    );
     const powerUp = (powerUps || []).find(p => 
         p && p.position && 
         typeof p.position.x === 'number' && typeof p.position.y === 'number' &&
@@ -302,7 +306,7 @@ function renderCell(x, y, gameMap, players, bombs, flames, powerUps) {
     let cellClass = 'game-cell';
     
     // IMPORTANT: Only render ONE item per cell in priority order
-    // Priority: Player > Bomb > Flame > Power-up > Block > Wall > Empty
+    // Priority: Player > Bomb > Flame > Lava > Power-up > Block > Wall > Empty
     
     // Check for players FIRST (highest priority) - using playersHere from above
     if (playersHere && playersHere.length > 0) {
@@ -344,6 +348,16 @@ function renderCell(x, y, gameMap, players, bombs, flames, powerUps) {
         }, createElement('div', { className: 'flame' }, '🔥'));
     }
     
+    // Lava left by a lava bomb, shown until it cools
+    if (hazard) {
+        return createElement('div', {
+            key: cellKey,
+            className: `${cellClass} has-hazard`,
+            'data-x': x,
+            'data-y': y
+        }, createElement('div', { className: 'hazard' }, '🌋'));
+    }
+    
     // Check for power-up (fourth priority) - using powerUp from above
     if (powerUp) {
         return createElement('div', {
@@ -526,24 +540,22 @@ function handleKeyDown(event, onMove, onPlaceBomb) {
     event.preventDefault();
     
     const key = event.key.toLowerCase();
-    const preciseMove = event.shiftKey; // Shift key enables precise movement (1 step at a time)
-    
     switch (key) {
         case 'w':
         case 'arrowup':
-            onMove && onMove('up', preciseMove);
+            onMove && onMove('up');
             break;
         case 's':
         case 'arrowdown':
-            onMove && onMove('down', preciseMove);
+            onMove && onMove('down');
             break;
         case 'a':
         case 'arrowleft':
-            onMove && onMove('left', preciseMove);
+            onMove && onMove('left');
             break;
         case 'd':
         case 'arrowright':
-            onMove && onMove('right', preciseMove);
+            onMove && onMove('right');
             break;
         case ' ':
         case 'space':
@@ -3,6 +3,9 @@
  * @author Chan
  */
 
+// Protocol version this client speaks; must match the server's supported range
+const PROTOCOL_VERSION = 2;
+
 /**
  * GameState class - manages the real WebSocket connection to backend
  */
@@ -83,6 +86,11 @@ export class GameState {
           nickname: nickname,
         });
 
+        // Announce the protocol version first so an incompatible server rejects us clearly
+        this.websocket.send(
+          JSON.stringify({ type: "hello", data: { version: PROTOCOL_VERSION } })
+        );
+
         const joinMessage = {
           type: "join_lobby",
           data: {
@@ -146,13 +154,17 @@ export class GameState {
       console.log("Data:", message.data);
       console.log("==============================");
 
-      const messageData = message.data || message.Data || {};
+      const messageData = message.data || {};
 
       switch (message.type) {
         case "success":
           this.handleSuccessMessage(messageData);
           break;
 
+        case "hello":
+          console.log("🤝 Protocol version negotiated:", messageData.version);
+          break;
+
         case "player_joined":
           this.handlePlayerJoined(messageData);
           break;
@@ -177,14 +189,60 @@ export class GameState {
           this.handleTimerUpdate(messageData);
           break;
 
+        case "map_preview":
+          // Arena and spawn points for the upcoming game, shown during the countdown
+          this.setState({ mapPreview: messageData });
+          break;
+
         case "game_start":
+          this.setState({ spectating: false, deathInfo: null });
           this.handleGameStart(messageData);
           break;
 
         case "game_state_update":
+        case "game_update": // Deprecated alias of game_state_update, dropped next release
           this.handleGameStateUpdate(messageData);
           break;
 
+        case "resync":
+          // Full authoritative state after a requested resync
+          this.handleGameStateUpdate(messageData.state);
+          break;
+
+        case "player_state":
+          this.setState({ playerState: messageData });
+          break;
+
+        case "my_stats":
+          // Reply to get_my_stats: the private player state plus lives, score and statuses
+          this.setState({ playerState: messageData });
+          break;
+
+        case "blocks_destroyed":
+          // Positions to play the destruction animation on; the next state update has the new map
+          this.setState({ destroyedBlocks: messageData.blocks || [] });
+          break;
+
+        case "series_update":
+          this.setState({ series: messageData });
+          break;
+
+        case "player_surrendered":
+          console.log("🏳️ Player surrendered:", messageData.name, messageData.reason);
+          break;
+
+        case "player_died":
+          // Kill feed entry, also the cue for the death animation at messageData.position
+          this.setState({
+            killFeed: [...(this.state.killFeed || []), messageData].slice(-5),
+          });
+          break;
+
+        case "you_died":
+          // Eliminated: keep rendering updates, but as a spectator
+          this.setState({ spectating: true, deathInfo: messageData });
+          break;
+
         case "error":
           this.handleError(messageData);
           break;
@@ -209,6 +267,7 @@ export class GameState {
       )
     ) {
       console.log("📡 Connected to WebSocket, join_lobby message sent");
+      this.setState({ motd: messageData.motd || "" });
     } else if (messageData.message?.includes("Joined lobby successfully")) {
       console.log("🎉 SUCCESSFULLY JOINED LOBBY!");
 
@@ -254,31 +313,24 @@ export class GameState {
 
     let players = [];
     if (data.lobby && data.lobby.players) {
-      players = Object.values(data.lobby.players).map((player) => ({
+      players = data.lobby.players.map((player) => ({
         id: player.webSocketId,
         WebSocketID: player.webSocketId,
-        nickname: player.Name,
-        lives: player.Lives,
+        nickname: player.name,
+        lives: player.lives,
         isHost: data.lobby.host === player.webSocketId,
       }));
     }
 
-    let waitingTimer = null;
-    let gameTimer = null;
-
-    if (data.status === "waiting_for_players" && data.timeLeft > 0) {
-      waitingTimer = data.timeLeft;
-      console.log("⏳ SETTING WAITING TIMER:", waitingTimer);
-    } else if (data.status === "starting" && data.timeLeft > 0) {
-      gameTimer = data.timeLeft;
-      console.log("🎮 SETTING GAME TIMER:", gameTimer);
-    }
+    // Countdowns arrive separately as timer_update messages; clear them only
+    // when the lobby falls back to plain waiting
+    const timers =
+      data.status === "waiting" ? { waitingTimer: null, gameTimer: null } : {};
 
     this.setState({
       players: players,
       playerId: data.your_player_id || data.player_id || this.state.playerId,
-      waitingTimer: waitingTimer,
-      gameTimer: gameTimer,
+      ...timers,
     });
   }
 
@@ -288,12 +340,12 @@ export class GameState {
   handlePlayerJoined(data) {
     console.log("👤 Player joined:", data);
 
-    if (data.Player) {
+    if (data.player) {
       const newPlayer = {
-        id: data.Player.WebSocketID,
-        WebSocketID: data.Player.WebSocketID,
-        nickname: data.Player.Name,
-        lives: data.Player.Lives,
+        id: data.player.webSocketId,
+        WebSocketID: data.player.webSocketId,
+        nickname: data.player.name,
+        lives: data.player.lives,
         isHost: false,
       };
 
@@ -351,6 +403,7 @@ export class GameState {
       author: author,
       text: text,
       timestamp: data.Timestamp ? new Date(data.Timestamp) : new Date(),
+      channel: data.channel || "lobby", // "global" messages come from outside the lobby
     };
 
     console.log("✅ Adding message to chat:", newMessage);
@@ -381,10 +434,11 @@ export class GameState {
    * Handle timer updates
    */
   handleTimerUpdate(data) {
-    this.setState({
-      waitingTimer: data.waiting_timer,
-      gameTimer: data.game_timer,
-    });
+    if (data.phase === "waiting") {
+      this.setState({ waitingTimer: data.secondsLeft, gameTimer: null });
+    } else if (data.phase === "starting") {
+      this.setState({ waitingTimer: null, gameTimer: data.secondsLeft });
+    }
   }
 
   /**
@@ -408,92 +462,80 @@ export class GameState {
     // Map backend game state format to frontend format
     let updateData = {};
     
-    if (data.Players) {
-      updateData.players = data.Players.map(player => ({
-        id: player.ID,
-        WebSocketID: player.ID,
-        nickname: player.Name,
-        lives: player.Lives,
+    if (data.players) {
+      updateData.players = data.players.map(player => ({
+        id: player.id,
+        WebSocketID: player.id,
+        nickname: player.name,
+        lives: player.lives,
         position: {
-          x: player.Position.X,  // Map X -> x
-          y: player.Position.Y   // Map Y -> y
+          x: player.position.x,
+          y: player.position.y
         },
-        alive: player.Alive,
-        bombCount: player.BombCount || 1,
-        flameRange: player.FlameRange || 1,
-        speed: player.Speed || 0
+        alive: player.alive,
+        bombCount: player.bombCount || 1,
+        flameRange: player.flameRange || 1,
+        speed: player.speed || 0
       }));
     }
     
-    if (data.Map) {
+    if (data.map) {
       updateData.gameMap = {
-        width: data.Map.Width || 15,
-        height: data.Map.Height || 13,
-        walls: (data.Map.Walls || []).map(wall => ({
+        width: data.map.width || 15,
+        height: data.map.height || 13,
+        walls: (data.map.walls || []).map(wall => ({
           position: {
-            x: wall.Position.X,  // Map X -> x
-            y: wall.Position.Y   // Map Y -> y
+            x: wall.x,
+            y: wall.y
           }
         })),
-        blocks: (data.Map.Blocks || []).map(block => ({
+        blocks: (data.map.blocks || []).map(block => ({
           position: {
-            x: block.Position.X,  // Map X -> x
-            y: block.Position.Y   // Map Y -> y
+            x: block.position.x,
+            y: block.position.y
           },
-          destroyed: block.Destroyed || false,
-          hiddenPowerUp: block.HiddenPowerUp
+          destroyed: block.destroyed || false
         }))
       };
     }
     
-    // Handle bombs - clear array when null/undefined
-    if (data.Bombs) {
-      updateData.bombs = data.Bombs
-        .filter(bomb => bomb.Timer > 0) // Only include active bombs
-        .map(bomb => ({
-          id: bomb.ID,
-          position: {
-            x: bomb.Position.X,  // Map X -> x
-            y: bomb.Position.Y   // Map Y -> y
-          },
-          ownerId: bomb.OwnerID,
-          timer: bomb.Timer,
-          timestamp: Date.now()
-        }));
-    } else if (data.Bombs === null) {
-      // Explicitly clear bombs when backend sends null
-      updateData.bombs = [];
-    }
-    
-    // Handle flames - clear array when null/undefined
-    if (data.Flames) {
-      updateData.flames = data.Flames.map(flame => ({
-        id: flame.ID,
+    // The server always sends arrays, empty when there is nothing on the map
+    updateData.bombs = (data.bombs || [])
+      .filter(bomb => bomb.timer > 0) // Only include active bombs
+      .map(bomb => ({
         position: {
-          x: flame.Position.X,  // Map X -> x
-          y: flame.Position.Y   // Map Y -> y
+          x: bomb.position.x,
+          y: bomb.position.y
         },
+        ownerId: bomb.ownerId,
+        timer: bomb.timer,
+        imminent: bomb.imminent,
         timestamp: Date.now()
       }));
-    } else if (data.Flames === null) {
-      // Explicitly clear flames when backend sends null
-      updateData.flames = [];
-    }
     
-    // Handle power-ups - clear array when null/undefined
-    if (data.PowerUps) {
-      updateData.powerUps = data.PowerUps.map(powerUp => ({
-        id: powerUp.ID,
-        position: {
-          x: powerUp.Position.X,  // Map X -> x
-          y: powerUp.Position.Y   // Map Y -> y
-        },
-        type: powerUp.Type
-      }));
-    } else if (data.PowerUps === null) {
-      // Explicitly clear power-ups when backend sends null
-      updateData.powerUps = [];
-    }
+    updateData.flames = (data.flames || []).map(flame => ({
+      position: {
+        x: flame.x,
+        y: flame.y
+      },
+      timestamp: Date.now()
+    }));
+    
+    // Lava tiles left by lava bombs, impassable until they cool
+    updateData.hazards = (data.hazards || []).map(hazard => ({
+      position: {
+        x: hazard.x,
+        y: hazard.y
+      }
+    }));
+    
+    updateData.powerUps = (data.powerUps || []).map(powerUp => ({
+      position: {
+        x: powerUp.position.x,
+        y: powerUp.position.y
+      },
+      type: powerUp.type
+    }));
     
     // Map numeric status to string values
     const statusMap = {
@@ -502,16 +544,16 @@ export class GameState {
       2: 'in_progress',         // InProgress
       3: 'finished'             // Finished
     };
-    updateData.gameStatus = statusMap[data.Status] || 'in_progress';
+    updateData.gameStatus = statusMap[data.status] || 'in_progress';
     updateData.lastUpdate = Date.now();
     
     // Handle winner information
-    if (data.Winner) {
+    if (data.winner) {
       updateData.winner = {
-        id: data.Winner.ID,
-        name: data.Winner.Name,
-        nickname: data.Winner.Nickname,
-        score: data.Winner.Score || 0
+        id: data.winner.id,
+        name: data.winner.name,
+        nickname: data.winner.name,
+        score: data.winner.score || 0
       };
     }
     
@@ -530,6 +572,7 @@ export class GameState {
 
     this.setState({
       error: data.message || data.error || "An error occurred",
+      errorHint: data.hint || null, // e.g. "wait_for_game_end" when a join is refused mid-game
       isJoining: false,
     });
   }
@@ -577,7 +620,7 @@ export class GameState {
   /**
    * Send chat message
    */
-  sendChatMessage(message) {
+  sendChatMessage(message, channel = "lobby") {
     if (!this.websocket || this.websocket.readyState !== WebSocket.OPEN) {
       console.error("❌ WebSocket not connected");
       this.setState({
@@ -595,6 +638,7 @@ export class GameState {
       type: "chat_message",
       data: {
         message: message.trim(),
+        channel: channel,
       },
     };
 
@@ -617,7 +661,7 @@ export class GameState {
   /**
    * Send player movement command
    */
-  sendPlayerMove(direction, precise = false) {
+  sendPlayerMove(direction) {
     if (!this.websocket || this.websocket.readyState !== WebSocket.OPEN) {
       console.error("❌ WebSocket not connected for movement");
       return;
@@ -626,8 +670,7 @@ export class GameState {
     const moveMessage = {
       type: "player_move",
       data: {
-        direction: direction,
-        ...(precise && { precise: true }) // Only include precise field if it's true
+        direction: direction
       }
     };
 
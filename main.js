@@ -83,8 +83,8 @@ function handleSendMessage(message) {
 /**
  * Handle player movement in game
  */
-function handlePlayerMove(direction, precise = false) {
-    gameState.sendPlayerMove(direction, precise);
+function handlePlayerMove(direction) {
+    gameState.sendPlayerMove(direction);
 }
 
 /**